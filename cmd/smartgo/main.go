@@ -0,0 +1,382 @@
+/*
+Command smartgo reads the identity and the health of the ATA, NVMe and SCSI
+devices.
+
+	smartgo scan
+	smartgo info /dev/sda
+	smartgo health -json /dev/nvme0
+
+The exit status is 0 on success, 1 for the bad command line, 2 when the
+device is not opened or skipped by the power policy, 4 when a command to the
+device failed and 8 when the device reports the failing health.
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sungup/smartgo"
+)
+
+const (
+	exitOK      = 0
+	exitUsage   = 1
+	exitOpen    = 2
+	exitCommand = 4
+	exitFailing = 8
+)
+
+// the entry points of the library, replaced by the tests to run the
+// commands on the fake devices
+var (
+	openDevice = smartgo.Open
+	scanDevice = smartgo.ScanDevice
+	summarize  = smartgo.Summarize
+)
+
+// The protocol specific reads of the commands are implemented by *ATADevice
+// and *NVMeDevice.
+type (
+	ataSmartReader interface {
+		ReadSMART() (*smartgo.SmartData, error)
+	}
+
+	nvmeSmartReader interface {
+		ReadSmartLog(nsid uint32) (*smartgo.NVMeSmartLog, error)
+	}
+
+	ataLogReader interface {
+		ReadExtSelfTestLog(pages int) (*smartgo.AtaSelfTestLog, error)
+		ReadSelfTestLog() (*smartgo.AtaSelfTestLog, error)
+		ReadExtErrorLog(pages int) (*smartgo.AtaErrorLog, error)
+		ReadSummaryErrorLog() (*smartgo.AtaErrorLog, error)
+	}
+
+	nvmeLogReader interface {
+		ReadSelfTestLog() (*smartgo.NVMeSelfTestLog, error)
+		ReadErrorLog() ([]smartgo.NVMeErrorEntry, error)
+	}
+
+	firmwareReader interface {
+		FirmwareInventory() (*smartgo.NVMeFirmwareInventory, error)
+	}
+)
+
+// env is the output and the options shared by the subcommands.
+type env struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	json    bool
+	devType smartgo.DeviceType
+	policy  smartgo.PowerPolicy
+}
+
+func (e *env) errorf(format string, args ...interface{}) {
+	fmt.Fprintf(e.stderr, "smartgo: "+format+"\n", args...)
+}
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(e *env, args []string) (report, int)
+}
+
+var commands = []*command{
+	{"scan", "", "list the devices with the type, model, serial and capacity", runScan},
+	{"info", "<dev>", "show the identity and the capabilities", deviceCommand(runInfo)},
+	{"health", "<dev>", "show the health verdict and the key metrics", deviceCommand(runHealth)},
+	{"attributes", "<dev>", "show the ATA SMART attributes or the NVMe health log", deviceCommand(runAttributes)},
+	{"logs", "<dev>", "show the self-test and the error logs", deviceCommand(runLogs)},
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+
+	return nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: smartgo <command> [-json] [-n policy] [-type type] [<dev>]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %-5s  %s\n", cmd.name, cmd.args, cmd.summary)
+	}
+}
+
+// parseType accepts the device types Open supports.
+func parseType(name string) (smartgo.DeviceType, error) {
+	switch devType := smartgo.DeviceType(name); devType {
+	case "", smartgo.SATA, smartgo.NVMe, smartgo.SCSI, smartgo.USB:
+		return devType, nil
+	}
+
+	return "", fmt.Errorf("unknown device type %q", name)
+}
+
+// deviceCommand opens the device given as the only argument.
+func deviceCommand(fn func(e *env, device smartgo.Device) (report, int)) func(*env, []string) (report, int) {
+	return func(e *env, args []string) (report, int) {
+		if len(args) != 1 {
+			e.errorf("one device is required")
+			return nil, exitUsage
+		}
+
+		opts := []smartgo.Option{smartgo.WithPowerPolicy(e.policy)}
+		if e.devType != "" {
+			opts = append(opts, smartgo.WithType(e.devType))
+		}
+
+		device, err := openDevice(args[0], opts...)
+		if err != nil {
+			// SkippedError names the device itself
+			if _, ok := err.(*smartgo.SkippedError); ok {
+				e.errorf("%v", err)
+			} else {
+				e.errorf("%s: %v", args[0], err)
+			}
+
+			return nil, exitOpen
+		}
+		defer device.Close()
+
+		return fn(e, device)
+	}
+}
+
+// parseArgs parses the flags before and after the device. The flag package
+// stops at the first argument, so the rest is parsed again until no flag is
+// left.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0, 1)
+
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return exitOK
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(stderr, "smartgo: unknown command %q\n\n", args[0])
+		usage(stderr)
+		return exitUsage
+	}
+
+	e := &env{stdout: stdout, stderr: stderr}
+
+	var policy, devType string
+
+	flags := flag.NewFlagSet("smartgo "+cmd.name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.BoolVar(&e.json, "json", false, "print JSON instead of the table")
+	flags.StringVar(&policy, "n", "never", "skip the device in the power mode by the `policy`, never, sleep, standby or idle")
+	flags.StringVar(&devType, "type", "", "device `type`, sata, nvme, scsi or usb, found in the sysfs if not given")
+
+	positional, err := parseArgs(flags, args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+
+		return exitUsage
+	}
+
+	if e.policy, err = smartgo.ParsePowerPolicy(policy); err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+
+	if e.devType, err = parseType(devType); err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+
+	r, code := cmd.run(e, positional)
+	if r == nil {
+		return code
+	}
+
+	// the report is rendered even if a part of it failed to read
+	if err := render(stdout, r, e.json); err != nil {
+		e.errorf("%v", err)
+
+		if code == exitOK {
+			code = exitCommand
+		}
+	}
+
+	return code
+}
+
+func runScan(e *env, args []string) (report, int) {
+	if len(args) != 0 {
+		e.errorf("scan takes no device")
+		return nil, exitUsage
+	}
+
+	result, err := scanDevice(e.policy)
+	if err != nil {
+		e.errorf("%v", err)
+		return nil, exitCommand
+	}
+
+	r := newScanReport(result)
+
+	for _, device := range result.Devices {
+		_ = device.Close()
+	}
+
+	if len(r.Errors) != 0 {
+		return r, exitCommand
+	}
+
+	return r, exitOK
+}
+
+func runInfo(e *env, device smartgo.Device) (report, int) {
+	r := newInfoReport(device)
+
+	if dev, ok := device.(firmwareReader); ok {
+		inv, err := dev.FirmwareInventory()
+		if err != nil {
+			e.errorf("%s: firmware slot log: %v", device.Device(), err)
+			return r, exitCommand
+		}
+
+		r.NVMeFirmware = inv
+	}
+
+	return r, exitOK
+}
+
+func runHealth(e *env, device smartgo.Device) (report, int) {
+	summary, err := summarize(device)
+	if err != nil {
+		e.errorf("%s: %v", device.Device(), err)
+		return nil, exitCommand
+	}
+
+	r := &healthReport{Device: device.Device(), Health: summary}
+
+	if summary.Verdict == smartgo.HealthFailed {
+		return r, exitFailing
+	}
+
+	return r, exitOK
+}
+
+func runAttributes(e *env, device smartgo.Device) (report, int) {
+	r := &attributesReport{Device: device.Device()}
+
+	var err error
+
+	switch dev := device.(type) {
+	case ataSmartReader:
+		var smart *smartgo.SmartData
+		if smart, err = dev.ReadSMART(); err == nil {
+			r.Attributes = smart.Attributes
+		}
+	case nvmeSmartReader:
+		r.SmartLog, err = dev.ReadSmartLog(smartgo.NVMeNSIDAll)
+	default:
+		err = smartgo.ErrUnsupportedType
+	}
+
+	if err != nil {
+		e.errorf("%s: %v", device.Device(), err)
+		return nil, exitCommand
+	}
+
+	return r, exitOK
+}
+
+func runLogs(e *env, device smartgo.Device) (report, int) {
+	r := &logsReport{Device: device.Device(), Errors: make(map[string]string)}
+
+	failed := func(log string, err error) {
+		e.errorf("%s: %s log: %v", device.Device(), log, err)
+		r.Errors[log] = err.Error()
+	}
+
+	switch dev := device.(type) {
+	case ataLogReader:
+		// the extended logs record the 48-bit LBA, and the SMART logs are
+		// read from the device without the GPL feature set
+		selfTest, err := dev.ReadExtSelfTestLog(0)
+		if err != nil {
+			selfTest, err = dev.ReadSelfTestLog()
+		}
+
+		if err != nil {
+			failed("self-test", err)
+		}
+
+		errorLog, err := dev.ReadExtErrorLog(0)
+		if err != nil {
+			errorLog, err = dev.ReadSummaryErrorLog()
+		}
+
+		if err != nil {
+			failed("error", err)
+		}
+
+		r.ATASelfTest, r.ATAErrors = selfTest, errorLog
+	case nvmeLogReader:
+		selfTest, err := dev.ReadSelfTestLog()
+		if err != nil {
+			failed("self-test", err)
+		}
+
+		errorLog, err := dev.ReadErrorLog()
+		if err != nil {
+			failed("error", err)
+		}
+
+		r.NVMeSelfTest, r.NVMeErrors = selfTest, errorLog
+	default:
+		e.errorf("%s: %v", device.Device(), smartgo.ErrUnsupportedType)
+		return nil, exitCommand
+	}
+
+	if len(r.Errors) != 0 {
+		return r, exitCommand
+	}
+
+	return r, exitOK
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
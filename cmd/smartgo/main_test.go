@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sungup/smartgo"
+)
+
+type fakeDevice struct {
+	path   string
+	closed bool
+}
+
+func (dev *fakeDevice) Type() smartgo.DeviceType { return smartgo.SATA }
+func (dev *fakeDevice) Device() string           { return dev.path }
+func (dev *fakeDevice) Model() string            { return "ST4000NM0035-1V4" }
+func (dev *fakeDevice) Firmware() string         { return "TN03" }
+func (dev *fakeDevice) Serial() string           { return "ZC1ABCDE" }
+func (dev *fakeDevice) ID() string               { return "wwn-0x5000c500a1b2c3d4" }
+func (dev *fakeDevice) Capacity() uint64         { return 4000787030016 }
+
+func (dev *fakeDevice) HealthStatus() (smartgo.HealthStatus, error) {
+	return smartgo.HealthPassed, nil
+}
+
+func (dev *fakeDevice) Features() []smartgo.Capability {
+	return []smartgo.Capability{{Name: "SMART", Supported: true, Enabled: true}}
+}
+
+func (dev *fakeDevice) PowerMode() (smartgo.PowerMode, error) {
+	return smartgo.PowerModeActive, nil
+}
+
+func (dev *fakeDevice) ScanSMART() error {
+	return nil
+}
+
+func (dev *fakeDevice) Close() error {
+	dev.closed = true
+
+	return nil
+}
+
+// fakeATA reads the SMART data and the logs of the ATA device, the extended
+// logs fail to test the fallback on the SMART logs.
+type fakeATA struct {
+	*fakeDevice
+}
+
+func (dev *fakeATA) ReadSMART() (*smartgo.SmartData, error) {
+	return &smartgo.SmartData{Attributes: []smartgo.SmartAttribute{
+		{ID: 9, Name: "Power_On_Hours", Flags: 0x0032, Current: 91, Worst: 91, Raw: [6]byte{0x4c, 0x1d}},
+	}}, nil
+}
+
+func (dev *fakeATA) ReadExtSelfTestLog(pages int) (*smartgo.AtaSelfTestLog, error) {
+	return nil, errors.New("aborted")
+}
+
+func (dev *fakeATA) ReadSelfTestLog() (*smartgo.AtaSelfTestLog, error) {
+	return &smartgo.AtaSelfTestLog{Revision: 1, Entries: []smartgo.AtaSelfTestEntry{
+		{Test: smartgo.AtaSelfTest(0x01), LifeTime: 7500},
+	}}, nil
+}
+
+func (dev *fakeATA) ReadExtErrorLog(pages int) (*smartgo.AtaErrorLog, error) {
+	return nil, errors.New("aborted")
+}
+
+func (dev *fakeATA) ReadSummaryErrorLog() (*smartgo.AtaErrorLog, error) {
+	return &smartgo.AtaErrorLog{Version: 1}, nil
+}
+
+// fakeNVMe fails to read the error log.
+type fakeNVMe struct {
+	*fakeDevice
+}
+
+func (dev *fakeNVMe) ReadSmartLog(nsid uint32) (*smartgo.NVMeSmartLog, error) {
+	return &smartgo.NVMeSmartLog{Temperature: 310, PercentageUsed: 3}, nil
+}
+
+func (dev *fakeNVMe) ReadSelfTestLog() (*smartgo.NVMeSelfTestLog, error) {
+	return &smartgo.NVMeSelfTestLog{}, nil
+}
+
+func (dev *fakeNVMe) ReadErrorLog() ([]smartgo.NVMeErrorEntry, error) {
+	return nil, errors.New("invalid log page")
+}
+
+func (dev *fakeNVMe) FirmwareInventory() (*smartgo.NVMeFirmwareInventory, error) {
+	return &smartgo.NVMeFirmwareInventory{ActiveSlot: 1, Slots: []smartgo.NVMeFirmwareSlot{{Slot: 1, Revision: "2B2QEXM7", Active: true}}}, nil
+}
+
+// fakeOpen replaces openDevice while the test runs.
+func fakeOpen(t *testing.T, device smartgo.Device, err error) {
+	orig := openDevice
+
+	openDevice = func(path string, opts ...smartgo.Option) (smartgo.Device, error) {
+		return device, err
+	}
+
+	t.Cleanup(func() { openDevice = orig })
+}
+
+// fakeSummarize replaces summarize while the test runs.
+func fakeSummarize(t *testing.T, summary *smartgo.HealthSummary, err error) {
+	orig := summarize
+
+	summarize = func(device smartgo.Device) (*smartgo.HealthSummary, error) {
+		return summary, err
+	}
+
+	t.Cleanup(func() { summarize = orig })
+}
+
+// fakeScan replaces scanDevice while the test runs.
+func fakeScan(t *testing.T, result *smartgo.ScanResult, err error) {
+	orig := scanDevice
+
+	scanDevice = func(policy smartgo.PowerPolicy) (*smartgo.ScanResult, error) {
+		return result, err
+	}
+
+	t.Cleanup(func() { scanDevice = orig })
+}
+
+func runTest(args ...string) (int, string, string) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	code := run(args, stdout, stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunUsage(t *testing.T) {
+	a := assert.New(t)
+
+	code, _, stderr := runTest()
+	a.Equal(exitUsage, code)
+	a.Contains(stderr, "commands:")
+
+	code, stdout, _ := runTest("help")
+	a.Equal(exitOK, code)
+	a.Contains(stdout, "attributes")
+
+	code, _, stderr = runTest("format", "/dev/sda")
+	a.Equal(exitUsage, code)
+	a.Contains(stderr, `unknown command "format"`)
+
+	code, _, _ = runTest("info", "-bogus", "/dev/sda")
+	a.Equal(exitUsage, code)
+
+	code, _, stderr = runTest("info", "-n", "never-ever", "/dev/sda")
+	a.Equal(exitUsage, code)
+	a.Contains(stderr, "unknown power policy")
+
+	code, _, stderr = runTest("info", "-type", "fcoe", "/dev/sda")
+	a.Equal(exitUsage, code)
+	a.Contains(stderr, `unknown device type "fcoe"`)
+
+	code, _, _ = runTest("info")
+	a.Equal(exitUsage, code)
+
+	code, _, _ = runTest("scan", "/dev/sda")
+	a.Equal(exitUsage, code)
+}
+
+func TestRunInfo(t *testing.T) {
+	a := assert.New(t)
+
+	device := &fakeDevice{path: "/dev/sda"}
+	fakeOpen(t, device, nil)
+
+	code, stdout, _ := runTest("info", "/dev/sda")
+	a.Equal(exitOK, code)
+	a.Contains(stdout, "Model:     ST4000NM0035-1V4\n")
+	a.Contains(stdout, "Capacity:  4000787030016 bytes [4.00 TB]\n")
+	a.Contains(stdout, "SMART    yes        yes\n")
+	a.True(device.closed)
+
+	code, stdout, _ = runTest("info", "-json", "/dev/sda")
+	a.Equal(exitOK, code)
+
+	info := make(map[string]interface{})
+	a.NoError(json.Unmarshal([]byte(stdout), &info))
+	a.Equal("ZC1ABCDE", info["serial"])
+	a.Equal("sata", info["type"])
+	a.NotContains(info, "nvme")
+}
+
+func TestRunDeviceErrors(t *testing.T) {
+	a := assert.New(t)
+
+	fakeOpen(t, nil, &smartgo.SkippedError{Device: "/dev/sda", Mode: smartgo.PowerModeStandby, Policy: smartgo.PowerPolicyStandby})
+
+	code, stdout, stderr := runTest("health", "-n", "standby", "/dev/sda")
+	a.Equal(exitOpen, code)
+	a.Empty(stdout)
+	a.Contains(stderr, "skipped")
+
+	fakeOpen(t, nil, errors.New("permission denied"))
+
+	code, _, _ = runTest("info", "/dev/sda")
+	a.Equal(exitOpen, code)
+
+	// the fake implements none of the protocol specific reads
+	fakeOpen(t, &fakeDevice{path: "/dev/sda"}, nil)
+
+	for _, cmd := range []string{"health", "attributes", "logs"} {
+		code, stdout, stderr = runTest(cmd, "/dev/sda")
+		a.Equal(exitCommand, code, cmd)
+		a.Empty(stdout)
+		a.Contains(stderr, smartgo.ErrUnsupportedType.Error())
+	}
+}
+
+func TestRunFlagsAfterDevice(t *testing.T) {
+	a := assert.New(t)
+
+	fakeOpen(t, &fakeDevice{path: "/dev/sda"}, nil)
+
+	code, stdout, _ := runTest("info", "/dev/sda", "-json")
+	a.Equal(exitOK, code)
+	a.True(json.Valid([]byte(stdout)))
+
+	code, stdout, _ = runTest("info", "-n", "standby", "/dev/sda", "-json")
+	a.Equal(exitOK, code)
+	a.True(json.Valid([]byte(stdout)))
+
+	code, _, stderr := runTest("info", "/dev/sda", "-n", "bogus")
+	a.Equal(exitUsage, code)
+	a.Contains(stderr, "unknown power policy")
+
+	code, _, stderr = runTest("info", "/dev/sda", "-json", "/dev/sdb")
+	a.Equal(exitUsage, code)
+	a.Contains(stderr, "one device is required")
+}
+
+func TestRunHealth(t *testing.T) {
+	a := assert.New(t)
+
+	fakeOpen(t, &fakeDevice{path: "/dev/sda"}, nil)
+
+	summary := &smartgo.HealthSummary{
+		Verdict:       smartgo.HealthPassed,
+		VerdictSource: "SMART RETURN STATUS",
+		Temperature:   smartgo.Metric{Value: 38, Known: true, Source: "SMART attribute 194 (Temperature_Celsius)"},
+	}
+	fakeSummarize(t, summary, nil)
+
+	code, stdout, _ := runTest("health", "/dev/sda")
+	a.Equal(exitOK, code)
+	a.Contains(stdout, "Health:  PASSED (SMART RETURN STATUS)\n")
+
+	summary.Verdict = smartgo.HealthFailed
+
+	code, stdout, _ = runTest("health", "-json", "/dev/sda")
+	a.Equal(exitFailing, code)
+
+	decoded := struct {
+		Health map[string]interface{}
+	}{}
+	a.NoError(json.Unmarshal([]byte(stdout), &decoded))
+	a.Equal("FAILED", decoded.Health["verdict"])
+
+	fakeSummarize(t, nil, errors.New("io error"))
+
+	code, stdout, stderr := runTest("health", "/dev/sda")
+	a.Equal(exitCommand, code)
+	a.Empty(stdout)
+	a.Contains(stderr, "/dev/sda: io error")
+}
+
+func TestRunAttributes(t *testing.T) {
+	a := assert.New(t)
+
+	fakeOpen(t, &fakeATA{&fakeDevice{path: "/dev/sda"}}, nil)
+
+	code, stdout, _ := runTest("attributes", "/dev/sda")
+	a.Equal(exitOK, code)
+	a.Contains(stdout, "9    Power_On_Hours  0x0032  091    091    000     Old_age  Always   -            7500\n")
+
+	fakeOpen(t, &fakeNVMe{&fakeDevice{path: "/dev/nvme0"}}, nil)
+
+	code, stdout, _ = runTest("attributes", "/dev/nvme0")
+	a.Equal(exitOK, code)
+	a.Contains(stdout, "Temperature:                      37 Celsius\n")
+	a.Contains(stdout, "Percentage Used:                  3%\n")
+}
+
+func TestRunLogs(t *testing.T) {
+	a := assert.New(t)
+
+	fakeOpen(t, &fakeATA{&fakeDevice{path: "/dev/sda"}}, nil)
+
+	// the SMART logs are read after the extended logs failed
+	code, stdout, stderr := runTest("logs", "/dev/sda")
+	a.Equal(exitOK, code)
+	a.Empty(stderr)
+	a.Contains(stdout, "Self-test log (revision 1)\n")
+	a.Contains(stdout, "Error log (version 1), 0 errors counted by the device\n")
+
+	fakeOpen(t, &fakeNVMe{&fakeDevice{path: "/dev/nvme0"}}, nil)
+
+	// the log read is rendered with the failure of the other log
+	code, stdout, stderr = runTest("logs", "-json", "/dev/nvme0")
+	a.Equal(exitCommand, code)
+	a.Contains(stderr, "/dev/nvme0: error log: invalid log page")
+
+	decoded := &logsReport{}
+	a.NoError(json.Unmarshal([]byte(stdout), decoded))
+	a.NotNil(decoded.NVMeSelfTest)
+	a.Nil(decoded.NVMeErrors)
+	a.Equal(map[string]string{"error": "invalid log page"}, decoded.Errors)
+}
+
+func TestRunInfoNVMe(t *testing.T) {
+	a := assert.New(t)
+
+	fakeOpen(t, &fakeNVMe{&fakeDevice{path: "/dev/nvme0"}}, nil)
+
+	code, stdout, _ := runTest("info", "/dev/nvme0")
+	a.Equal(exitOK, code)
+	a.Contains(stdout, "Firmware slots (active 1, next reset -, activation without reset no)\n")
+}
+
+func TestRunScan(t *testing.T) {
+	a := assert.New(t)
+
+	sda, nvme0 := &fakeDevice{path: "/dev/sda"}, &fakeNVMe{&fakeDevice{path: "/dev/nvme0"}}
+
+	result := &smartgo.ScanResult{
+		Devices: map[string]smartgo.Device{"wwn-0x5000c500a1b2c3d4": sda, "nvme-0": nvme0},
+		Skipped: map[string]*smartgo.SkippedError{
+			"/dev/sdb": {Device: "/dev/sdb", Mode: smartgo.PowerModeStandby, Policy: smartgo.PowerPolicyStandby},
+		},
+		Errors: map[string]error{},
+	}
+	fakeScan(t, result, nil)
+
+	code, stdout, _ := runTest("scan", "-n", "standby")
+	a.Equal(exitOK, code)
+	a.Contains(stdout, "/dev/nvme0  sata  ST4000NM0035-1V4")
+	a.Contains(stdout, "/dev/sdb: skipped, device is in STANDBY mode (power policy: standby)\n")
+	a.True(sda.closed)
+	a.True(nvme0.closed)
+
+	result.Errors["/dev/sdc"] = errors.New("permission denied")
+
+	code, stdout, _ = runTest("scan", "-json")
+	a.Equal(exitCommand, code)
+
+	decoded := &scanReport{}
+	a.NoError(json.Unmarshal([]byte(stdout), decoded))
+	a.Len(decoded.Devices, 2)
+	a.Equal("/dev/nvme0", decoded.Devices[0].Device)
+	a.Equal([]scanIssue{{Device: "/dev/sdc", Error: "permission denied"}}, decoded.Errors)
+
+	fakeScan(t, nil, errors.New("no sysfs"))
+
+	code, stdout, stderr := runTest("scan")
+	a.Equal(exitCommand, code)
+	a.Empty(stdout)
+	a.Contains(stderr, "no sysfs")
+}
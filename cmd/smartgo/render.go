@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// report is the output of a subcommand. The report is encoded as it is for
+// JSON, and writes the tab separated text aligned by render for the human.
+type report interface {
+	writeText(w io.Writer)
+}
+
+func render(w io.Writer, r report, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(r)
+	}
+
+	// the columns are aligned in each block of the lines separated by the
+	// blank line
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	r.writeText(tw)
+
+	return tw.Flush()
+}
+
+type field struct {
+	name  string
+	value string
+}
+
+// writeFields writes the "name: value" lines, the empty value is skipped.
+func writeFields(w io.Writer, fields ...field) {
+	for _, f := range fields {
+		if f.value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", f.name, f.value)
+		}
+	}
+}
+
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func newTable(header ...string) *table {
+	return &table{header: header}
+}
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+func (t *table) write(w io.Writer) {
+	fmt.Fprintln(w, strings.Join(t.header, "\t"))
+
+	for _, row := range t.rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+}
+
+// section starts the block with the title after the blank line.
+func section(w io.Writer, format string, args ...interface{}) {
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, format+"\n", args...)
+}
+
+var byteUnits = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+
+// humanBytes formats the bytes in the decimal units like the disk label.
+func humanBytes(bytes uint64) string {
+	if bytes < 1000 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	value, unit := float64(bytes), 0
+	for value >= 1000 && unit < len(byteUnits)-1 {
+		value /= 1000
+		unit++
+	}
+
+	return fmt.Sprintf("%.2f %s", value, byteUnits[unit])
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
+
+// orDash shows the empty cell as "-" to keep the column.
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sungup/smartgo"
+)
+
+func TestHumanBytes(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("0 B", humanBytes(0))
+	a.Equal("512 B", humanBytes(512))
+	a.Equal("1.00 kB", humanBytes(1000))
+	a.Equal("1.00 TB", humanBytes(1000204886016))
+	a.Equal("18.45 EB", humanBytes(^uint64(0)))
+}
+
+func TestRenderTable(t *testing.T) {
+	a := assert.New(t)
+
+	r := &scanReport{
+		Devices: []scanEntry{
+			{Device: "/dev/nvme0", Type: smartgo.NVMe, Model: "Samsung SSD 970 EVO Plus 1TB", Serial: "S4EWNX0N123456", Firmware: "2B2QEXM7", Capacity: 1000204886016},
+			{Device: "/dev/sda", Type: smartgo.SATA, Capacity: 4000787030016},
+		},
+		Skipped: []scanIssue{{Device: "/dev/sdb", Error: "/dev/sdb: skipped, device is in STANDBY mode (power policy: standby)"}},
+		Errors:  []scanIssue{{Device: "/dev/sdc", Error: "permission denied"}},
+	}
+
+	buf := new(bytes.Buffer)
+	a.NoError(render(buf, r, false))
+	a.Equal(""+
+		"DEVICE      TYPE  MODEL                         SERIAL          FIRMWARE  CAPACITY\n"+
+		"/dev/nvme0  nvme  Samsung SSD 970 EVO Plus 1TB  S4EWNX0N123456  2B2QEXM7  1.00 TB\n"+
+		"/dev/sda    sata  -                             -               -         4.00 TB\n"+
+		"/dev/sdb: skipped, device is in STANDBY mode (power policy: standby)\n"+
+		"/dev/sdc: permission denied\n", buf.String())
+
+	buf.Reset()
+	a.NoError(render(buf, r, true))
+
+	decoded := &scanReport{}
+	a.NoError(json.Unmarshal(buf.Bytes(), decoded))
+	a.Equal(r.Devices, decoded.Devices)
+	a.Equal(r.Errors, decoded.Errors)
+}
+
+func TestRenderHealth(t *testing.T) {
+	a := assert.New(t)
+
+	r := &healthReport{
+		Device: "/dev/nvme0",
+		Health: &smartgo.HealthSummary{
+			Verdict:       smartgo.HealthFailed,
+			VerdictSource: "SMART / Health Information log (02h) Critical Warning",
+			Temperature:   smartgo.Metric{Value: 37, Known: true, Source: "SMART / Health Information log (02h) Composite Temperature"},
+			BytesWritten:  smartgo.Metric{Value: 512000, Known: true, Source: "SMART / Health Information log (02h) Data Units Written"},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	a.NoError(render(buf, r, false))
+
+	text := buf.String()
+	a.Contains(text, "Health:  FAILED (SMART / Health Information log (02h) Critical Warning)\n")
+	a.Contains(text, "Temperature (Celsius)  37                  SMART / Health Information log (02h) Composite Temperature\n")
+	a.Contains(text, "Bytes Written          512000 [512.00 kB]  ")
+	a.Contains(text, "Pending Sectors        unknown             -\n")
+
+	buf.Reset()
+	a.NoError(render(buf, r, true))
+
+	decoded := struct {
+		Health map[string]interface{}
+	}{}
+	a.NoError(json.Unmarshal(buf.Bytes(), &decoded))
+	a.Equal("FAILED", decoded.Health["verdict"])
+	a.Nil(decoded.Health["pending_sectors"])
+}
+
+func TestRenderAttributes(t *testing.T) {
+	a := assert.New(t)
+
+	r := &attributesReport{
+		Device: "/dev/sda",
+		Attributes: []smartgo.SmartAttribute{
+			{ID: 5, Name: "Reallocated_Sector_Ct", Flags: 0x0033, Current: 100, Worst: 100, Threshold: 10},
+			{ID: 10, Name: "Spin_Retry_Count", Flags: 0x0013, Current: 20, Worst: 20, Threshold: 97, Status: smartgo.AttrFailingNow},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	a.NoError(render(buf, r, false))
+	a.Contains(buf.String(), "5    Reallocated_Sector_Ct  0x0033  100    100    010     Pre-fail  Always   -            0\n")
+	a.Contains(buf.String(), "FAILING_NOW")
+
+	buf.Reset()
+	a.NoError(render(buf, r, true))
+	a.Contains(buf.String(), `"Status": "FAILING_NOW"`)
+	a.NotContains(buf.String(), "smart_log")
+}
+
+func TestRenderInfoFirmware(t *testing.T) {
+	a := assert.New(t)
+
+	r := &infoReport{
+		Device:   "/dev/nvme0",
+		Type:     smartgo.NVMe,
+		Firmware: "2B2QEXM7",
+		NVMe:     &smartgo.NVMeController{FirmwareSlots: 3},
+		NVMeFirmware: &smartgo.NVMeFirmwareInventory{
+			ActiveSlot: 1,
+			NextSlot:   2,
+			Slots: []smartgo.NVMeFirmwareSlot{
+				{Slot: 1, Revision: "2B2QEXM7", ReadOnly: true, Active: true},
+				{Slot: 2, Revision: "3B2QEXM7", NextActive: true},
+				{Slot: 3},
+			},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	a.NoError(render(buf, r, false))
+	a.Contains(buf.String(), ""+
+		"Firmware slots (active 1, next reset 2, activation without reset no)\n"+
+		"SLOT  REVISION  READ_ONLY  STATE\n"+
+		"1     2B2QEXM7  yes        active\n"+
+		"2     3B2QEXM7  no         next reset\n"+
+		"3     -         no         -\n")
+
+	buf.Reset()
+	a.NoError(render(buf, r, true))
+
+	decoded := struct {
+		NVMeFirmware struct {
+			ActiveSlot int
+			NextSlot   int
+			Slots      []struct {
+				Slot     int
+				Revision string
+			}
+		} `json:"nvme_firmware"`
+	}{}
+	a.NoError(json.Unmarshal(buf.Bytes(), &decoded))
+	a.Equal(1, decoded.NVMeFirmware.ActiveSlot)
+	a.Equal(2, decoded.NVMeFirmware.NextSlot)
+	a.Len(decoded.NVMeFirmware.Slots, 3)
+	a.Equal("3B2QEXM7", decoded.NVMeFirmware.Slots[1].Revision)
+}
+
+func TestRenderLogs(t *testing.T) {
+	a := assert.New(t)
+
+	r := &logsReport{
+		Device: "/dev/sda",
+		ATASelfTest: &smartgo.AtaSelfTestLog{Revision: 1, Entries: []smartgo.AtaSelfTestEntry{
+			{Test: smartgo.AtaSelfTest(0x02), Status: smartgo.SelfTestStatus(0x73), LifeTime: 7510},
+			{Test: smartgo.AtaSelfTest(0x01), LifeTime: 7500},
+		}},
+		ATAErrors: &smartgo.AtaErrorLog{Version: 1, ErrorCount: 3, Entries: []smartgo.AtaErrorLogEntry{{
+			Number:   3,
+			Commands: []smartgo.AtaErrorCommand{{Command: 0x60}},
+			Error:    smartgo.AtaErrorData{Error: 0x40, Status: 0x51, LBA: 123456, State: 0x01, LifeTime: 7400},
+		}}},
+	}
+
+	buf := new(bytes.Buffer)
+	a.NoError(render(buf, r, false))
+
+	text := buf.String()
+	a.Contains(text, "Device:  /dev/sda\n\nSelf-test log (revision 1)\n")
+	a.Contains(text, "1    Extended offline  Completed: read failure  30%        7510             -\n")
+	a.Contains(text, "2    Short offline     Completed without error  0%         7500             -\n")
+	a.Contains(text, "\nError log (version 1), 3 errors counted by the device\n")
+	a.Contains(text, "3    7400             Sleep  0x40   0x51    123456  READ FPDMA QUEUED\n")
+
+	r = &logsReport{
+		Device: "/dev/nvme0",
+		NVMeSelfTest: &smartgo.NVMeSelfTestLog{
+			Current:    smartgo.NVMeSelfTestCode(0x2),
+			Completion: 40,
+			Results:    []smartgo.NVMeSelfTestResult{{Code: smartgo.NVMeSelfTestCode(0x1), Result: smartgo.NVMeSelfTestResultCode(0x7), Segment: 3, PowerOnHour: 1234}},
+		},
+		NVMeErrors: []smartgo.NVMeErrorEntry{{ErrorCount: 12, SQID: 1, CommandID: 0x1a, Status: smartgo.NVMeStatus(0x0281), LBA: 4096, NSID: 1}},
+	}
+
+	buf.Reset()
+	a.NoError(render(buf, r, false))
+
+	text = buf.String()
+	a.Contains(text, "Extended self-test in progress, 40% completed\n")
+	a.Contains(text, "1    Short  Completed with one or more failed segments  3        1234            -     -\n")
+	a.Contains(text, "\nError information log\n")
+	a.Contains(text, "12           1     0x001a  ")
+	a.NotContains(text, "Self-test log (revision")
+}
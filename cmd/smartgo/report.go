@@ -0,0 +1,451 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/sungup/smartgo"
+)
+
+type scanEntry struct {
+	ID       string             `json:"id"`
+	Device   string             `json:"device"`
+	Type     smartgo.DeviceType `json:"type"`
+	Model    string             `json:"model"`
+	Serial   string             `json:"serial"`
+	Firmware string             `json:"firmware"`
+	Capacity uint64             `json:"capacity"`
+}
+
+type scanIssue struct {
+	Device string `json:"device"`
+	Error  string `json:"error"`
+}
+
+// scanReport lists the scanned devices sorted by the device path.
+type scanReport struct {
+	Devices []scanEntry `json:"devices"`
+	Skipped []scanIssue `json:"skipped"`
+	Errors  []scanIssue `json:"errors"`
+}
+
+func newScanReport(result *smartgo.ScanResult) *scanReport {
+	r := &scanReport{
+		Devices: make([]scanEntry, 0, len(result.Devices)),
+		Skipped: make([]scanIssue, 0, len(result.Skipped)),
+		Errors:  make([]scanIssue, 0, len(result.Errors)),
+	}
+
+	for id, device := range result.Devices {
+		r.Devices = append(r.Devices, scanEntry{
+			ID:       id,
+			Device:   device.Device(),
+			Type:     device.Type(),
+			Model:    device.Model(),
+			Serial:   device.Serial(),
+			Firmware: device.Firmware(),
+			Capacity: device.Capacity(),
+		})
+	}
+
+	for path, skipped := range result.Skipped {
+		r.Skipped = append(r.Skipped, scanIssue{Device: path, Error: skipped.Error()})
+	}
+
+	for path, err := range result.Errors {
+		r.Errors = append(r.Errors, scanIssue{Device: path, Error: err.Error()})
+	}
+
+	sort.Slice(r.Devices, func(i, j int) bool { return r.Devices[i].Device < r.Devices[j].Device })
+	sort.Slice(r.Skipped, func(i, j int) bool { return r.Skipped[i].Device < r.Skipped[j].Device })
+	sort.Slice(r.Errors, func(i, j int) bool { return r.Errors[i].Device < r.Errors[j].Device })
+
+	return r
+}
+
+func (r *scanReport) writeText(w io.Writer) {
+	t := newTable("DEVICE", "TYPE", "MODEL", "SERIAL", "FIRMWARE", "CAPACITY")
+
+	for _, dev := range r.Devices {
+		t.add(dev.Device, string(dev.Type), orDash(dev.Model), orDash(dev.Serial), orDash(dev.Firmware), humanBytes(dev.Capacity))
+	}
+
+	t.write(w)
+
+	for _, issue := range r.Skipped {
+		fmt.Fprintln(w, issue.Error)
+	}
+
+	for _, issue := range r.Errors {
+		fmt.Fprintf(w, "%s: %s\n", issue.Device, issue.Error)
+	}
+}
+
+// infoReport has the identity of the device, the identify data of the
+// protocol and the firmware slots of the NVMe controller.
+type infoReport struct {
+	Device   string                  `json:"device"`
+	Type     smartgo.DeviceType      `json:"type"`
+	Model    string                  `json:"model"`
+	Serial   string                  `json:"serial"`
+	Firmware string                  `json:"firmware"`
+	ID       string                  `json:"id"`
+	Capacity uint64                  `json:"capacity"`
+	Features []smartgo.Capability    `json:"features"`
+	ATA      *smartgo.AtaIdentity    `json:"ata,omitempty"`
+	NVMe     *smartgo.NVMeController `json:"nvme,omitempty"`
+
+	NVMeFirmware *smartgo.NVMeFirmwareInventory `json:"nvme_firmware,omitempty"`
+}
+
+func newInfoReport(device smartgo.Device) *infoReport {
+	r := &infoReport{
+		Device:   device.Device(),
+		Type:     device.Type(),
+		Model:    device.Model(),
+		Serial:   device.Serial(),
+		Firmware: device.Firmware(),
+		ID:       device.ID(),
+		Capacity: device.Capacity(),
+		Features: device.Features(),
+	}
+
+	switch dev := device.(type) {
+	case *smartgo.ATADevice:
+		r.ATA = dev.Identity()
+	case *smartgo.NVMeDevice:
+		r.NVMe = dev.Controller()
+	}
+
+	return r
+}
+
+func rotationRate(rpm uint16) string {
+	switch rpm {
+	case 0:
+		return ""
+	case 1:
+		return "Solid State Device"
+	}
+
+	return fmt.Sprintf("%d rpm", rpm)
+}
+
+// kelvin formats the temperature threshold of the controller, 0 is not
+// reported.
+func kelvin(temp uint16) string {
+	if temp == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%d Celsius", int(temp)-273)
+}
+
+func (r *infoReport) writeText(w io.Writer) {
+	writeFields(w,
+		field{"Device", r.Device},
+		field{"Type", string(r.Type)},
+		field{"Model", r.Model},
+		field{"Serial", r.Serial},
+		field{"Firmware", r.Firmware},
+		field{"ID", r.ID},
+		field{"Capacity", fmt.Sprintf("%d bytes [%s]", r.Capacity, humanBytes(r.Capacity))},
+	)
+
+	if id := r.ATA; id != nil {
+		writeFields(w,
+			field{"Rotation Rate", rotationRate(id.RotationRate)},
+			field{"Form Factor", id.FormFactor},
+			field{"Sector Sizes", fmt.Sprintf("%d bytes logical, %d bytes physical", id.LogicalSize, id.PhysicalSize)},
+			field{"ATA Version", id.MajorVersion},
+			field{"SATA Version", id.SATAVersion},
+		)
+	}
+
+	if ctrl := r.NVMe; ctrl != nil {
+		writeFields(w,
+			field{"NVMe Version", ctrl.VersionString()},
+			field{"Namespaces", strconv.Itoa(int(ctrl.NumNamespaces))},
+			field{"Firmware Slots", strconv.Itoa(int(ctrl.FirmwareSlots))},
+			field{"Warning Temperature", kelvin(ctrl.WarningTemp)},
+			field{"Critical Temperature", kelvin(ctrl.CriticalTemp)},
+		)
+	}
+
+	if inv := r.NVMeFirmware; inv != nil {
+		writeFirmwareSlots(w, inv)
+	}
+
+	if len(r.Features) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+
+	t := newTable("FEATURE", "SUPPORTED", "ENABLED")
+	for _, c := range r.Features {
+		t.add(c.Name, yesNo(c.Supported), yesNo(c.Enabled))
+	}
+
+	t.write(w)
+}
+
+// firmwareSlotState names the slot running the firmware and the slot
+// activated at the next reset.
+func firmwareSlotState(slot *smartgo.NVMeFirmwareSlot) string {
+	switch {
+	case slot.Active && slot.NextActive:
+		return "active, next reset"
+	case slot.Active:
+		return "active"
+	case slot.NextActive:
+		return "next reset"
+	}
+
+	return "-"
+}
+
+func writeFirmwareSlots(w io.Writer, inv *smartgo.NVMeFirmwareInventory) {
+	next := "-"
+	if inv.NextSlot != 0 {
+		next = strconv.Itoa(int(inv.NextSlot))
+	}
+
+	section(w, "Firmware slots (active %d, next reset %s, activation without reset %s)",
+		inv.ActiveSlot, next, yesNo(inv.ActivateWithoutReset))
+
+	t := newTable("SLOT", "REVISION", "READ_ONLY", "STATE")
+	for i := range inv.Slots {
+		slot := &inv.Slots[i]
+
+		t.add(strconv.Itoa(int(slot.Slot)), orDash(slot.Revision), yesNo(slot.ReadOnly), firmwareSlotState(slot))
+	}
+
+	t.write(w)
+}
+
+type healthReport struct {
+	Device string                 `json:"device"`
+	Health *smartgo.HealthSummary `json:"health"`
+}
+
+func bytesMetric(m smartgo.Metric) string {
+	if !m.Known {
+		return m.String()
+	}
+
+	return fmt.Sprintf("%d [%s]", m.Value, humanBytes(uint64(m.Value)))
+}
+
+func (r *healthReport) writeText(w io.Writer) {
+	h := r.Health
+
+	writeFields(w,
+		field{"Device", r.Device},
+		field{"Health", fmt.Sprintf("%s (%s)", h.Verdict, h.VerdictSource)},
+	)
+
+	fmt.Fprintln(w)
+
+	t := newTable("METRIC", "VALUE", "SOURCE")
+	row := func(name string, m smartgo.Metric, value string) {
+		t.add(name, value, orDash(m.Source))
+	}
+
+	row("Temperature (Celsius)", h.Temperature, h.Temperature.String())
+	row("Power-On Hours", h.PowerOnHours, h.PowerOnHours.String())
+	row("Power Cycles", h.PowerCycles, h.PowerCycles.String())
+	row("Unsafe Shutdowns", h.UnsafeShutdowns, h.UnsafeShutdowns.String())
+	row("Media Errors", h.MediaErrors, h.MediaErrors.String())
+	row("Reallocated Sectors", h.ReallocatedSectors, h.ReallocatedSectors.String())
+	row("Pending Sectors", h.PendingSectors, h.PendingSectors.String())
+	row("Percent Used", h.PercentUsed, h.PercentUsed.String())
+	row("Bytes Written", h.BytesWritten, bytesMetric(h.BytesWritten))
+	row("Bytes Read", h.BytesRead, bytesMetric(h.BytesRead))
+
+	t.write(w)
+}
+
+// attributesReport has the SMART attributes of the ATA device or the SMART /
+// Health Information log of the NVMe device.
+type attributesReport struct {
+	Device     string                   `json:"device"`
+	Attributes []smartgo.SmartAttribute `json:"attributes,omitempty"`
+	SmartLog   *smartgo.NVMeSmartLog    `json:"smart_log,omitempty"`
+}
+
+func attrType(attr *smartgo.SmartAttribute) string {
+	if attr.PreFail() {
+		return "Pre-fail"
+	}
+
+	return "Old_age"
+}
+
+func attrUpdated(attr *smartgo.SmartAttribute) string {
+	if attr.Online() {
+		return "Always"
+	}
+
+	return "Offline"
+}
+
+func attrWhenFailed(attr *smartgo.SmartAttribute) string {
+	if attr.Status == smartgo.AttrOK {
+		return "-"
+	}
+
+	return attr.Status.String()
+}
+
+func (r *attributesReport) writeText(w io.Writer) {
+	if r.SmartLog != nil {
+		r.writeSmartLog(w)
+		return
+	}
+
+	t := newTable("ID#", "ATTRIBUTE_NAME", "FLAG", "VALUE", "WORST", "THRESH", "TYPE", "UPDATED", "WHEN_FAILED", "RAW_VALUE")
+
+	for i := range r.Attributes {
+		attr := &r.Attributes[i]
+
+		t.add(
+			strconv.Itoa(int(attr.ID)), attr.Name, fmt.Sprintf("0x%04x", attr.Flags),
+			fmt.Sprintf("%03d", attr.Current), fmt.Sprintf("%03d", attr.Worst), fmt.Sprintf("%03d", attr.Threshold),
+			attrType(attr), attrUpdated(attr), attrWhenFailed(attr), attr.RawString(),
+		)
+	}
+
+	t.write(w)
+}
+
+func (r *attributesReport) writeSmartLog(w io.Writer) {
+	log := r.SmartLog
+
+	dataUnits := func(units smartgo.Uint128, bytes smartgo.Uint128) string {
+		return fmt.Sprintf("%s [%s]", units, humanBytes(bytes.Uint64()))
+	}
+
+	writeFields(w,
+		field{"Critical Warning", fmt.Sprintf("0x%02x", log.CriticalWarning)},
+		field{"Temperature", fmt.Sprintf("%d Celsius", log.TemperatureCelsius())},
+		field{"Available Spare", fmt.Sprintf("%d%%", log.AvailableSpare)},
+		field{"Available Spare Threshold", fmt.Sprintf("%d%%", log.AvailableSpareThreshold)},
+		field{"Percentage Used", fmt.Sprintf("%d%%", log.PercentageUsed)},
+		field{"Data Units Read", dataUnits(log.DataUnitsRead, log.BytesRead())},
+		field{"Data Units Written", dataUnits(log.DataUnitsWritten, log.BytesWritten())},
+		field{"Host Read Commands", log.HostReadCommands.String()},
+		field{"Host Write Commands", log.HostWriteCommands.String()},
+		field{"Controller Busy Time", log.ControllerBusyTime.String()},
+		field{"Power Cycles", log.PowerCycles.String()},
+		field{"Power On Hours", log.PowerOnHours.String()},
+		field{"Unsafe Shutdowns", log.UnsafeShutdowns.String()},
+		field{"Media and Data Integrity Errors", log.MediaErrors.String()},
+		field{"Error Information Log Entries", log.ErrorLogEntries.String()},
+		field{"Warning Comp. Temperature Time", strconv.Itoa(int(log.WarningTempTime))},
+		field{"Critical Comp. Temperature Time", strconv.Itoa(int(log.CriticalTempTime))},
+	)
+
+	for i, temp := range log.SensorsCelsius() {
+		writeFields(w, field{fmt.Sprintf("Temperature Sensor %d", i+1), fmt.Sprintf("%d Celsius", temp)})
+	}
+}
+
+// logsReport has the self-test and the error logs of the protocol. The error
+// of each log failed to read is kept for JSON, the text goes to stderr.
+type logsReport struct {
+	Device       string                   `json:"device"`
+	ATASelfTest  *smartgo.AtaSelfTestLog  `json:"ata_self_test,omitempty"`
+	ATAErrors    *smartgo.AtaErrorLog     `json:"ata_errors,omitempty"`
+	NVMeSelfTest *smartgo.NVMeSelfTestLog `json:"nvme_self_test,omitempty"`
+	NVMeErrors   []smartgo.NVMeErrorEntry `json:"nvme_errors,omitempty"`
+	Errors       map[string]string        `json:"errors,omitempty"`
+}
+
+func lbaCell(valid bool, lba uint64) string {
+	if !valid {
+		return "-"
+	}
+
+	return strconv.FormatUint(lba, 10)
+}
+
+func (r *logsReport) writeText(w io.Writer) {
+	writeFields(w, field{"Device", r.Device})
+
+	if log := r.ATASelfTest; log != nil {
+		section(w, "Self-test log (revision %d)", log.Revision)
+
+		t := newTable("NUM", "TEST", "STATUS", "REMAINING", "LIFETIME(HOURS)", "LBA_OF_FIRST_ERROR")
+		for i := range log.Entries {
+			entry := &log.Entries[i]
+
+			t.add(strconv.Itoa(i+1), entry.Test.String(), entry.Status.String(),
+				fmt.Sprintf("%d%%", entry.Status.Remaining()), strconv.Itoa(int(entry.LifeTime)),
+				lbaCell(entry.FailingLBAValid(), entry.FailingLBA))
+		}
+
+		t.write(w)
+	}
+
+	if log := r.ATAErrors; log != nil {
+		section(w, "Error log (version %d), %d errors counted by the device", log.Version, log.ErrorCount)
+
+		t := newTable("NUM", "LIFETIME(HOURS)", "STATE", "ERROR", "STATUS", "LBA", "COMMAND")
+		for _, entry := range log.Entries {
+			command := "-"
+			if len(entry.Commands) != 0 {
+				command = entry.Commands[0].Name()
+			}
+
+			t.add(strconv.Itoa(entry.Number), strconv.Itoa(int(entry.Error.LifeTime)), entry.Error.StateName(),
+				fmt.Sprintf("0x%02x", entry.Error.Error), fmt.Sprintf("0x%02x", entry.Error.Status),
+				strconv.FormatUint(entry.Error.LBA, 10), command)
+		}
+
+		t.write(w)
+	}
+
+	if log := r.NVMeSelfTest; log != nil {
+		section(w, "Self-test log")
+
+		if log.InProgress() {
+			fmt.Fprintf(w, "%s self-test in progress, %d%% completed\n", log.Current, log.Completion)
+		}
+
+		t := newTable("NUM", "TEST", "RESULT", "SEGMENT", "POWER_ON_HOURS", "NSID", "FAILING_LBA")
+		for i := range log.Results {
+			result := &log.Results[i]
+
+			nsid := "-"
+			if result.NSIDValid() {
+				nsid = strconv.FormatUint(uint64(result.NSID), 10)
+			}
+
+			segment := "-"
+			if result.Segment != 0 {
+				segment = strconv.Itoa(int(result.Segment))
+			}
+
+			t.add(strconv.Itoa(i+1), result.Code.String(), result.Result.String(), segment,
+				strconv.FormatUint(result.PowerOnHour, 10), nsid, lbaCell(result.FailingLBAValid(), result.FailingLBA))
+		}
+
+		t.write(w)
+	}
+
+	if r.NVMeErrors != nil {
+		section(w, "Error information log")
+
+		t := newTable("ERROR_COUNT", "SQID", "CMD_ID", "STATUS", "LBA", "NSID")
+		for _, entry := range r.NVMeErrors {
+			t.add(strconv.FormatUint(entry.ErrorCount, 10), strconv.Itoa(int(entry.SQID)),
+				fmt.Sprintf("0x%04x", entry.CommandID), entry.Status.String(),
+				strconv.FormatUint(entry.LBA, 10), strconv.FormatUint(uint64(entry.NSID), 10))
+		}
+
+		t.write(w)
+	}
+}
@@ -0,0 +1,231 @@
+package smartgo
+
+import (
+	"github.com/sungup/smartgo/internal"
+)
+
+// DeviceType is the protocol used to talk to the device.
+type DeviceType = internal.DeviceType
+
+const (
+	NVMe = internal.NVMe
+	SATA = internal.SATA
+	SCSI = internal.SCSI // SAS, FC and the other SCSI disks
+	USB  = internal.USB  // opened as SATA through the SAT layer of the bridge
+)
+
+type (
+	HealthStatus    = internal.HealthStatus
+	AttributeStatus = internal.AttributeStatus
+	PowerMode       = internal.PowerMode
+	PowerPolicy     = internal.PowerPolicy
+	Capability      = internal.Capability
+	SkippedError    = internal.SkippedError
+	DriveDB         = internal.DriveDB
+	Uint128         = internal.Uint128
+
+	HealthSummary = internal.HealthSummary
+	Metric        = internal.Metric
+)
+
+const (
+	HealthUnknown = internal.HealthUnknown
+	HealthPassed  = internal.HealthPassed
+	HealthFailed  = internal.HealthFailed
+
+	AttrOK           = internal.AttrOK
+	AttrFailedInPast = internal.AttrFailedInPast
+	AttrFailingNow   = internal.AttrFailingNow
+
+	PowerModeUnknown = internal.PowerModeUnknown
+	PowerModeActive  = internal.PowerModeActive
+	PowerModeIdle    = internal.PowerModeIdle
+	PowerModeStandby = internal.PowerModeStandby
+	PowerModeSleep   = internal.PowerModeSleep
+
+	PowerPolicyNever   = internal.PowerPolicyNever
+	PowerPolicySleep   = internal.PowerPolicySleep
+	PowerPolicyStandby = internal.PowerPolicyStandby
+	PowerPolicyIdle    = internal.PowerPolicyIdle
+
+	NVMeNSIDAll = internal.NVMeNSIDAll // controller wide log of all namespaces
+)
+
+// ATA device and its data
+type (
+	ATADevice        = internal.SATADevice
+	AtaIdentity      = internal.AtaIdentity
+	Capabilities     = internal.Capabilities
+	FeatureState     = internal.FeatureState
+	SmartData        = internal.SmartData
+	SmartAttribute   = internal.SmartAttribute
+	LogDirectory     = internal.LogDirectory
+	AtaSelfTest      = internal.AtaSelfTest
+	AtaSelfTestLog   = internal.AtaSelfTestLog
+	AtaSelfTestEntry = internal.AtaSelfTestEntry
+	SelfTestStatus   = internal.SelfTestStatus
+	AtaErrorLog      = internal.AtaErrorLog
+	AtaErrorLogEntry = internal.AtaErrorLogEntry
+	AtaErrorCommand  = internal.AtaErrorCommand
+	AtaErrorData     = internal.AtaErrorData
+	DeviceStatistics = internal.DeviceStatistics
+	PhyEventCounters = internal.PhyEventCounters
+	SCTStatus        = internal.SCTStatus
+	SCTTempHistory   = internal.SCTTempHistory
+)
+
+// NVMe device and its data
+type (
+	NVMeDevice             = internal.NVMeDevice
+	NVMeController         = internal.NVMeController
+	NVMeNamespace          = internal.NVMeNamespace
+	NVMeSmartLog           = internal.NVMeSmartLog
+	NVMeErrorEntry         = internal.NVMeErrorEntry
+	NVMeFirmwareInventory  = internal.NVMeFirmwareInventory
+	NVMeFirmwareSlot       = internal.NVMeFirmwareSlot
+	NVMeSelfTestCode       = internal.NVMeSelfTestCode
+	NVMeSelfTestLog        = internal.NVMeSelfTestLog
+	NVMeSelfTestResult     = internal.NVMeSelfTestResult
+	NVMeSelfTestResultCode = internal.NVMeSelfTestResultCode
+	NVMeStatus             = internal.NVMeStatus
+)
+
+// SCSI device and its data
+type (
+	SCSIDevice         = internal.SCSIDevice
+	ScsiError          = internal.ScsiError
+	ScsiInquiry        = internal.ScsiInquiry
+	ScsiCapacity       = internal.ScsiCapacity
+	ScsiLogPage        = internal.ScsiLogPage
+	ScsiLogParam       = internal.ScsiLogParam
+	ScsiTemperature    = internal.ScsiTemperature
+	ScsiStartStop      = internal.ScsiStartStop
+	ScsiErrorCounters  = internal.ScsiErrorCounters
+	ScsiInfoExceptions = internal.ScsiInfoExceptions
+)
+
+const (
+	ScsiLogWriteErrors     = internal.ScsiLogWriteErrors
+	ScsiLogReadErrors      = internal.ScsiLogReadErrors
+	ScsiLogVerifyErrors    = internal.ScsiLogVerifyErrors
+	ScsiLogTemperature     = internal.ScsiLogTemperature
+	ScsiLogStartStop       = internal.ScsiLogStartStop
+	ScsiLogSelfTest        = internal.ScsiLogSelfTest
+	ScsiLogSolidStateMedia = internal.ScsiLogSolidStateMedia
+	ScsiLogBackgroundScan  = internal.ScsiLogBackgroundScan
+	ScsiLogInfoExceptions  = internal.ScsiLogInfoExceptions
+
+	ScsiTemperatureInvalid = internal.ScsiTemperatureInvalid
+)
+
+var (
+	ErrUnsupportedType = internal.ErrUnsupportedType
+
+	DefaultDriveDB = internal.DefaultDriveDB
+)
+
+// Device is the storage device opened by Open or found by ScanDevice. The
+// protocol specific data are available by the type assertion to *ATADevice,
+// *NVMeDevice or *SCSIDevice.
+type Device interface {
+	Type() DeviceType
+	Device() string
+	Model() string
+	Firmware() string
+	Serial() string
+	ID() string
+	Capacity() uint64
+
+	HealthStatus() (HealthStatus, error)
+	Features() []Capability
+	PowerMode() (PowerMode, error)
+	ScanSMART() error
+	Close() error
+}
+
+// the devices returned by Open and ScanDevice
+var (
+	_ Device = (*ATADevice)(nil)
+	_ Device = (*NVMeDevice)(nil)
+	_ Device = (*SCSIDevice)(nil)
+)
+
+type options struct {
+	devType DeviceType
+	policy  PowerPolicy
+	driveDB *DriveDB
+	logDMA  bool
+}
+
+type Option func(*options)
+
+// WithType opens the device by the type instead of the type found in the
+// sysfs.
+func WithType(devType DeviceType) Option {
+	return func(opts *options) {
+		opts.devType = devType
+	}
+}
+
+// WithPowerPolicy checks the power mode before identifying the device, and
+// Open returns *SkippedError for the device not to wake up.
+func WithPowerPolicy(policy PowerPolicy) Option {
+	return func(opts *options) {
+		opts.policy = policy
+	}
+}
+
+// WithDriveDB names the ATA attributes by the drive database.
+func WithDriveDB(db *DriveDB) Option {
+	return func(opts *options) {
+		opts.driveDB = db
+	}
+}
+
+// WithLogDMA reads the ATA GPL logs by READ LOG DMA EXT.
+func WithLogDMA(enable bool) Option {
+	return func(opts *options) {
+		opts.logDMA = enable
+	}
+}
+
+// Open opens and identifies the device. The device type is found in the
+// sysfs unless WithType is given.
+func Open(path string, opts ...Option) (Device, error) {
+	o := &options{policy: PowerPolicyNever}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.devType == "" {
+		o.devType = internal.DetectType(path)
+	}
+
+	device, err := internal.OpenDevice(path, o.devType, o.policy)
+	if err != nil {
+		return nil, err
+	}
+
+	if sata, ok := device.(*ATADevice); ok {
+		if o.driveDB != nil {
+			sata.SetDriveDB(o.driveDB)
+		}
+
+		sata.SetLogDMA(o.logDMA)
+	}
+
+	return device, nil
+}
+
+// ParsePowerPolicy parses the policy name, never, sleep, standby or idle.
+func ParsePowerPolicy(name string) (PowerPolicy, error) {
+	return internal.ParsePowerPolicy(name)
+}
+
+// Summarize reads the health logs of the device and normalizes them to the
+// protocol independent summary. The value not reported by the device is
+// unknown, and each known value records the log or the attribute it came
+// from.
+func Summarize(device Device) (*HealthSummary, error) {
+	return internal.Summarize(device)
+}
@@ -0,0 +1,50 @@
+package smartgo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := Open("/dev/sdz", WithType(DeviceType("fcoe")))
+	a.True(errors.Is(err, ErrUnsupportedType))
+
+	_, err = Open("/dev/not-exist", WithType(SATA))
+	a.Error(err)
+
+	_, err = Open("/dev/not-exist", WithType(SCSI))
+	a.Error(err)
+	a.False(errors.Is(err, ErrUnsupportedType))
+
+	_, err = Open("/dev/not-exist", WithType(NVMe), WithPowerPolicy(PowerPolicyStandby))
+	a.Error(err)
+
+	policy, err := ParsePowerPolicy("idle")
+	a.NoError(err)
+	a.Equal(PowerPolicyIdle, policy)
+}
+
+func TestOptions(t *testing.T) {
+	a := assert.New(t)
+
+	o := &options{}
+	for _, opt := range []Option{WithType(NVMe), WithPowerPolicy(PowerPolicySleep), WithDriveDB(DefaultDriveDB), WithLogDMA(true)} {
+		opt(o)
+	}
+
+	a.Equal(NVMe, o.devType)
+	a.Equal(PowerPolicySleep, o.policy)
+	a.Equal(DefaultDriveDB, o.driveDB)
+	a.True(o.logDMA)
+}
+
+func TestSummarizeUnsupported(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := Summarize(&fakeDevice{path: "/dev/sdf"})
+	a.True(errors.Is(err, ErrUnsupportedType))
+}
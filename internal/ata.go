@@ -1,6 +1,9 @@
 package internal
 
-import "math"
+import (
+	"fmt"
+	"math"
+)
 
 type ataProtocol uint8
 
@@ -34,14 +37,30 @@ const (
 	MultiplePos   = 5
 	MultipleUMASK = ProtocolMASK | ExtendMASK
 
+	CkCondMASK  = uint8(0x01 << 5)
+	CkCondUMASK = ^CkCondMASK
+
 	TDirMASK  = uint8(0x01 << 3)
 	TDirUMASK = ^TDirMASK
 
 	BytBlokMASK = uint8(0x01 << 2)
 	TLenMASK    = uint8((0x01 << 2) - 1)
-	TLenUMASK   = ^TLenMASK | ^BytBlokMASK
+	TLenUMASK   = ^(TLenMASK | BytBlokMASK)
+
+	// T_LENGTH field location of the transfer length
+	TLenNone    = uint8(0x00)
+	TLenFeature = uint8(0x01)
+	TLenCount   = uint8(0x02)
+	TLenTPSIU   = uint8(0x03)
 
 	OfflinePos = 6
+
+	// ACS-3 (6.2 Status field, 6.3 Error field)
+	ataStatusERR  = uint8(0x01)
+	ataStatusDF   = uint8(0x20)
+	ataStatusDRDY = uint8(0x40)
+	ataStatusBSY  = uint8(0x80)
+	ataErrorABRT  = uint8(0x04)
 )
 
 // ATA Command Pass-Through Revision 8 (p.9)
@@ -89,6 +108,20 @@ func (cdb ataCDB) getMultiple() uint8 {
 	return 0x01 << (cdb[1] >> MultiplePos)
 }
 
+// setCheckCond requests the ATA output registers returned in the sense data
+// even if the command completes without error.
+func (cdb *ataCDB) setCheckCond() {
+	cdb[2] = cdb[2] | CkCondMASK
+}
+
+func (cdb *ataCDB) unsetCheckCond() {
+	cdb[2] = cdb[2] & CkCondUMASK
+}
+
+func (cdb ataCDB) isCheckCond() bool {
+	return (cdb[2] & CkCondMASK) == CkCondMASK
+}
+
 func (cdb *ataCDB) devToHostDir() {
 	cdb[2] = cdb[2] | TDirMASK
 }
@@ -161,6 +194,9 @@ func makeSoftReset(second uint8) ataCDB {
 // Count:              01h     [3:2]
 // LBA:                02h-04h [9:4]
 // Device and Command: 05h     [11:10]
+//
+// Each word keeps the CDB byte order, previous(15:8) first and current(7:0)
+// last, so the structure maps to CDB[14:3] as is.
 type ata48BitCmd struct {
 	feature word
 	count   word
@@ -169,6 +205,89 @@ type ata48BitCmd struct {
 	command byte
 }
 
+func (cmd *ata48BitCmd) setFeature(feature uint16) {
+	cmd.feature = word{byte(feature >> 8), byte(feature)}
+}
+
+func (cmd *ata48BitCmd) setCount(count uint16) {
+	cmd.count = word{byte(count >> 8), byte(count)}
+}
+
+func (cmd *ata48BitCmd) setLBA(lba uint64) {
+	cmd.lba[0] = word{byte(lba >> 24), byte(lba)}       // LBA low:  31..24, 7..0
+	cmd.lba[1] = word{byte(lba >> 32), byte(lba >> 8)}  // LBA mid:  39..32, 15..8
+	cmd.lba[2] = word{byte(lba >> 40), byte(lba >> 16)} // LBA high: 47..40, 23..16
+}
+
+func (cdb *ataCDB) setCommand(cmd ata48BitCmd) {
+	copy(cdb[3:5], cmd.feature[:])
+	copy(cdb[5:7], cmd.count[:])
+	copy(cdb[7:9], cmd.lba[0][:])
+	copy(cdb[9:11], cmd.lba[1][:])
+	copy(cdb[11:13], cmd.lba[2][:])
+	cdb[13] = cmd.device
+	cdb[14] = cmd.command
+}
+
+func (cdb ataCDB) getCommand() uint8 {
+	return cdb[14]
+}
+
+func makeNonDataCDB(cmd ata48BitCmd) ataCDB {
+	cdb := makeAtaCDB()
+
+	cdb.setProtocol(NonData)
+	cdb.setCommand(cmd)
+
+	return cdb
+}
+
+func makePIODataInCDB(cmd ata48BitCmd) ataCDB {
+	cdb := makeAtaCDB()
+
+	cdb.setProtocol(PIODataIn)
+	cdb.devToHostDir()
+	cdb.setBlockSize(TLenCount)
+	cdb.setCommand(cmd)
+
+	return cdb
+}
+
+func makePIODataOutCDB(cmd ata48BitCmd) ataCDB {
+	cdb := makeAtaCDB()
+
+	cdb.setProtocol(PIODataOut)
+	cdb.hostToDevDir()
+	cdb.setBlockSize(TLenCount)
+	cdb.setCommand(cmd)
+
+	return cdb
+}
+
+type AtaError struct {
+	Command uint8
+	Status  uint8
+	ErrReg  uint8
+}
+
+func (e *AtaError) Error() string {
+	return fmt.Sprintf("ata command 0x%02x failed (status: 0x%02x, error: 0x%02x)", e.Command, e.Status, e.ErrReg)
+}
+
+// Aborted reports the device rejected the command, usually because the
+// command or its feature set is not supported.
+func (e *AtaError) Aborted() bool {
+	return e.ErrReg&ataErrorABRT == ataErrorABRT
+}
+
+type ataResponse struct {
+	data       []byte
+	status     uint8
+	error      uint8
+	scsiStatus uint8
+	regs       *ataRegisters // nil if the device returns no ATA registers
+}
+
 // IDENTIFY DEVICE - 0xEC, PIO Data-In
 //   FEATURE: N/A
 //   COUNT:   N/A
@@ -178,6 +297,16 @@ type ata48BitCmd struct {
 
 type SATADevice struct {
 	StorageMeta
+
+	transport Transport
+	ident     *DevIdentify
+	identity  *AtaIdentity
+	smart     *SmartData
+	drivedb   *DriveDB
+
+	logDMA      bool
+	gplDir      *LogDirectory
+	smartLogDir *LogDirectory
 }
 
 func newSATADev(path string) *SATADevice {
@@ -189,29 +318,118 @@ func newSATADev(path string) *SATADevice {
 	return sata
 }
 
-func ScanSATA(storage map[string]StorageDevice) (map[string]StorageDevice, error) {
+func (sata *SATADevice) driveDB() *DriveDB {
+	if sata.drivedb == nil {
+		return DefaultDriveDB
+	}
+
+	return sata.drivedb
+}
+
+// SetDriveDB replaces the drive database used to name the attributes.
+func (sata *SATADevice) SetDriveDB(db *DriveDB) {
+	sata.drivedb = db
+}
+
+func (sata *SATADevice) open() error {
+	if sata.transport != nil {
+		return nil
+	}
+
+	transport, err := OpenTransport(sata.devPath)
+	if err != nil {
+		return err
+	}
+
+	sata.transport = transport
+
+	return nil
+}
+
+func (sata *SATADevice) Close() error {
+	if sata.transport == nil {
+		return nil
+	}
+
+	err := sata.transport.Close()
+	sata.transport = nil
+
+	return err
+}
+
+// execute sends the ATA PASS-THROUGH(16) cdb and transfers buf in the
+// direction of the T_DIR bit. The ATA status and error registers are taken
+// from the ATA return information in the sense data when available.
+func (sata *SATADevice) execute(cdb ataCDB, buf []byte) (*ataResponse, error) {
+	if err := sata.open(); err != nil {
+		return nil, err
+	}
+
+	dir := DataNone
+	if len(buf) > 0 {
+		if cdb.isHostDir() {
+			dir = DataFromDev
+		} else {
+			dir = DataToDev
+		}
+	}
+
+	scsi, err := sata.transport.Execute(cdb[:], dir, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ataResponse{data: buf, scsiStatus: scsi.Status}
+
+	regs, hasATA := ataRegistersFromSense(scsi.Sense)
+	if hasATA {
+		resp.status, resp.error, resp.regs = regs.status, regs.error, regs
+	}
+
+	switch {
+	case hasATA && regs.status&(ataStatusERR|ataStatusDF) != 0:
+		return resp, &AtaError{Command: cdb.getCommand(), Status: regs.status, ErrReg: regs.error}
+
+	case scsi.Status != ScsiGood && !hasATA:
+		return resp, fmt.Errorf("ata pass-through 0x%02x failed (scsi status: 0x%02x, sense: % x)", cdb.getCommand(), scsi.Status, scsi.Sense)
+	}
+
+	return resp, nil
+}
+
+func (sata *SATADevice) ScanSMART() error {
+	if _, err := sata.Identify(); err != nil {
+		return err
+	}
+
+	if _, err := sata.ReadSMART(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ScanSATA appends the ATA devices found in the system to storage. The
+// devices are not opened until the first command.
+func ScanSATA(storage []StorageDevice) ([]StorageDevice, error) {
 	files, err := GetDevFiles(SATA)
 	if err != nil {
 		return storage, err
 	}
 
 	for _, file := range files {
-		sata := newSATADev(file)
-
-		/*
-			if err := sata.ScanSMART(); err != nil {
-				// this device cannot support S.M.A.R.T
-				continue
-			}
+		storage = append(storage, newSATADev(file))
+	}
 
-			if _, ok := storage[sata.Serial()]; ok {
-				continue
-			}
+	return storage, nil
+}
 
-			storage[sata.Serial()] = sata
-		*/
-		storage[sata.Device()] = sata
+// ID is the world wide name of the identified device, or the model and the
+// serial if the device does not report the WWN.
+func (sata *SATADevice) ID() string {
+	if sata.identity != nil && sata.identity.WWN != 0 {
+		return fmt.Sprintf("wwn-0x%016x", sata.identity.WWN)
 	}
 
-	return storage, nil
+	return deviceID("ata", sata.model, sata.serial)
 }
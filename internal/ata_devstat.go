@@ -0,0 +1,311 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	ataLogDevStats = 0x04
+
+	devStatSupportedPages = 0x00
+	devStatEntrySize      = 8
+	devStatValueMASK      = uint64(0x00ffffffffffffff)
+
+	// flags in the bits 63:56 of the statistic
+	DevStatSupported    = uint8(0x80)
+	DevStatValid        = uint8(0x40)
+	DevStatNormalized   = uint8(0x20)
+	DevStatDSN          = uint8(0x10)
+	DevStatConditionMet = uint8(0x08)
+	DevStatReadInit     = uint8(0x04)
+)
+
+var ErrDevStatPages = errors.New("device statistics: invalid list of supported pages")
+
+// DevStatID identifies the statistic by the page number in 23:16 and the
+// byte offset in the page in 15:0.
+type DevStatID uint32
+
+func makeDevStatID(page uint8, offset uint16) DevStatID {
+	return DevStatID(page)<<16 | DevStatID(offset)
+}
+
+func (id DevStatID) Page() uint8 {
+	return uint8(id >> 16)
+}
+
+func (id DevStatID) Offset() uint16 {
+	return uint16(id)
+}
+
+// ACS-4 9.5 Device Statistics log
+const (
+	StatPowerOnResets         = DevStatID(0x01<<16 | 0x008)
+	StatPowerOnHours          = DevStatID(0x01<<16 | 0x010)
+	StatSectorsWritten        = DevStatID(0x01<<16 | 0x018)
+	StatWriteCommands         = DevStatID(0x01<<16 | 0x020)
+	StatSectorsRead           = DevStatID(0x01<<16 | 0x028)
+	StatReadCommands          = DevStatID(0x01<<16 | 0x030)
+	StatTimestamp             = DevStatID(0x01<<16 | 0x038)
+	StatPendingErrors         = DevStatID(0x01<<16 | 0x040)
+	StatWorkloadUtilization   = DevStatID(0x01<<16 | 0x048)
+	StatUtilizationUsageRate  = DevStatID(0x01<<16 | 0x050)
+	StatFreeFallEvents        = DevStatID(0x02<<16 | 0x008)
+	StatOverlimitShocks       = DevStatID(0x02<<16 | 0x010)
+	StatSpindleHours          = DevStatID(0x03<<16 | 0x008)
+	StatHeadFlyingHours       = DevStatID(0x03<<16 | 0x010)
+	StatHeadLoadEvents        = DevStatID(0x03<<16 | 0x018)
+	StatReallocatedSectors    = DevStatID(0x03<<16 | 0x020)
+	StatReadRecoveryAttempts  = DevStatID(0x03<<16 | 0x028)
+	StatStartFailures         = DevStatID(0x03<<16 | 0x030)
+	StatReallocCandidates     = DevStatID(0x03<<16 | 0x038)
+	StatHighPriorityUnloads   = DevStatID(0x03<<16 | 0x040)
+	StatReportedUncorrectable = DevStatID(0x04<<16 | 0x008)
+	StatResetsInCommand       = DevStatID(0x04<<16 | 0x010)
+	StatPhysicalElementChange = DevStatID(0x04<<16 | 0x018)
+	StatTemperature           = DevStatID(0x05<<16 | 0x008)
+	StatAvgShortTemp          = DevStatID(0x05<<16 | 0x010)
+	StatAvgLongTemp           = DevStatID(0x05<<16 | 0x018)
+	StatHighestTemp           = DevStatID(0x05<<16 | 0x020)
+	StatLowestTemp            = DevStatID(0x05<<16 | 0x028)
+	StatHighestAvgShortTemp   = DevStatID(0x05<<16 | 0x030)
+	StatLowestAvgShortTemp    = DevStatID(0x05<<16 | 0x038)
+	StatHighestAvgLongTemp    = DevStatID(0x05<<16 | 0x040)
+	StatLowestAvgLongTemp     = DevStatID(0x05<<16 | 0x048)
+	StatOverTempTime          = DevStatID(0x05<<16 | 0x050)
+	StatMaxOperatingTemp      = DevStatID(0x05<<16 | 0x058)
+	StatUnderTempTime         = DevStatID(0x05<<16 | 0x060)
+	StatMinOperatingTemp      = DevStatID(0x05<<16 | 0x068)
+	StatHardwareResets        = DevStatID(0x06<<16 | 0x008)
+	StatASREvents             = DevStatID(0x06<<16 | 0x010)
+	StatInterfaceCRCErrors    = DevStatID(0x06<<16 | 0x018)
+	StatPercentageUsed        = DevStatID(0x07<<16 | 0x008)
+)
+
+var devStatPageNames = map[uint8]string{
+	0x00: "List of Supported Pages",
+	0x01: "General Statistics",
+	0x02: "Free-Fall Statistics",
+	0x03: "Rotating Media Statistics",
+	0x04: "General Errors Statistics",
+	0x05: "Temperature Statistics",
+	0x06: "Transport Statistics",
+	0x07: "Solid State Device Statistics",
+}
+
+type devStatDef struct {
+	name   string
+	bits   uint // width of the value
+	signed bool
+}
+
+var devStatDefs = map[DevStatID]devStatDef{
+	StatPowerOnResets:         {"Lifetime Power-On Resets", 32, false},
+	StatPowerOnHours:          {"Power-on Hours", 32, false},
+	StatSectorsWritten:        {"Logical Sectors Written", 48, false},
+	StatWriteCommands:         {"Number of Write Commands", 48, false},
+	StatSectorsRead:           {"Logical Sectors Read", 48, false},
+	StatReadCommands:          {"Number of Read Commands", 48, false},
+	StatTimestamp:             {"Date and Time TimeStamp", 48, false},
+	StatPendingErrors:         {"Pending Error Count", 32, false},
+	StatWorkloadUtilization:   {"Workload Utilization", 16, false},
+	StatUtilizationUsageRate:  {"Utilization Usage Rate", 8, false},
+	StatFreeFallEvents:        {"Number of Free-Fall Events Detected", 32, false},
+	StatOverlimitShocks:       {"Overlimit Shock Events", 32, false},
+	StatSpindleHours:          {"Spindle Motor Power-on Hours", 32, false},
+	StatHeadFlyingHours:       {"Head Flying Hours", 32, false},
+	StatHeadLoadEvents:        {"Head Load Events", 32, false},
+	StatReallocatedSectors:    {"Number of Reallocated Logical Sectors", 32, false},
+	StatReadRecoveryAttempts:  {"Read Recovery Attempts", 32, false},
+	StatStartFailures:         {"Number of Mechanical Start Failures", 32, false},
+	StatReallocCandidates:     {"Number of Realloc. Candidate Logical Sectors", 32, false},
+	StatHighPriorityUnloads:   {"Number of High Priority Unload Events", 32, false},
+	StatReportedUncorrectable: {"Number of Reported Uncorrectable Errors", 32, false},
+	StatResetsInCommand:       {"Resets Between Cmd Acceptance and Completion", 32, false},
+	StatPhysicalElementChange: {"Physical Element Status Changed", 32, false},
+	StatTemperature:           {"Current Temperature", 8, true},
+	StatAvgShortTemp:          {"Average Short Term Temperature", 8, true},
+	StatAvgLongTemp:           {"Average Long Term Temperature", 8, true},
+	StatHighestTemp:           {"Highest Temperature", 8, true},
+	StatLowestTemp:            {"Lowest Temperature", 8, true},
+	StatHighestAvgShortTemp:   {"Highest Average Short Term Temperature", 8, true},
+	StatLowestAvgShortTemp:    {"Lowest Average Short Term Temperature", 8, true},
+	StatHighestAvgLongTemp:    {"Highest Average Long Term Temperature", 8, true},
+	StatLowestAvgLongTemp:     {"Lowest Average Long Term Temperature", 8, true},
+	StatOverTempTime:          {"Time in Over-Temperature", 32, false},
+	StatMaxOperatingTemp:      {"Specified Maximum Operating Temperature", 8, true},
+	StatUnderTempTime:         {"Time in Under-Temperature", 32, false},
+	StatMinOperatingTemp:      {"Specified Minimum Operating Temperature", 8, true},
+	StatHardwareResets:        {"Number of Hardware Resets", 32, false},
+	StatASREvents:             {"Number of ASR Events", 32, false},
+	StatInterfaceCRCErrors:    {"Number of Interface CRC Errors", 32, false},
+	StatPercentageUsed:        {"Percentage Used Endurance Indicator", 8, false},
+}
+
+// DevStat is a statistic of the Device Statistics log.
+type DevStat struct {
+	ID    DevStatID
+	Name  string
+	Value int64
+	Flags uint8
+}
+
+func (stat *DevStat) Supported() bool {
+	return stat.Flags&DevStatSupported != 0
+}
+
+// Valid reports the value is valid, the device may support the statistic
+// but has no valid value yet.
+func (stat *DevStat) Valid() bool {
+	return stat.Flags&DevStatValid != 0
+}
+
+func (stat *DevStat) Normalized() bool {
+	return stat.Flags&DevStatNormalized != 0
+}
+
+// DSN reports the statistic supports the Device Statistics Notification.
+func (stat *DevStat) DSN() bool {
+	return stat.Flags&DevStatDSN != 0
+}
+
+func (stat *DevStat) ConditionMet() bool {
+	return stat.Flags&DevStatConditionMet != 0
+}
+
+func (stat *DevStat) ReadThenInit() bool {
+	return stat.Flags&DevStatReadInit != 0
+}
+
+type DevStatPage struct {
+	Number   uint8
+	Revision uint16
+	Name     string
+	Stats    []DevStat
+}
+
+// DeviceStatistics is the Device Statistics log (04h).
+type DeviceStatistics struct {
+	Pages []DevStatPage
+}
+
+// Stat finds the supported statistic, or nil if the device does not report.
+func (ds *DeviceStatistics) Stat(id DevStatID) *DevStat {
+	for i := range ds.Pages {
+		if ds.Pages[i].Number != id.Page() {
+			continue
+		}
+
+		for j := range ds.Pages[i].Stats {
+			if ds.Pages[i].Stats[j].ID == id {
+				return &ds.Pages[i].Stats[j]
+			}
+		}
+	}
+
+	return nil
+}
+
+// Value returns the valid value of the statistic.
+func (ds *DeviceStatistics) Value(id DevStatID) (int64, bool) {
+	if stat := ds.Stat(id); stat != nil && stat.Valid() {
+		return stat.Value, true
+	}
+
+	return 0, false
+}
+
+func devStatPageName(page uint8) string {
+	if name, ok := devStatPageNames[page]; ok {
+		return name
+	}
+
+	if page >= 0x80 {
+		return "Vendor Specific Statistics"
+	}
+
+	return fmt.Sprintf("Unknown Statistics (0x%02x)", page)
+}
+
+func parseDevStat(id DevStatID, raw uint64) DevStat {
+	stat := DevStat{ID: id, Flags: uint8(raw >> 56)}
+
+	def, ok := devStatDefs[id]
+	if !ok {
+		stat.Name = fmt.Sprintf("Unknown Statistic (0x%03x)", id.Offset())
+		stat.Value = int64(raw & devStatValueMASK)
+
+		return stat
+	}
+
+	stat.Name = def.name
+
+	value := raw & (uint64(1)<<def.bits - 1)
+	if def.signed {
+		// sign extension of the value width
+		shift := 64 - def.bits
+		stat.Value = int64(value<<shift) >> shift
+	} else {
+		stat.Value = int64(value)
+	}
+
+	return stat
+}
+
+func parseDevStatPage(number uint8, buf []byte) DevStatPage {
+	page := DevStatPage{
+		Number:   number,
+		Revision: binary.LittleEndian.Uint16(buf[0:2]),
+		Name:     devStatPageName(number),
+		Stats:    make([]DevStat, 0),
+	}
+
+	for offset := devStatEntrySize; offset < ataLogPageSize; offset += devStatEntrySize {
+		raw := binary.LittleEndian.Uint64(buf[offset:])
+		if uint8(raw>>56)&DevStatSupported == 0 {
+			continue
+		}
+
+		page.Stats = append(page.Stats, parseDevStat(makeDevStatID(number, uint16(offset)), raw))
+	}
+
+	return page
+}
+
+// parseDeviceStatistics decodes the pages listed in the supported pages,
+// the pages missing in buf or with the unexpected page number are skipped.
+func parseDeviceStatistics(buf []byte) (*DeviceStatistics, error) {
+	if len(buf) < ataLogPageSize || buf[2] != devStatSupportedPages {
+		return nil, ErrDevStatPages
+	}
+
+	ds := &DeviceStatistics{Pages: make([]DevStatPage, 0)}
+
+	count := int(buf[8])
+	for _, number := range buf[9 : 9+count] {
+		if number == devStatSupportedPages {
+			continue
+		}
+
+		pos := int(number) * ataLogPageSize
+		if pos+ataLogPageSize > len(buf) || buf[pos+2] != number {
+			continue
+		}
+
+		ds.Pages = append(ds.Pages, parseDevStatPage(number, buf[pos:pos+ataLogPageSize]))
+	}
+
+	return ds, nil
+}
+
+// ReadDeviceStatistics reads all pages of the Device Statistics log.
+func (sata *SATADevice) ReadDeviceStatistics() (*DeviceStatistics, error) {
+	buf, err := sata.ReadLog(ataLogDevStats, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDeviceStatistics(buf)
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func putDevStat(buf []byte, id DevStatID, flags uint8, value uint64) {
+	pos := int(id.Page())*ataLogPageSize + int(id.Offset())
+	binary.LittleEndian.PutUint64(buf[pos:], uint64(flags)<<56|value)
+}
+
+func sampleDeviceStatistics() []byte {
+	pages := []uint8{0x00, 0x01, 0x05, 0x06, 0x07}
+	buf := make([]byte, 8*ataLogPageSize)
+
+	for _, page := range pages {
+		binary.LittleEndian.PutUint16(buf[int(page)*ataLogPageSize:], 0x0001)
+		buf[int(page)*ataLogPageSize+2] = page
+	}
+
+	// page 03h is listed but not returned by the device
+	buf[8] = uint8(len(pages) + 1)
+	copy(buf[9:], append(pages, 0x03))
+
+	valid := DevStatSupported | DevStatValid
+
+	putDevStat(buf, StatPowerOnResets, valid, 52)
+	putDevStat(buf, StatPowerOnHours, valid, 12345)
+	putDevStat(buf, StatSectorsWritten, valid, 0x0000_1234_5678_9abc)
+	putDevStat(buf, StatPendingErrors, DevStatSupported, 0)
+	putDevStat(buf, StatTemperature, valid|DevStatDSN, 0xfb) // -5
+	putDevStat(buf, StatHighestTemp, valid, 65)
+	putDevStat(buf, StatOverTempTime, valid|DevStatConditionMet, 30)
+	putDevStat(buf, StatInterfaceCRCErrors, valid|DevStatReadInit, 3)
+	putDevStat(buf, StatPercentageUsed, valid|DevStatNormalized, 12)
+	putDevStat(buf, makeDevStatID(0x07, 0x010), valid, 99)
+
+	return buf
+}
+
+func TestParseDeviceStatistics(t *testing.T) {
+	a := assert.New(t)
+
+	ds, err := parseDeviceStatistics(sampleDeviceStatistics())
+	a.NoError(err)
+	a.Len(ds.Pages, 4)
+	a.Equal("General Statistics", ds.Pages[0].Name)
+	a.Equal(uint16(1), ds.Pages[0].Revision)
+	a.Len(ds.Pages[0].Stats, 4)
+
+	value, ok := ds.Value(StatPowerOnHours)
+	a.True(ok)
+	a.Equal(int64(12345), value)
+
+	value, _ = ds.Value(StatSectorsWritten)
+	a.Equal(int64(0x1234_5678_9abc), value)
+
+	// supported without the valid value
+	pending := ds.Stat(StatPendingErrors)
+	a.True(pending.Supported())
+	a.False(pending.Valid())
+	_, ok = ds.Value(StatPendingErrors)
+	a.False(ok)
+
+	temp := ds.Stat(StatTemperature)
+	a.Equal("Current Temperature", temp.Name)
+	a.Equal(int64(-5), temp.Value)
+	a.True(temp.DSN())
+
+	a.True(ds.Stat(StatOverTempTime).ConditionMet())
+	a.True(ds.Stat(StatInterfaceCRCErrors).ReadThenInit())
+	a.True(ds.Stat(StatPercentageUsed).Normalized())
+	a.Equal("Unknown Statistic (0x010)", ds.Stat(makeDevStatID(0x07, 0x010)).Name)
+
+	a.Nil(ds.Stat(StatHardwareResets))
+	a.Nil(ds.Stat(StatHeadFlyingHours))
+
+	_, err = parseDeviceStatistics(make([]byte, 8))
+	a.Equal(ErrDevStatPages, err)
+
+	a.Equal("Vendor Specific Statistics", devStatPageName(0xff))
+	a.Equal("Unknown Statistics (0x08)", devStatPageName(0x08))
+}
+
+func TestSATAReadDeviceStatistics(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeTransport(
+		fakeResponse{data: sampleLogDirectory(map[uint8]uint16{ataLogDevStats: 8})},
+		fakeResponse{data: sampleDeviceStatistics()},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	ds, err := sata.ReadDeviceStatistics()
+	a.NoError(err)
+	a.Len(ds.Pages, 4)
+
+	cdb := transport.lastCDB()
+	a.Equal(uint8(ataLogDevStats), cdb[8])
+	a.Equal(uint8(8), cdb[6])
+}
@@ -0,0 +1,454 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+)
+
+type RawFormat string
+
+// The raw value formats of smartctl -v option
+const (
+	RawRaw48        = RawFormat("raw48")
+	RawHex48        = RawFormat("hex48")
+	RawRaw16        = RawFormat("raw16")
+	RawRaw16Raw16   = RawFormat("raw16(raw16)")
+	RawRaw16Avg16   = RawFormat("raw16(avg16)")
+	RawRaw24Raw8    = RawFormat("raw24(raw8)")
+	RawRaw24Raw24   = RawFormat("raw24/raw24")
+	RawTempMinMax   = RawFormat("tempminmax")
+	RawTemp10x      = RawFormat("temp10x")
+	RawSec2Hour     = RawFormat("sec2hour")
+	RawMin2Hour     = RawFormat("min2hour")
+	RawHalfMin2Hour = RawFormat("halfmin2hour")
+	RawMsec24Hour32 = RawFormat("msec24hour32")
+)
+
+var rawFormats = map[RawFormat]bool{
+	RawRaw48: true, RawHex48: true, RawRaw16: true, RawRaw16Raw16: true, RawRaw16Avg16: true,
+	RawRaw24Raw8: true, RawRaw24Raw24: true, RawTempMinMax: true, RawTemp10x: true,
+	RawSec2Hour: true, RawMin2Hour: true, RawHalfMin2Hour: true, RawMsec24Hour32: true,
+}
+
+func (attr *SmartAttribute) raw16(i int) uint64 {
+	return uint64(attr.Raw[i*2]) | uint64(attr.Raw[i*2+1])<<8
+}
+
+// tempMinMax returns the current temperature and the min/max temperature
+// stored in the other bytes when the values are consistent.
+func (attr *SmartAttribute) tempMinMax() (cur, min, max uint8, ok bool) {
+	cur, min, max = attr.Raw[0], attr.Raw[2], attr.Raw[4]
+	if min > max {
+		min, max = max, min
+	}
+
+	return cur, min, max, max > 0 && min <= cur && cur <= max
+}
+
+// RawNumber interprets the raw value by the raw format, and returns the main
+// value in the unit of the format, e.g. Celsius, hours or counts.
+func (attr *SmartAttribute) RawNumber() uint64 {
+	raw := attr.RawValue()
+
+	switch attr.Format {
+	case RawRaw16Raw16, RawRaw16Avg16:
+		return attr.raw16(0)
+	case RawRaw24Raw8, RawRaw24Raw24:
+		return raw & 0xffffff
+	case RawTempMinMax:
+		return uint64(attr.Raw[0])
+	case RawTemp10x:
+		return attr.raw16(0) / 10
+	case RawSec2Hour:
+		return raw / 3600
+	case RawMin2Hour:
+		return raw / 60
+	case RawHalfMin2Hour:
+		return raw / 120
+	case RawMsec24Hour32:
+		return raw & 0xffffffff
+	}
+
+	return raw
+}
+
+// RawString formats the raw value like smartctl.
+func (attr *SmartAttribute) RawString() string {
+	raw := attr.RawValue()
+
+	switch attr.Format {
+	case RawHex48:
+		return fmt.Sprintf("0x%012x", raw)
+
+	case RawRaw16:
+		return fmt.Sprintf("%d %d %d", attr.raw16(2), attr.raw16(1), attr.raw16(0))
+
+	case RawRaw16Raw16:
+		if raw>>16 != 0 {
+			return fmt.Sprintf("%d (%d %d)", attr.raw16(0), attr.raw16(2), attr.raw16(1))
+		}
+
+		return fmt.Sprintf("%d", attr.raw16(0))
+
+	case RawRaw16Avg16:
+		if attr.raw16(1) != 0 {
+			return fmt.Sprintf("%d (Average %d)", attr.raw16(0), attr.raw16(1))
+		}
+
+		return fmt.Sprintf("%d", attr.raw16(0))
+
+	case RawRaw24Raw8:
+		if raw>>24 != 0 {
+			return fmt.Sprintf("%d (%d %d %d)", raw&0xffffff, attr.Raw[5], attr.Raw[4], attr.Raw[3])
+		}
+
+		return fmt.Sprintf("%d", raw&0xffffff)
+
+	case RawRaw24Raw24:
+		return fmt.Sprintf("%d/%d", raw>>24, raw&0xffffff)
+
+	case RawTempMinMax:
+		if cur, min, max, ok := attr.tempMinMax(); ok {
+			return fmt.Sprintf("%d (Min/Max %d/%d)", cur, min, max)
+		}
+
+		return fmt.Sprintf("%d", attr.Raw[0])
+
+	case RawTemp10x:
+		return fmt.Sprintf("%d.%d", attr.raw16(0)/10, attr.raw16(0)%10)
+
+	case RawSec2Hour:
+		return fmt.Sprintf("%dh+%02dm+%02ds", raw/3600, raw/60%60, raw%60)
+
+	case RawMin2Hour:
+		return fmt.Sprintf("%dh+%02dm", raw/60, raw%60)
+
+	case RawHalfMin2Hour:
+		return fmt.Sprintf("%dh+%02dm", raw/120, raw/2%60)
+
+	case RawMsec24Hour32:
+		// hours in 31..0, milliseconds in 47..32 and the reserved byte
+		msec := raw>>32 | uint64(attr.Reserved)<<16
+		return fmt.Sprintf("%dh+%02dm+%02d.%03ds", raw&0xffffffff, msec/60000, msec/1000%60, msec%1000)
+	}
+
+	return fmt.Sprintf("%d", raw)
+}
+
+type AttributeDef struct {
+	ID     uint8     `json:"id"`
+	Name   string    `json:"name"`
+	Format RawFormat `json:"format,omitempty"`
+}
+
+// DriveEntry describes a drive family like the entry of smartctl drivedb.h.
+// Model and Firmware are regular expressions, and the empty Firmware matches
+// all firmware revisions.
+type DriveEntry struct {
+	Family     string         `json:"family"`
+	Model      string         `json:"model"`
+	Firmware   string         `json:"firmware,omitempty"`
+	Warning    string         `json:"warning,omitempty"`
+	Attributes []AttributeDef `json:"attributes,omitempty"`
+
+	model    *regexp.Regexp
+	firmware *regexp.Regexp
+}
+
+func (entry *DriveEntry) compile() (err error) {
+	if entry.model, err = regexp.Compile(entry.Model); err != nil {
+		return fmt.Errorf("drivedb %q model: %v", entry.Family, err)
+	}
+
+	if entry.Firmware != "" {
+		if entry.firmware, err = regexp.Compile(entry.Firmware); err != nil {
+			return fmt.Errorf("drivedb %q firmware: %v", entry.Family, err)
+		}
+	}
+
+	for _, attr := range entry.Attributes {
+		if attr.Format != "" && !rawFormats[attr.Format] {
+			return fmt.Errorf("drivedb %q attribute %d: unknown raw format %q", entry.Family, attr.ID, attr.Format)
+		}
+	}
+
+	return nil
+}
+
+func (entry *DriveEntry) match(model, firmware string) bool {
+	return entry.model.MatchString(model) && (entry.firmware == nil || entry.firmware.MatchString(firmware))
+}
+
+func (entry *DriveEntry) attribute(id uint8) (AttributeDef, bool) {
+	for _, attr := range entry.Attributes {
+		if attr.ID == id {
+			return attr, true
+		}
+	}
+
+	return AttributeDef{}, false
+}
+
+// DriveDB is the attribute naming and raw value interpretation database.
+// User entries loaded later take precedence over the built-in entries.
+type DriveDB struct {
+	mutex    sync.RWMutex
+	defaults map[uint8]AttributeDef
+	entries  []*DriveEntry
+}
+
+func NewDriveDB(defaults []AttributeDef, entries []DriveEntry) (*DriveDB, error) {
+	db := &DriveDB{defaults: make(map[uint8]AttributeDef)}
+
+	for _, attr := range defaults {
+		db.defaults[attr.ID] = attr
+	}
+
+	if err := db.Add(entries...); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Add registers entries ahead of the current entries, so the new entries
+// override the existing ones on the same drives.
+func (db *DriveDB) Add(entries ...DriveEntry) error {
+	added := make([]*DriveEntry, 0, len(entries))
+
+	for i := range entries {
+		entry := entries[i]
+		if err := entry.compile(); err != nil {
+			return err
+		}
+
+		added = append(added, &entry)
+	}
+
+	db.mutex.Lock()
+	db.entries = append(added, db.entries...)
+	db.mutex.Unlock()
+
+	return nil
+}
+
+// Load adds the JSON array of DriveEntry.
+func (db *DriveDB) Load(r io.Reader) error {
+	entries := make([]DriveEntry, 0)
+
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return fmt.Errorf("drivedb: %v", err)
+	}
+
+	return db.Add(entries...)
+}
+
+func (db *DriveDB) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = file.Close() }()
+
+	return db.Load(file)
+}
+
+// Lookup finds the first entry matching the model and firmware.
+func (db *DriveDB) Lookup(model, firmware string) *DriveEntry {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	for _, entry := range db.entries {
+		if entry.match(model, firmware) {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// Attribute returns the definition of the attribute on the drive entry,
+// falling back to the default definition.
+func (db *DriveDB) Attribute(entry *DriveEntry, id uint8) AttributeDef {
+	if entry != nil {
+		if attr, ok := entry.attribute(id); ok {
+			if attr.Format == "" {
+				attr.Format = db.Attribute(nil, id).Format
+			}
+
+			return attr
+		}
+	}
+
+	if attr, ok := db.defaults[id]; ok {
+		return attr
+	}
+
+	return AttributeDef{ID: id, Name: "Unknown_Attribute", Format: RawRaw48}
+}
+
+// apply names the attributes of the SMART data and sets the raw format.
+func (db *DriveDB) apply(model, firmware string, smart *SmartData) {
+	entry := db.Lookup(model, firmware)
+
+	if entry != nil {
+		smart.Family = entry.Family
+		smart.Warning = entry.Warning
+	}
+
+	for i := range smart.Attributes {
+		attr := &smart.Attributes[i]
+		def := db.Attribute(entry, attr.ID)
+
+		attr.Name = def.Name
+		attr.Format = def.Format
+	}
+}
+
+var DefaultDriveDB = builtinDriveDB()
+
+func builtinDriveDB() *DriveDB {
+	db, err := NewDriveDB(defaultAttributes, builtinDrives)
+	if err != nil {
+		panic(err)
+	}
+
+	return db
+}
+
+// The default attribute definitions of smartctl
+var defaultAttributes = []AttributeDef{
+	{1, "Raw_Read_Error_Rate", RawRaw48},
+	{2, "Throughput_Performance", RawRaw48},
+	{3, "Spin_Up_Time", RawRaw16Avg16},
+	{4, "Start_Stop_Count", RawRaw48},
+	{5, "Reallocated_Sector_Ct", RawRaw16Raw16},
+	{7, "Seek_Error_Rate", RawRaw48},
+	{8, "Seek_Time_Performance", RawRaw48},
+	{9, "Power_On_Hours", RawRaw24Raw8},
+	{10, "Spin_Retry_Count", RawRaw48},
+	{11, "Calibration_Retry_Count", RawRaw48},
+	{12, "Power_Cycle_Count", RawRaw48},
+	{13, "Read_Soft_Error_Rate", RawRaw48},
+	{175, "Program_Fail_Count_Chip", RawRaw48},
+	{176, "Erase_Fail_Count_Chip", RawRaw48},
+	{177, "Wear_Leveling_Count", RawRaw48},
+	{178, "Used_Rsvd_Blk_Cnt_Chip", RawRaw48},
+	{179, "Used_Rsvd_Blk_Cnt_Tot", RawRaw48},
+	{180, "Unused_Rsvd_Blk_Cnt_Tot", RawRaw48},
+	{181, "Program_Fail_Cnt_Total", RawRaw48},
+	{182, "Erase_Fail_Count_Total", RawRaw48},
+	{183, "Runtime_Bad_Block", RawRaw48},
+	{184, "End-to-End_Error", RawRaw48},
+	{187, "Reported_Uncorrect", RawRaw48},
+	{188, "Command_Timeout", RawRaw48},
+	{189, "High_Fly_Writes", RawRaw48},
+	{190, "Airflow_Temperature_Cel", RawTempMinMax},
+	{191, "G-Sense_Error_Rate", RawRaw48},
+	{192, "Power-Off_Retract_Count", RawRaw48},
+	{193, "Load_Cycle_Count", RawRaw48},
+	{194, "Temperature_Celsius", RawTempMinMax},
+	{195, "Hardware_ECC_Recovered", RawRaw48},
+	{196, "Reallocated_Event_Count", RawRaw16Raw16},
+	{197, "Current_Pending_Sector", RawRaw48},
+	{198, "Offline_Uncorrectable", RawRaw48},
+	{199, "UDMA_CRC_Error_Count", RawRaw48},
+	{200, "Multi_Zone_Error_Rate", RawRaw48},
+	{201, "Soft_Read_Error_Rate", RawRaw48},
+	{202, "Data_Address_Mark_Errs", RawRaw48},
+	{220, "Disk_Shift", RawRaw48},
+	{221, "G-Sense_Error_Rate", RawRaw48},
+	{222, "Loaded_Hours", RawRaw48},
+	{223, "Load_Retry_Count", RawRaw48},
+	{224, "Load_Friction", RawRaw48},
+	{225, "Load_Cycle_Count", RawRaw48},
+	{226, "Load-in_Time", RawRaw48},
+	{227, "Torq-amp_Count", RawRaw48},
+	{228, "Power-off_Retract_Count", RawRaw48},
+	{230, "Head_Amplitude", RawRaw48},
+	{231, "Temperature_Celsius", RawTempMinMax},
+	{232, "Available_Reservd_Space", RawRaw48},
+	{233, "Media_Wearout_Indicator", RawRaw48},
+	{240, "Head_Flying_Hours", RawRaw24Raw8},
+	{241, "Total_LBAs_Written", RawRaw48},
+	{242, "Total_LBAs_Read", RawRaw48},
+	{250, "Read_Error_Retry_Rate", RawRaw48},
+	{254, "Free_Fall_Sensor", RawRaw48},
+}
+
+var builtinDrives = []DriveEntry{
+	{
+		Family:   "Crucial/Micron RealSSD m4/C400",
+		Model:    "^M4-CT(064|128|256|512)M4SSD[123]$",
+		Firmware: "^0309$",
+		Warning:  "This firmware hangs after 5184 hours of power-on time, update the firmware to 040H or later",
+		Attributes: []AttributeDef{
+			{173, "Wear_Leveling_Count", RawRaw48},
+			{202, "Perc_Rated_Life_Used", RawRaw48},
+		},
+	},
+	{
+		Family: "Crucial/Micron Client SSDs",
+		Model:  "^(Crucial_)?CT[0-9]+(M|MX|BX)[0-9]+SSD[0-9]*$|^Micron_[0-9]+_MTFD",
+		Attributes: []AttributeDef{
+			{173, "Ave_Block-Erase_Count", RawRaw48},
+			{202, "Percent_Lifetime_Remain", RawRaw48},
+			{246, "Total_LBAs_Written", RawRaw48},
+		},
+	},
+	{
+		Family: "Samsung based SSDs",
+		Model:  "^Samsung SSD (8[3-7]0|9[0-9]0|PM|SM)",
+		Attributes: []AttributeDef{
+			{177, "Wear_Leveling_Count", RawRaw48},
+			{179, "Used_Rsvd_Blk_Cnt_Tot", RawRaw48},
+			{190, "Airflow_Temperature_Cel", RawTempMinMax},
+			{235, "POR_Recovery_Count", RawRaw48},
+			{241, "Total_LBAs_Written", RawRaw48},
+		},
+	},
+	{
+		Family: "Intel Data Center and Client SSDs",
+		Model:  "^INTEL SSDSC2",
+		Attributes: []AttributeDef{
+			{9, "Power_On_Hours_and_Msec", RawMsec24Hour32},
+			{225, "Host_Writes_32MiB", RawRaw48},
+			{226, "Workld_Media_Wear_Indic", RawRaw48},
+			{227, "Workld_Host_Reads_Perc", RawRaw48},
+			{228, "Workload_Minutes", RawRaw48},
+			{232, "Available_Reservd_Space", RawRaw48},
+			{233, "Media_Wearout_Indicator", RawRaw48},
+			{241, "Host_Writes_32MiB", RawRaw48},
+			{242, "Host_Reads_32MiB", RawRaw48},
+		},
+	},
+	{
+		Family: "Seagate HDDs",
+		Model:  "^ST[0-9]+(NM|DM|VN|VX|AS|LM)[0-9A-Z]*(-[0-9A-Z]+)?$",
+		Attributes: []AttributeDef{
+			{1, "Raw_Read_Error_Rate", RawRaw48},
+			{7, "Seek_Error_Rate", RawRaw48},
+			{188, "Command_Timeout", RawRaw16},
+			{240, "Head_Flying_Hours", RawMsec24Hour32},
+		},
+	},
+	{
+		Family: "HGST/Hitachi HDDs",
+		Model:  "^(HGST |Hitachi )?H[UDMTE][A-Z]*[0-9]+",
+		Attributes: []AttributeDef{
+			{22, "Helium_Level", RawRaw48},
+		},
+	},
+	{
+		Family: "Western Digital HDDs",
+		Model:  "^WDC WD[0-9]+[A-Z]+",
+		Attributes: []AttributeDef{
+			{16, "Total_LBAs_Read", RawRaw48},
+		},
+	},
+}
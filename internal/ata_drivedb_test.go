@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rawAttr(format RawFormat, raw []byte, reserved uint8) *SmartAttribute {
+	attr := &SmartAttribute{Format: format, Reserved: reserved}
+	copy(attr.Raw[:], raw)
+
+	return attr
+}
+
+func TestSmartAttributeRawFormat(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		attr   *SmartAttribute
+		str    string
+		number uint64
+	}{
+		{rawAttr(RawRaw48, []byte{0x10, 0x27}, 0), "10000", 10000},
+		{rawAttr(RawHex48, []byte{0x34, 0x12, 0x00, 0x07}, 0), "0x000007001234", 0x07001234},
+		{rawAttr(RawRaw16, []byte{0x01, 0x00, 0x02, 0x00, 0x03, 0x00}, 0), "3 2 1", 0x000300020001},
+		{rawAttr(RawRaw16Raw16, []byte{0x08, 0x00}, 0), "8", 8},
+		{rawAttr(RawRaw16Raw16, []byte{0x08, 0x00, 0x02, 0x00, 0x01, 0x00}, 0), "8 (1 2)", 8},
+		{rawAttr(RawRaw16Avg16, []byte{0xa0, 0x0f, 0x9c, 0x0f}, 0), "4000 (Average 3996)", 4000},
+		{rawAttr(RawRaw24Raw8, []byte{0x38, 0x22, 0x00}, 0), "8760", 8760},
+		{rawAttr(RawRaw24Raw8, []byte{0x38, 0x22, 0x00, 0x01, 0x02, 0x03}, 0), "8760 (3 2 1)", 8760},
+		{rawAttr(RawRaw24Raw24, []byte{0x0a, 0x00, 0x00, 0x64, 0x00, 0x00}, 0), "100/10", 10},
+		{rawAttr(RawTempMinMax, []byte{38, 0, 20, 0, 60, 0}, 0), "38 (Min/Max 20/60)", 38},
+		{rawAttr(RawTempMinMax, []byte{38, 0, 0, 0, 0, 0}, 0), "38", 38},
+		{rawAttr(RawTemp10x, []byte{0x7d, 0x01}, 0), "38.1", 38},
+		{rawAttr(RawSec2Hour, []byte{0x3d, 0x0e}, 0), "1h+00m+45s", 1},
+		{rawAttr(RawMin2Hour, []byte{0x7b}, 0), "2h+03m", 2},
+		{rawAttr(RawHalfMin2Hour, []byte{0xf5}, 0), "2h+02m", 2},
+		{rawAttr(RawMsec24Hour32, []byte{0x38, 0x22, 0x00, 0x00, 0x10, 0x27}, 0x01), "8760h+01m+15.536s", 8760},
+	}
+
+	for _, test := range tests {
+		a.Equal(test.str, test.attr.RawString(), test.attr.Format)
+		a.Equal(test.number, test.attr.RawNumber(), test.attr.Format)
+	}
+}
+
+func TestDriveDB(t *testing.T) {
+	a := assert.New(t)
+
+	db := builtinDriveDB()
+
+	entry := db.Lookup("INTEL SSDSC2KB480G8", "XCV10110")
+	a.NotNil(entry)
+	a.Equal("Power_On_Hours_and_Msec", db.Attribute(entry, 9).Name)
+	a.Equal(RawMsec24Hour32, db.Attribute(entry, 9).Format)
+	a.Equal("Temperature_Celsius", db.Attribute(entry, 194).Name)
+	a.Equal("Unknown_Attribute", db.Attribute(entry, 100).Name)
+
+	// firmware specific warning
+	a.NotEmpty(db.Lookup("M4-CT256M4SSD2", "0309").Warning)
+	a.Nil(db.Lookup("M4-CT256M4SSD2", "040H"))
+
+	a.Nil(db.Lookup("QEMU HARDDISK", "2.5+"))
+	a.Equal("Power_On_Hours", db.Attribute(nil, 9).Name)
+
+	// user entries override the built-in entries
+	a.NoError(db.Load(strings.NewReader(`[
+		{"family": "Team SSD", "model": "^INTEL SSDSC2KB", "warning": "test drive",
+		 "attributes": [{"id": 9, "name": "Power_On_Minutes", "format": "min2hour"}, {"id": 177, "name": "Wear"}]}
+	]`)))
+
+	entry = db.Lookup("INTEL SSDSC2KB480G8", "XCV10110")
+	a.Equal("Team SSD", entry.Family)
+	a.Equal(RawMin2Hour, db.Attribute(entry, 9).Format)
+	a.Equal(RawRaw48, db.Attribute(entry, 177).Format)
+	a.Equal("Intel Data Center and Client SSDs", db.Lookup("INTEL SSDSC2BB480G7", "").Family)
+
+	a.Error(db.Load(strings.NewReader(`[{"family": "bad", "model": "^("}]`)))
+	a.Error(db.Load(strings.NewReader(`[{"family": "bad", "model": "^X", "attributes": [{"id": 1, "format": "raw99"}]}]`)))
+	a.Error(db.Load(strings.NewReader(`{`)))
+	a.Error(db.LoadFile("/nonexistent/drivedb.json"))
+}
+
+func TestSATAReadSMARTDriveDB(t *testing.T) {
+	a := assert.New(t)
+
+	data, thresholds := sampleSmartPages(sampleAttrs)
+	identify := sampleIdentify().
+		setString(27, 20, "INTEL SSDSC2KB480G8").
+		setWord(82, 0x0001).setWord(83, 0x4000).setWord(85, 0x0001).setWord(87, 0x4000)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = newFakeTransport(
+		fakeResponse{data: identify},
+		fakeResponse{data: data},
+		fakeResponse{data: thresholds},
+	)
+
+	a.NoError(sata.ScanSMART())
+
+	smart := sata.SMART()
+	a.Equal("Intel Data Center and Client SSDs", smart.Family)
+	a.Equal("Power_On_Hours_and_Msec", smart.Attribute(9).Name)
+	a.Equal("Airflow_Temperature_Cel", smart.Attribute(190).Name)
+	a.Equal("38", smart.Attribute(194).RawString())
+}
@@ -0,0 +1,378 @@
+package internal
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	ataLogSummaryError = 0x01
+	ataLogCompError    = 0x02
+	ataLogExtCompError = 0x03
+
+	// SMART summary and comprehensive error log (ACS-3 A.14, A.4)
+	errorLogEntries    = 5
+	errorLogOffset     = 2
+	errorLogSize       = 90
+	errorLogCmdSize    = 12
+	errorLogDataOffset = 60
+	errorLogCountOff   = 452
+
+	// Extended comprehensive error log (ACS-3 A.5)
+	extErrorLogEntries    = 4
+	extErrorLogOffset     = 4
+	extErrorLogSize       = 124
+	extErrorLogCmdSize    = 18
+	extErrorLogDataOffset = 90
+	extErrorLogCountOff   = 500
+
+	errorLogCommands = 5
+	errorStateMASK   = uint8(0x0f)
+)
+
+// ACS-3 Table A.9 - State field values
+var errorStateNames = map[uint8]string{
+	0x0: "Unknown",
+	0x1: "Sleep",
+	0x2: "Standby",
+	0x3: "Active or Idle",
+	0x4: "Executing SMART off-line or self-test",
+}
+
+var ataCommandNames = map[uint8]string{
+	0x00: "NOP",
+	0x06: "DATA SET MANAGEMENT",
+	0x08: "DEVICE RESET",
+	0x20: "READ SECTOR(S)",
+	0x24: "READ SECTOR(S) EXT",
+	0x25: "READ DMA EXT",
+	0x27: "READ NATIVE MAX ADDRESS EXT",
+	0x29: "READ MULTIPLE EXT",
+	0x2f: "READ LOG EXT",
+	0x30: "WRITE SECTOR(S)",
+	0x34: "WRITE SECTOR(S) EXT",
+	0x35: "WRITE DMA EXT",
+	0x39: "WRITE MULTIPLE EXT",
+	0x3d: "WRITE DMA FUA EXT",
+	0x3f: "WRITE LOG EXT",
+	0x40: "READ VERIFY SECTOR(S)",
+	0x42: "READ VERIFY SECTOR(S) EXT",
+	0x45: "WRITE UNCORRECTABLE EXT",
+	0x47: "READ LOG DMA EXT",
+	0x57: "WRITE LOG DMA EXT",
+	0x60: "READ FPDMA QUEUED",
+	0x61: "WRITE FPDMA QUEUED",
+	0x63: "NCQ NON-DATA",
+	0x64: "SEND FPDMA QUEUED",
+	0x65: "RECEIVE FPDMA QUEUED",
+	0x70: "SEEK",
+	0x90: "EXECUTE DEVICE DIAGNOSTIC",
+	0x91: "INITIALIZE DEVICE PARAMETERS",
+	0x92: "DOWNLOAD MICROCODE",
+	0x93: "DOWNLOAD MICROCODE DMA",
+	0xa0: "PACKET",
+	0xa1: "IDENTIFY PACKET DEVICE",
+	0xb0: "SMART",
+	0xb1: "DEVICE CONFIGURATION OVERLAY",
+	0xb4: "SANITIZE DEVICE",
+	0xc4: "READ MULTIPLE",
+	0xc5: "WRITE MULTIPLE",
+	0xc6: "SET MULTIPLE MODE",
+	0xc8: "READ DMA",
+	0xca: "WRITE DMA",
+	0xe0: "STANDBY IMMEDIATE",
+	0xe1: "IDLE IMMEDIATE",
+	0xe2: "STANDBY",
+	0xe3: "IDLE",
+	0xe4: "READ BUFFER",
+	0xe5: "CHECK POWER MODE",
+	0xe6: "SLEEP",
+	0xe7: "FLUSH CACHE",
+	0xe8: "WRITE BUFFER",
+	0xea: "FLUSH CACHE EXT",
+	0xec: "IDENTIFY DEVICE",
+	0xef: "SET FEATURES",
+	0xf1: "SECURITY SET PASSWORD",
+	0xf2: "SECURITY UNLOCK",
+	0xf3: "SECURITY ERASE PREPARE",
+	0xf4: "SECURITY ERASE UNIT",
+	0xf5: "SECURITY FREEZE LOCK",
+	0xf6: "SECURITY DISABLE PASSWORD",
+	0xf8: "READ NATIVE MAX ADDRESS",
+	0xf9: "SET MAX ADDRESS",
+}
+
+var smartCommandNames = map[uint8]string{
+	SmartReadData:       "SMART READ DATA",
+	SmartReadThresholds: "SMART READ ATTRIBUTE THRESHOLDS",
+	SmartExecOffline:    "SMART EXECUTE OFF-LINE IMMEDIATE",
+	SmartReadLog:        "SMART READ LOG",
+	SmartWriteLog:       "SMART WRITE LOG",
+	0xd8:                "SMART ENABLE OPERATIONS",
+	0xd9:                "SMART DISABLE OPERATIONS",
+	SmartReturnStatus:   "SMART RETURN STATUS",
+}
+
+// AtaCommandName names the command, SMART commands are named by the feature.
+func AtaCommandName(command uint8, feature uint16) string {
+	if command == AtaSmart {
+		if name, ok := smartCommandNames[uint8(feature)]; ok {
+			return name
+		}
+	}
+
+	if name, ok := ataCommandNames[command]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("[VENDOR SPECIFIC 0x%02x]", command)
+}
+
+// AtaErrorCommand is the command data structure of the commands preceding
+// the error.
+type AtaErrorCommand struct {
+	DeviceControl uint8
+	Feature       uint16
+	Count         uint16
+	LBA           uint64
+	Device        uint8
+	Command       uint8
+	Timestamp     uint32 // milliseconds since power-on
+}
+
+func (cmd *AtaErrorCommand) Name() string {
+	return AtaCommandName(cmd.Command, cmd.Feature)
+}
+
+// AtaErrorData is the error data structure of the error log.
+type AtaErrorData struct {
+	Error    uint8
+	Status   uint8
+	Count    uint16
+	LBA      uint64
+	Device   uint8
+	State    uint8
+	LifeTime uint16 // power-on hours when the error occurred
+	Extended [19]byte
+}
+
+func (data *AtaErrorData) StateName() string {
+	state := data.State & errorStateMASK
+
+	if name, ok := errorStateNames[state]; ok {
+		return name
+	}
+
+	if state >= 0xb {
+		return "Vendor Specific"
+	}
+
+	return "Reserved"
+}
+
+type AtaErrorLogEntry struct {
+	Number   int               // error number counted by the device
+	Commands []AtaErrorCommand // newest first, the first one caused the error
+	Error    AtaErrorData
+}
+
+// AtaErrorLog is the SMART summary, comprehensive or extended comprehensive
+// error log, the entries are ordered from the newest.
+type AtaErrorLog struct {
+	Version    uint8
+	ErrorCount int // total errors counted by the device
+	Entries    []AtaErrorLogEntry
+}
+
+// lba28 combines the 28-bit LBA of the registers.
+func lba28(low, mid, high, device uint8) uint64 {
+	return uint64(device&0x0f)<<24 | uint64(high)<<16 | uint64(mid)<<8 | uint64(low)
+}
+
+// lba48 combines the 48-bit LBA stored in the order of 7:0, 31:24, 15:8,
+// 39:32, 23:16 and 47:40.
+func lba48(raw []byte) uint64 {
+	return uint64(raw[0]) | uint64(raw[2])<<8 | uint64(raw[4])<<16 |
+		uint64(raw[1])<<24 | uint64(raw[3])<<32 | uint64(raw[5])<<40
+}
+
+func parseErrorEntry(buf []byte) (AtaErrorLogEntry, bool) {
+	raw := buf[errorLogDataOffset:errorLogSize]
+	if isEmptyDescriptor(raw) {
+		return AtaErrorLogEntry{}, false
+	}
+
+	entry := AtaErrorLogEntry{
+		Commands: make([]AtaErrorCommand, 0, errorLogCommands),
+		Error: AtaErrorData{
+			Error:    raw[1],
+			Count:    uint16(raw[2]),
+			LBA:      lba28(raw[3], raw[4], raw[5], raw[6]),
+			Device:   raw[6],
+			Status:   raw[7],
+			State:    raw[27],
+			LifeTime: binary.LittleEndian.Uint16(raw[28:30]),
+		},
+	}
+	copy(entry.Error.Extended[:], raw[8:27])
+
+	for i := errorLogCommands - 1; i >= 0; i-- {
+		cmd := buf[i*errorLogCmdSize : (i+1)*errorLogCmdSize]
+		if isEmptyDescriptor(cmd) {
+			continue
+		}
+
+		entry.Commands = append(entry.Commands, AtaErrorCommand{
+			DeviceControl: cmd[0],
+			Feature:       uint16(cmd[1]),
+			Count:         uint16(cmd[2]),
+			LBA:           lba28(cmd[3], cmd[4], cmd[5], cmd[6]),
+			Device:        cmd[6],
+			Command:       cmd[7],
+			Timestamp:     binary.LittleEndian.Uint32(cmd[8:12]),
+		})
+	}
+
+	return entry, true
+}
+
+func parseExtErrorEntry(buf []byte) (AtaErrorLogEntry, bool) {
+	raw := buf[extErrorLogDataOffset:extErrorLogSize]
+	if isEmptyDescriptor(raw) {
+		return AtaErrorLogEntry{}, false
+	}
+
+	entry := AtaErrorLogEntry{
+		Commands: make([]AtaErrorCommand, 0, errorLogCommands),
+		Error: AtaErrorData{
+			Error:    raw[1],
+			Count:    binary.LittleEndian.Uint16(raw[2:4]),
+			LBA:      lba48(raw[4:10]),
+			Device:   raw[10],
+			Status:   raw[11],
+			State:    raw[31],
+			LifeTime: binary.LittleEndian.Uint16(raw[32:34]),
+		},
+	}
+	copy(entry.Error.Extended[:], raw[12:31])
+
+	for i := errorLogCommands - 1; i >= 0; i-- {
+		cmd := buf[i*extErrorLogCmdSize : (i+1)*extErrorLogCmdSize]
+		if isEmptyDescriptor(cmd) {
+			continue
+		}
+
+		entry.Commands = append(entry.Commands, AtaErrorCommand{
+			DeviceControl: cmd[0],
+			Feature:       binary.LittleEndian.Uint16(cmd[1:3]),
+			Count:         binary.LittleEndian.Uint16(cmd[3:5]),
+			LBA:           lba48(cmd[5:11]),
+			Device:        cmd[11],
+			Command:       cmd[12],
+			Timestamp:     binary.LittleEndian.Uint32(cmd[14:18]),
+		})
+	}
+
+	return entry, true
+}
+
+type errorLogLayout struct {
+	name     string
+	entries  int // error log data structures per page
+	offset   int
+	size     int
+	countOff int
+	index16  bool // 16-bit error log index at byte 2, otherwise 8-bit at byte 1
+	decode   func(buf []byte) (AtaErrorLogEntry, bool)
+}
+
+var (
+	summaryErrorLayout = errorLogLayout{
+		"summary error log", errorLogEntries, errorLogOffset, errorLogSize, errorLogCountOff, false, parseErrorEntry,
+	}
+	compErrorLayout = errorLogLayout{
+		"comprehensive error log", errorLogEntries, errorLogOffset, errorLogSize, errorLogCountOff, false, parseErrorEntry,
+	}
+	extCompErrorLayout = errorLogLayout{
+		"extended comprehensive error log", extErrorLogEntries, extErrorLogOffset, extErrorLogSize, extErrorLogCountOff, true, parseExtErrorEntry,
+	}
+)
+
+// parse decodes the circular error log data structures over all pages of
+// buf from the newest pointed by the 1's based error log index.
+func (layout errorLogLayout) parse(buf []byte) (*AtaErrorLog, error) {
+	pages := len(buf) / ataLogPageSize
+	if pages == 0 {
+		return nil, fmt.Errorf("%s: %w", layout.name, ErrChecksum)
+	}
+
+	for page := 0; page < pages; page++ {
+		if !checksum8(buf[page*ataLogPageSize : (page+1)*ataLogPageSize]) {
+			return nil, fmt.Errorf("%s: %w", layout.name, ErrChecksum)
+		}
+	}
+
+	var index int
+	if layout.index16 {
+		index = int(binary.LittleEndian.Uint16(buf[2:4]))
+	} else {
+		index = int(buf[1])
+	}
+
+	log := &AtaErrorLog{
+		Version:    buf[0],
+		ErrorCount: int(binary.LittleEndian.Uint16(buf[layout.countOff : layout.countOff+2])),
+		Entries:    make([]AtaErrorLogEntry, 0),
+	}
+
+	newestFirst(pages*layout.entries, index, func(i int) {
+		page, n := i/layout.entries, i%layout.entries
+		pos := page*ataLogPageSize + layout.offset + n*layout.size
+
+		entry, ok := layout.decode(buf[pos : pos+layout.size])
+		if !ok {
+			return
+		}
+
+		entry.Number = log.ErrorCount - len(log.Entries)
+		log.Entries = append(log.Entries, entry)
+	})
+
+	return log, nil
+}
+
+// ReadSummaryErrorLog reads the SMART summary error log which keeps the
+// last five errors.
+func (sata *SATADevice) ReadSummaryErrorLog() (*AtaErrorLog, error) {
+	buf := make([]byte, ataLogPageSize)
+
+	if err := sata.smartReadLog(ataLogSummaryError, buf); err != nil {
+		return nil, err
+	}
+
+	return summaryErrorLayout.parse(buf)
+}
+
+// ReadComprehensiveErrorLog reads the pages of the SMART comprehensive error
+// log by SMART READ LOG, or all pages in the SMART log directory if pages is
+// 0.
+func (sata *SATADevice) ReadComprehensiveErrorLog(pages int) (*AtaErrorLog, error) {
+	buf, err := sata.ReadSMARTLog(ataLogCompError, pages)
+	if err != nil {
+		return nil, err
+	}
+
+	return compErrorLayout.parse(buf)
+}
+
+// ReadExtErrorLog reads the pages of the extended comprehensive error log by
+// READ LOG EXT, or all pages in the GPL directory if pages is 0. The log
+// records the 48-bit commands.
+func (sata *SATADevice) ReadExtErrorLog(pages int) (*AtaErrorLog, error) {
+	buf, err := sata.ReadLog(ataLogExtCompError, 0, pages)
+	if err != nil {
+		return nil, err
+	}
+
+	return extCompErrorLayout.parse(buf)
+}
@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleErrorLog(pages int) []byte {
+	buf := make([]byte, pages*ataLogPageSize)
+	buf[0] = 0x01
+
+	// error 2 in the first structure, READ DMA failed at LBA 0x0123_4567
+	entry := buf[errorLogOffset:]
+	cmd := entry[4*errorLogCmdSize:]
+	cmd[2], cmd[3], cmd[4], cmd[5], cmd[6], cmd[7] = 0x08, 0x67, 0x45, 0x23, 0xe1, 0xc8
+	binary.LittleEndian.PutUint32(cmd[8:], 123456)
+
+	cmd = entry[3*errorLogCmdSize:]
+	cmd[1], cmd[3], cmd[4], cmd[5], cmd[7] = SmartReadData, 0x00, smartSignatureMid, smartSignatureHigh, AtaSmart
+
+	data := entry[errorLogDataOffset:]
+	data[1], data[2], data[3], data[4], data[5], data[6], data[7] = 0x40, 0x08, 0x67, 0x45, 0x23, 0xe1, 0x51
+	data[27] = 0x03
+	binary.LittleEndian.PutUint16(data[28:], 1500)
+
+	// error 3 in the second structure
+	entry = buf[errorLogOffset+errorLogSize:]
+	cmd = entry[4*errorLogCmdSize:]
+	cmd[7] = 0xca
+
+	data = entry[errorLogDataOffset:]
+	data[1], data[7], data[27] = 0x04, 0x51, 0x0c
+	binary.LittleEndian.PutUint16(data[28:], 1600)
+
+	buf[1] = 2
+	binary.LittleEndian.PutUint16(buf[errorLogCountOff:], 3)
+
+	for page := 0; page < pages; page++ {
+		pos := page * ataLogPageSize
+		buf[pos+511] = -sumBytes(buf[pos : pos+511])
+	}
+
+	return buf
+}
+
+func sampleExtErrorLog() []byte {
+	buf := make([]byte, ataLogPageSize)
+	buf[0] = 0x01
+
+	entry := buf[extErrorLogOffset:]
+	cmd := entry[4*extErrorLogCmdSize:]
+	binary.LittleEndian.PutUint16(cmd[3:], 0x0100)
+	copy(cmd[5:11], []byte{0x01, 0x04, 0x02, 0x05, 0x03, 0x06})
+	cmd[11], cmd[12] = 0x40, 0x60
+	binary.LittleEndian.PutUint32(cmd[14:], 654321)
+
+	data := entry[extErrorLogDataOffset:]
+	data[1] = 0x40
+	binary.LittleEndian.PutUint16(data[2:], 0x0100)
+	copy(data[4:10], []byte{0x01, 0x04, 0x02, 0x05, 0x03, 0x06})
+	data[10], data[11], data[31] = 0x40, 0x41, 0x03
+	binary.LittleEndian.PutUint16(data[32:], 2500)
+
+	binary.LittleEndian.PutUint16(buf[2:], 1)
+	binary.LittleEndian.PutUint16(buf[extErrorLogCountOff:], 7)
+	buf[511] = -sumBytes(buf[:511])
+
+	return buf
+}
+
+func TestAtaCommandName(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("READ DMA EXT", AtaCommandName(0x25, 0))
+	a.Equal("SMART READ DATA", AtaCommandName(AtaSmart, SmartReadData))
+	a.Equal("SMART", AtaCommandName(AtaSmart, 0xe0))
+	a.Equal("[VENDOR SPECIFIC 0xff]", AtaCommandName(0xff, 0))
+}
+
+func TestParseErrorLog(t *testing.T) {
+	a := assert.New(t)
+
+	log, err := summaryErrorLayout.parse(sampleErrorLog(1))
+	a.NoError(err)
+	a.Equal(uint8(1), log.Version)
+	a.Equal(3, log.ErrorCount)
+	a.Len(log.Entries, 2)
+
+	// newest first
+	entry := log.Entries[0]
+	a.Equal(3, entry.Number)
+	a.Equal(uint16(1600), entry.Error.LifeTime)
+	a.Equal("Vendor Specific", entry.Error.StateName())
+	a.Len(entry.Commands, 1)
+	a.Equal("WRITE DMA", entry.Commands[0].Name())
+
+	entry = log.Entries[1]
+	a.Equal(2, entry.Number)
+	a.Equal(uint8(0x40), entry.Error.Error)
+	a.Equal(uint8(0x51), entry.Error.Status)
+	a.Equal(uint16(8), entry.Error.Count)
+	a.Equal(uint64(0x01234567), entry.Error.LBA)
+	a.Equal("Active or Idle", entry.Error.StateName())
+	a.Equal(uint16(1500), entry.Error.LifeTime)
+
+	a.Len(entry.Commands, 2)
+	a.Equal("READ DMA", entry.Commands[0].Name())
+	a.Equal(uint64(0x01234567), entry.Commands[0].LBA)
+	a.Equal(uint32(123456), entry.Commands[0].Timestamp)
+	a.Equal("SMART READ DATA", entry.Commands[1].Name())
+
+	// comprehensive error log over the pages
+	log, err = compErrorLayout.parse(sampleErrorLog(2))
+	a.NoError(err)
+	a.Len(log.Entries, 2)
+
+	// no error recorded
+	log, err = summaryErrorLayout.parse(make([]byte, ataLogPageSize))
+	a.NoError(err)
+	a.Empty(log.Entries)
+
+	corrupted := sampleErrorLog(1)
+	corrupted[10]++
+	_, err = summaryErrorLayout.parse(corrupted)
+	a.True(errors.Is(err, ErrChecksum))
+}
+
+func TestParseExtErrorLog(t *testing.T) {
+	a := assert.New(t)
+
+	log, err := extCompErrorLayout.parse(sampleExtErrorLog())
+	a.NoError(err)
+	a.Equal(7, log.ErrorCount)
+	a.Len(log.Entries, 1)
+
+	entry := log.Entries[0]
+	a.Equal(7, entry.Number)
+	a.Equal(uint16(0x0100), entry.Error.Count)
+	a.Equal(uint64(0x060504030201), entry.Error.LBA)
+	a.Equal(uint16(2500), entry.Error.LifeTime)
+
+	a.Len(entry.Commands, 1)
+	a.Equal("READ FPDMA QUEUED", entry.Commands[0].Name())
+	a.Equal(uint16(0x0100), entry.Commands[0].Count)
+	a.Equal(uint64(0x060504030201), entry.Commands[0].LBA)
+	a.Equal(uint32(654321), entry.Commands[0].Timestamp)
+}
+
+func TestSATAReadErrorLogs(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeTransport(
+		fakeResponse{data: sampleErrorLog(1)},
+		fakeResponse{data: sampleLogDirectory(map[uint8]uint16{ataLogSummaryError: 1, ataLogCompError: 2})},
+		fakeResponse{data: sampleErrorLog(2)},
+		fakeResponse{data: sampleLogDirectory(map[uint8]uint16{ataLogExtCompError: 4})},
+		fakeResponse{data: sampleExtErrorLog()},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	log, err := sata.ReadSummaryErrorLog()
+	a.NoError(err)
+	a.Len(log.Entries, 2)
+	a.Equal(uint8(SmartReadLog), transport.lastCDB()[4])
+	a.Equal(uint8(ataLogSummaryError), transport.lastCDB()[8])
+
+	log, err = sata.ReadComprehensiveErrorLog(0)
+	a.NoError(err)
+	a.Len(log.Entries, 2)
+	a.Equal(uint8(2), transport.lastCDB()[6])
+	a.Equal(uint8(ataLogCompError), transport.lastCDB()[8])
+
+	log, err = sata.ReadExtErrorLog(1)
+	a.NoError(err)
+	a.Len(log.Entries, 1)
+	a.Equal(uint8(AtaReadLogExt), transport.lastCDB()[14])
+	a.Equal(uint8(ataLogExtCompError), transport.lastCDB()[8])
+}
@@ -0,0 +1,215 @@
+package internal
+
+type Feature int
+
+const (
+	FeatureSMART = Feature(iota)
+	FeatureSMARTErrorLog
+	FeatureSMARTSelfTest
+	FeatureSecurity
+	FeaturePowerManagement
+	FeatureWriteCache
+	FeatureReadLookAhead
+	FeatureAPM
+	FeaturePUIS
+	FeatureAAM
+	FeatureLBA48
+	FeatureFlushCache
+	FeatureFlushCacheExt
+	FeatureGPL
+	FeatureWWN
+	FeatureWriteReadVerify
+	FeatureLogDMAExt
+	FeatureFreeFall
+	FeatureSenseDataReporting
+	FeatureEPC
+	FeatureDSN
+	FeatureNCQ
+	FeatureNCQPriority
+	FeatureHIPM
+	FeatureDIPM
+	FeaturePhyEventCounters
+	FeatureSoftwareSettingsPreservation
+	FeatureDevSleep
+	FeatureTRIM
+	FeatureDeterministicTRIM
+	FeatureZeroAfterTRIM
+	FeatureSCT
+	FeatureSCTErrorRecovery
+	FeatureSCTFeatureControl
+	FeatureSCTDataTables
+	FeatureSanitize
+	FeatureSanitizeCryptoScramble
+	FeatureSanitizeOverwrite
+	FeatureSanitizeBlockErase
+
+	featureCount
+)
+
+type identBit struct {
+	word int
+	bit  uint
+}
+
+// featureBits is the location of the supported and enabled bits in the
+// IDENTIFY DEVICE data. Features without the enabled bit are always enabled
+// when supported, so both locations are the same.
+type featureBits struct {
+	name      string
+	supported identBit
+	enabled   identBit
+}
+
+// ACS-3 (7.12.6 IDENTIFY DEVICE data)
+var ataFeatures = [featureCount]featureBits{
+	FeatureSMART:                        {"SMART", identBit{82, 0}, identBit{85, 0}},
+	FeatureSMARTErrorLog:                {"SMART error logging", identBit{84, 0}, identBit{87, 0}},
+	FeatureSMARTSelfTest:                {"SMART self-test", identBit{84, 1}, identBit{87, 1}},
+	FeatureSecurity:                     {"Security", identBit{128, 0}, identBit{128, 1}},
+	FeaturePowerManagement:              {"Power Management", identBit{82, 3}, identBit{85, 3}},
+	FeatureWriteCache:                   {"Volatile write cache", identBit{82, 5}, identBit{85, 5}},
+	FeatureReadLookAhead:                {"Read look-ahead", identBit{82, 6}, identBit{85, 6}},
+	FeatureAPM:                          {"Advanced Power Management", identBit{83, 3}, identBit{86, 3}},
+	FeaturePUIS:                         {"Power-Up In Standby", identBit{83, 5}, identBit{86, 5}},
+	FeatureAAM:                          {"Automatic Acoustic Management", identBit{83, 9}, identBit{86, 9}},
+	FeatureLBA48:                        {"48-bit Address", identBit{83, 10}, identBit{86, 10}},
+	FeatureFlushCache:                   {"FLUSH CACHE", identBit{83, 12}, identBit{86, 12}},
+	FeatureFlushCacheExt:                {"FLUSH CACHE EXT", identBit{83, 13}, identBit{86, 13}},
+	FeatureGPL:                          {"General Purpose Logging", identBit{84, 5}, identBit{87, 5}},
+	FeatureWWN:                          {"World Wide Name", identBit{84, 8}, identBit{87, 8}},
+	FeatureWriteReadVerify:              {"Write-Read-Verify", identBit{119, 1}, identBit{120, 1}},
+	FeatureLogDMAExt:                    {"READ/WRITE LOG DMA EXT", identBit{119, 3}, identBit{120, 3}},
+	FeatureFreeFall:                     {"Free-fall Control", identBit{119, 5}, identBit{120, 5}},
+	FeatureSenseDataReporting:           {"Sense Data Reporting", identBit{119, 6}, identBit{120, 6}},
+	FeatureEPC:                          {"Extended Power Conditions", identBit{119, 7}, identBit{120, 7}},
+	FeatureDSN:                          {"Device Statistics Notification", identBit{119, 9}, identBit{120, 9}},
+	FeatureNCQ:                          {"Native Command Queuing", identBit{76, 8}, identBit{76, 8}},
+	FeatureNCQPriority:                  {"NCQ priority information", identBit{76, 12}, identBit{76, 12}},
+	FeatureHIPM:                         {"Host-initiated power management", identBit{76, 9}, identBit{76, 9}},
+	FeatureDIPM:                         {"Device-initiated power management", identBit{78, 3}, identBit{79, 3}},
+	FeaturePhyEventCounters:             {"SATA Phy Event Counters", identBit{76, 10}, identBit{76, 10}},
+	FeatureSoftwareSettingsPreservation: {"Software Settings Preservation", identBit{78, 6}, identBit{79, 6}},
+	FeatureDevSleep:                     {"DevSleep", identBit{78, 8}, identBit{79, 8}},
+	FeatureTRIM:                         {"DATA SET MANAGEMENT TRIM", identBit{169, 0}, identBit{169, 0}},
+	FeatureDeterministicTRIM:            {"Deterministic read after TRIM", identBit{69, 14}, identBit{69, 14}},
+	FeatureZeroAfterTRIM:                {"Read zeroes after TRIM", identBit{69, 5}, identBit{69, 5}},
+	FeatureSCT:                          {"SCT Command Transport", identBit{206, 0}, identBit{206, 0}},
+	FeatureSCTErrorRecovery:             {"SCT Error Recovery Control", identBit{206, 3}, identBit{206, 3}},
+	FeatureSCTFeatureControl:            {"SCT Feature Control", identBit{206, 4}, identBit{206, 4}},
+	FeatureSCTDataTables:                {"SCT Data Tables", identBit{206, 5}, identBit{206, 5}},
+	FeatureSanitize:                     {"Sanitize", identBit{59, 12}, identBit{59, 12}},
+	FeatureSanitizeCryptoScramble:       {"Sanitize CRYPTO SCRAMBLE", identBit{59, 13}, identBit{59, 13}},
+	FeatureSanitizeOverwrite:            {"Sanitize OVERWRITE", identBit{59, 14}, identBit{59, 14}},
+	FeatureSanitizeBlockErase:           {"Sanitize BLOCK ERASE", identBit{59, 15}, identBit{59, 15}},
+}
+
+func (f Feature) String() string {
+	if f < 0 || f >= featureCount {
+		return "Unknown"
+	}
+
+	return ataFeatures[f].name
+}
+
+// validWord checks the validity signature of the IDENTIFY word. Words 83,
+// 84, 87, 119 and 120 shall have 01b in bits 15:14. The supported words 82
+// and 83 follow the signature of word 83, and the enabled words 85 and 86
+// follow the signature of word 87 like smartctl. The others are invalid when
+// all bits are cleared or set.
+func (ident *DevIdentify) validWord(w int) bool {
+	value := ident.word(w).uint16()
+
+	switch w {
+	case 82, 83:
+		return ident.word(83).uint16()&0xc000 == 0x4000 && value != 0xffff
+	case 85:
+		return ident.word(87).uint16()&0xc000 == 0x4000 && value != 0xffff
+	case 86:
+		// bit 15 of word 86 is set when words 119..120 are valid
+		return ident.word(87).uint16()&0xc000 == 0x4000
+	case 84, 87, 119, 120:
+		return value&0xc000 == 0x4000
+	case 76, 78, 79:
+		return value != 0x0000 && value != 0xffff
+	}
+
+	return value != 0xffff
+}
+
+func (ident *DevIdentify) identBit(loc identBit) bool {
+	return ident.validWord(loc.word) && ident.word(loc.word).bit(loc.bit)
+}
+
+type FeatureState struct {
+	Feature   Feature
+	Name      string
+	Supported bool
+	Enabled   bool
+}
+
+// Capabilities is the feature set decoded from the IDENTIFY DEVICE data.
+type Capabilities struct {
+	states [featureCount]FeatureState
+}
+
+func (ident *DevIdentify) Capabilities() *Capabilities {
+	caps := new(Capabilities)
+
+	for f, bits := range ataFeatures {
+		state := FeatureState{Feature: Feature(f), Name: bits.name}
+
+		state.Supported = ident.identBit(bits.supported)
+		state.Enabled = state.Supported && ident.identBit(bits.enabled)
+
+		caps.states[f] = state
+	}
+
+	return caps
+}
+
+func (caps *Capabilities) state(f Feature) FeatureState {
+	if caps == nil || f < 0 || f >= featureCount {
+		return FeatureState{Feature: f}
+	}
+
+	return caps.states[f]
+}
+
+func (caps *Capabilities) Supports(f Feature) bool {
+	return caps.state(f).Supported
+}
+
+func (caps *Capabilities) Enabled(f Feature) bool {
+	return caps.state(f).Enabled
+}
+
+// Features lists all known features with their supported and enabled state.
+func (caps *Capabilities) Features() []FeatureState {
+	if caps == nil {
+		return nil
+	}
+
+	return append([]FeatureState{}, caps.states[:]...)
+}
+
+// Capabilities returns the feature set of the last IDENTIFY DEVICE data, or
+// nil if the device is not identified yet.
+func (sata *SATADevice) Capabilities() *Capabilities {
+	if sata.ident == nil {
+		return nil
+	}
+
+	return sata.ident.Capabilities()
+}
+
+// Features lists the known features of the last IDENTIFY DEVICE data.
+func (sata *SATADevice) Features() []Capability {
+	states := sata.Capabilities().Features()
+	features := make([]Capability, 0, len(states))
+
+	for _, state := range states {
+		features = append(features, Capability{Name: state.Name, Supported: state.Supported, Enabled: state.Enabled})
+	}
+
+	return features
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilities(t *testing.T) {
+	a := assert.New(t)
+
+	ident, _ := parseDevIdentify(sampleIdentify().
+		setWord(76, 0x0500). // NCQ, Phy event counters
+		setWord(78, 0x0108). // DIPM, DevSleep supported
+		setWord(79, 0x0008). // DIPM enabled
+		setWord(82, 0x0061). // SMART, write cache, read look-ahead
+		setWord(83, 0x7408). // APM, 48-bit, FLUSH CACHE EXT
+		setWord(84, 0x4023). // SMART error log, self-test, GPL
+		setWord(85, 0x0021). // SMART, write cache
+		setWord(86, 0x3400).
+		setWord(87, 0x4023).
+		setWord(119, 0x4008).
+		setWord(120, 0x4000).
+		setWord(169, 0x0001).
+		setWord(206, 0x0039))
+
+	caps := ident.Capabilities()
+
+	a.True(caps.Supports(FeatureSMART))
+	a.True(caps.Enabled(FeatureSMART))
+	a.True(caps.Supports(FeatureWriteCache))
+	a.True(caps.Enabled(FeatureWriteCache))
+	a.True(caps.Supports(FeatureReadLookAhead))
+	a.False(caps.Enabled(FeatureReadLookAhead))
+	a.True(caps.Supports(FeatureAPM))
+	a.False(caps.Enabled(FeatureAPM))
+	a.True(caps.Enabled(FeatureLBA48))
+	a.True(caps.Supports(FeatureGPL))
+	a.True(caps.Supports(FeatureSMARTSelfTest))
+	a.True(caps.Supports(FeatureLogDMAExt))
+	a.False(caps.Enabled(FeatureLogDMAExt))
+	a.True(caps.Enabled(FeatureNCQ))
+	a.True(caps.Supports(FeaturePhyEventCounters))
+	a.True(caps.Enabled(FeatureDIPM))
+	a.True(caps.Supports(FeatureDevSleep))
+	a.False(caps.Enabled(FeatureDevSleep))
+	a.True(caps.Supports(FeatureTRIM))
+	a.True(caps.Supports(FeatureSCT))
+	a.True(caps.Supports(FeatureSCTErrorRecovery))
+	a.True(caps.Supports(FeatureSCTDataTables))
+	a.False(caps.Supports(FeatureSecurity))
+	a.False(caps.Supports(FeatureSanitize))
+
+	features := caps.Features()
+	a.Len(features, int(featureCount))
+	a.Equal(FeatureSMART, features[FeatureSMART].Feature)
+	a.Equal("SMART", features[FeatureSMART].Name)
+	a.Equal("Unknown", Feature(-1).String())
+
+	// invalid signature in word 83 hides the command set words
+	ident, _ = parseDevIdentify(sampleIdentify().setWord(82, 0x0001).setWord(83, 0x0000))
+	a.False(ident.Capabilities().Supports(FeatureSMART))
+
+	// the enabled words follow the signature of word 87, not word 83
+	ident, _ = parseDevIdentify(sampleIdentify().setWord(82, 0x0001).setWord(83, 0x4000).setWord(85, 0x0001))
+	a.True(ident.Capabilities().Supports(FeatureSMART))
+	a.False(ident.Capabilities().Enabled(FeatureSMART))
+
+	ident, _ = parseDevIdentify(sampleIdentify().setWord(82, 0x0001).setWord(83, 0x4000).setWord(85, 0x0001).setWord(87, 0x4000))
+	a.True(ident.Capabilities().Enabled(FeatureSMART))
+
+	// not identified device
+	var empty *Capabilities
+	a.False(empty.Supports(FeatureSMART))
+	a.Nil(newSATADev("/dev/sda").Capabilities())
+}
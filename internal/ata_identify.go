@@ -0,0 +1,413 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+const (
+	identifySize = 512
+
+	defaultLogicalSize = 512
+
+	// word 106 (7.12.6.56)
+	w106ValidMASK      = uint16(0xc000)
+	w106Valid          = uint16(0x4000)
+	w106MultiLogical   = 13
+	w106LongLogical    = 12
+	w106LogPerPhysMASK = uint16(0x000f)
+
+	// word 209 (7.12.6.75)
+	w209ValidMASK  = uint16(0xc000)
+	w209Valid      = uint16(0x4000)
+	w209OffsetMASK = uint16(0x3fff)
+
+	// word 222 (7.12.6.84)
+	transportTypePos    = 12
+	transportTypeSerial = uint16(0x1)
+
+	rotationNotReported = uint16(0x0000)
+	rotationNonRotating = uint16(0x0001)
+
+	// word 255 (7.12.6.91)
+	integritySignature = uint8(0xa5)
+
+	maxLBA48 = uint64(1) << 48
+)
+
+var (
+	ataMajorVersions = []string{
+		5:  "ATA/ATAPI-5",
+		6:  "ATA/ATAPI-6",
+		7:  "ATA/ATAPI-7",
+		8:  "ATA8-ACS",
+		9:  "ACS-2",
+		10: "ACS-3",
+		11: "ACS-4",
+		12: "ACS-5",
+	}
+
+	// ACS-3 Table 45 - Minor version number, the codes reported by
+	// drives in the field.
+	ataMinorVersions = map[uint16]string{
+		0x0013: "ATA/ATAPI-5 T13/1321D revision 3",
+		0x0015: "ATA/ATAPI-5 T13/1321D revision 1",
+		0x0016: "ATA/ATAPI-5 published, ANSI INCITS 340-2000",
+		0x0018: "ATA/ATAPI-6 T13/1410D revision 0",
+		0x001a: "ATA/ATAPI-7 T13/1532D revision 1",
+		0x001c: "ATA/ATAPI-6 T13/1410D revision 3b",
+		0x001d: "ATA/ATAPI-7 published, ANSI INCITS 397-2005",
+		0x001e: "ATA/ATAPI-7 T13/1532D revision 0",
+		0x0021: "ATA/ATAPI-7 T13/1532D revision 4a",
+		0x0022: "ATA/ATAPI-6 published, ANSI INCITS 361-2002",
+		0x0027: "ATA8-ACS T13/1699-D revision 3c",
+		0x0028: "ATA8-ACS T13/1699-D revision 6",
+		0x0029: "ATA8-ACS T13/1699-D revision 4",
+		0x0031: "ACS-2 T13/2015-D revision 2",
+		0x0033: "ATA8-ACS T13/1699-D revision 3e",
+		0x0039: "ATA8-ACS T13/1699-D revision 4c",
+		0x0042: "ATA8-ACS T13/1699-D revision 3f",
+		0x0052: "ATA8-ACS T13/1699-D revision 3b",
+		0x005e: "ACS-4 T13/BSR INCITS 529 revision 5",
+		0x006d: "ACS-3 T13/2161-D revision 5",
+		0x0082: "ACS-2 published, ANSI INCITS 482-2012",
+		0x0107: "ATA8-ACS T13/1699-D revision 2d",
+		0x010a: "ACS-3 published, ANSI INCITS 522-2014",
+		0x0110: "ACS-2 T13/2015-D revision 3",
+		0x011b: "ACS-3 T13/2161-D revision 4",
+	}
+
+	sataVersions = []string{
+		0:  "ATA8-AST",
+		1:  "SATA 1.0a",
+		2:  "SATA II Ext",
+		3:  "SATA 2.5",
+		4:  "SATA 2.6",
+		5:  "SATA 3.0",
+		6:  "SATA 3.1",
+		7:  "SATA 3.2",
+		8:  "SATA 3.3",
+		9:  "SATA 3.4",
+		10: "SATA 3.5",
+	}
+
+	formFactors = []string{
+		0: "",
+		1: "5.25 inches",
+		2: "3.5 inches",
+		3: "2.5 inches",
+		4: "1.8 inches",
+		5: "less than 1.8 inches",
+		6: "mSATA",
+		7: "M.2",
+		8: "MicroSSD",
+		9: "CFast",
+	}
+
+	ErrShortIdentify = errors.New("identify data is shorter than 512 bytes")
+)
+
+// AtaIdentity is the decoded IDENTIFY DEVICE data.
+type AtaIdentity struct {
+	Model    string
+	Firmware string
+	Serial   string
+	WWN      uint64
+
+	Sectors28      uint32 // words 60..61
+	Sectors48      uint64 // words 100..103
+	ExtSectors     uint64 // words 230..233
+	Capacity       uint64 // bytes of the user addressable sectors
+	LogicalSize    uint32
+	PhysicalSize   uint32
+	LogicalOffset  uint16 // logical sector offset within the first physical sector
+	RotationRate   uint16 // rpm, 0 if not reported and 1 for the non-rotating media
+	FormFactor     string
+	MajorVersion   string
+	MinorVersion   string
+	MinorCode      uint16
+	SATAVersion    string
+	TransportMajor uint16
+	Checksum       bool // integrity word is present and the checksum is verified
+}
+
+// IdentifyError reports the IDENTIFY DEVICE data failed the integrity or
+// the sanity check, which happens with some USB bridges and flaky
+// controllers.
+type IdentifyError struct {
+	Field  string
+	Reason string
+}
+
+func (e *IdentifyError) Error() string {
+	return fmt.Sprintf("corrupted identify data: %s %s", e.Field, e.Reason)
+}
+
+// IsSSD reports the device has the non-rotating media.
+func (id *AtaIdentity) IsSSD() bool {
+	return id.RotationRate == rotationNonRotating
+}
+
+func parseDevIdentify(buf []byte) (*DevIdentify, error) {
+	if len(buf) < identifySize {
+		return nil, ErrShortIdentify
+	}
+
+	ident := new(DevIdentify)
+	copy(ident.bytes(), buf)
+
+	return ident, nil
+}
+
+func (ident *DevIdentify) bytes() []byte {
+	return (*[identifySize]byte)(unsafe.Pointer(ident))[:]
+}
+
+func (ident *DevIdentify) word(w int) word {
+	return (*[identifySize / 2]word)(unsafe.Pointer(ident))[w]
+}
+
+func (ident *DevIdentify) logicalSize() uint32 {
+	w106 := ident.physPerLogSecs.uint16()
+	if w106&w106ValidMASK != w106Valid || !ident.physPerLogSecs.bit(w106LongLogical) {
+		return defaultLogicalSize
+	}
+
+	// words 117..118 are the number of words in a logical sector
+	if words := ident.sectorSize.uint32(); words >= defaultLogicalSize/2 {
+		return words * 2
+	}
+
+	return defaultLogicalSize
+}
+
+func (ident *DevIdentify) physicalSize(logical uint32) uint32 {
+	w106 := ident.physPerLogSecs.uint16()
+	if w106&w106ValidMASK != w106Valid || !ident.physPerLogSecs.bit(w106MultiLogical) {
+		return logical
+	}
+
+	return logical << (w106 & w106LogPerPhysMASK)
+}
+
+func (ident *DevIdentify) logicalOffset() uint16 {
+	w209 := ident.alignmentLSecs.uint16()
+	if w209&w209ValidMASK != w209Valid {
+		return 0
+	}
+
+	return w209 & w209OffsetMASK
+}
+
+func (ident *DevIdentify) userSectors() uint64 {
+	// 7.12.6.30, word 69 bit 3: Extended Number of User Addressable Sectors
+	if ident.additional.bit(3) {
+		if sectors := ident.extAddrSectors.uint64(); sectors > 0 {
+			return sectors
+		}
+	}
+
+	// 7.12.6.41, word 83 bit 10: 48-bit Address feature set
+	if ident.word(83).bit(10) {
+		if sectors := ident.sataSectors.uint64(); sectors > 0 {
+			return sectors
+		}
+	}
+
+	return uint64(ident.pATASectors.uint32())
+}
+
+func (ident *DevIdentify) wwn() uint64 {
+	var wwn uint64
+
+	for _, w := range ident.wwName {
+		wwn = wwn<<16 | uint64(w.uint16())
+	}
+
+	return wwn
+}
+
+func (ident *DevIdentify) majorVersionName() string {
+	major := ident.majorVersion.uint16()
+	if major == 0x0000 || major == 0xffff {
+		return ""
+	}
+
+	for pos := len(ataMajorVersions) - 1; pos >= 0; pos-- {
+		if major&(0x01<<uint(pos)) != 0 && ataMajorVersions[pos] != "" {
+			return ataMajorVersions[pos]
+		}
+	}
+
+	return ""
+}
+
+func (ident *DevIdentify) sataVersionName() string {
+	major := ident.transportMajor.uint16()
+	if major == 0x0000 || major == 0xffff || major>>transportTypePos != transportTypeSerial {
+		return ""
+	}
+
+	for pos := len(sataVersions) - 1; pos >= 0; pos-- {
+		if major&(0x01<<uint(pos)) != 0 {
+			return sataVersions[pos]
+		}
+	}
+
+	return ""
+}
+
+func (ident *DevIdentify) formFactorName() string {
+	if ff := int(ident.formFactor.uint16() & 0x000f); ff < len(formFactors) {
+		return formFactors[ff]
+	}
+
+	return ""
+}
+
+// hasChecksum reports the integrity word carries the checksum signature.
+func (ident *DevIdentify) hasChecksum() bool {
+	return ident.integrity[0] == integritySignature
+}
+
+// checksumOK verifies the sum of all 512 bytes including the checksum byte
+// is zero. The data without the signature has nothing to verify.
+func (ident *DevIdentify) checksumOK() bool {
+	if !ident.hasChecksum() {
+		return true
+	}
+
+	return checksum8(ident.bytes())
+}
+
+func validATAString(raw []byte) bool {
+	for _, b := range raw {
+		// ATA strings are printable ASCII padded by spaces, and some
+		// devices pad with NUL instead.
+		if (b < 0x20 || b > 0x7e) && b != 0x00 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Validate checks the integrity word and the decoded values which a broken
+// bridge tends to return, so the corrupted data is never taken as a device.
+func (ident *DevIdentify) Validate() error {
+	if !ident.checksumOK() {
+		return &IdentifyError{Field: "integrity word", Reason: "checksum mismatch"}
+	}
+
+	strs := []struct {
+		field string
+		raw   []byte
+	}{
+		{"model", ident.model[:]},
+		{"firmware", ident.firmware[:]},
+		{"serial", ident.serial[:]},
+	}
+
+	for _, str := range strs {
+		if !validATAString(str.raw) {
+			return &IdentifyError{Field: str.field, Reason: "has non-printable characters"}
+		}
+
+		if ataString(str.raw) == "" {
+			return &IdentifyError{Field: str.field, Reason: "is empty"}
+		}
+	}
+
+	// 7.12.6.2, word 0 bit 15 shall be cleared for ATA devices
+	if ident.generic.bit(15) {
+		return &IdentifyError{Field: "general configuration", Reason: "is not an ATA device"}
+	}
+
+	sectors := ident.userSectors()
+	if sectors == 0 || sectors >= maxLBA48 {
+		return &IdentifyError{Field: "capacity", Reason: fmt.Sprintf("has invalid sectors %d", sectors)}
+	}
+
+	if size := ident.logicalSize(); size%defaultLogicalSize != 0 {
+		return &IdentifyError{Field: "logical sector size", Reason: fmt.Sprintf("is invalid %d", size)}
+	}
+
+	return nil
+}
+
+// Decode converts the raw IDENTIFY DEVICE data to the AtaIdentity.
+func (ident *DevIdentify) Decode() *AtaIdentity {
+	id := &AtaIdentity{
+		Model:          ataString(ident.model[:]),
+		Firmware:       ataString(ident.firmware[:]),
+		Serial:         ataString(ident.serial[:]),
+		WWN:            ident.wwn(),
+		Sectors28:      ident.pATASectors.uint32(),
+		Sectors48:      ident.sataSectors.uint64(),
+		ExtSectors:     ident.extAddrSectors.uint64(),
+		LogicalSize:    ident.logicalSize(),
+		LogicalOffset:  ident.logicalOffset(),
+		RotationRate:   ident.rotationRate.uint16(),
+		FormFactor:     ident.formFactorName(),
+		MajorVersion:   ident.majorVersionName(),
+		MinorCode:      ident.minorVersion.uint16(),
+		SATAVersion:    ident.sataVersionName(),
+		TransportMajor: ident.transportMajor.uint16(),
+	}
+
+	id.PhysicalSize = ident.physicalSize(id.LogicalSize)
+	id.Capacity = ident.userSectors() * uint64(id.LogicalSize)
+	id.MinorVersion = ataMinorVersions[id.MinorCode]
+	id.Checksum = ident.hasChecksum() && ident.checksumOK()
+
+	return id
+}
+
+func (sata *SATADevice) readIdentify() (*DevIdentify, error) {
+	cmd := ata48BitCmd{command: AtaIdentifyDev}
+	cmd.setCount(1)
+
+	buf := make([]byte, identifySize)
+	if _, err := sata.execute(makePIODataInCDB(cmd), buf); err != nil {
+		return nil, err
+	}
+
+	return parseDevIdentify(buf)
+}
+
+// Identify issues IDENTIFY DEVICE and updates the model, firmware and serial
+// of the device. The corrupted data is returned as IdentifyError without
+// updating the device.
+func (sata *SATADevice) Identify() (*AtaIdentity, error) {
+	ident, err := sata.readIdentify()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ident.Validate(); err != nil {
+		return nil, err
+	}
+
+	sata.ident = ident
+	sata.identity = ident.Decode()
+
+	sata.model = sata.identity.Model
+	sata.firmware = sata.identity.Firmware
+	sata.serial = sata.identity.Serial
+
+	return sata.identity, nil
+}
+
+func (sata *SATADevice) Identity() *AtaIdentity {
+	return sata.identity
+}
+
+// Capacity is the user addressable bytes, 0 if the device is not identified.
+func (sata *SATADevice) Capacity() uint64 {
+	if sata.identity == nil {
+		return 0
+	}
+
+	return sata.identity.Capacity
+}
@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type identifyBuilder []byte
+
+func newIdentifyBuilder() identifyBuilder {
+	return make(identifyBuilder, identifySize)
+}
+
+func (b identifyBuilder) setWord(w int, value uint16) identifyBuilder {
+	binary.LittleEndian.PutUint16(b[w*2:], value)
+
+	return b
+}
+
+func (b identifyBuilder) setQword(w int, value uint64) identifyBuilder {
+	binary.LittleEndian.PutUint64(b[w*2:], value)
+
+	return b
+}
+
+// setString stores str as an ATA string padded with spaces.
+func (b identifyBuilder) setString(w, words int, str string) identifyBuilder {
+	field := b[w*2 : (w+words)*2]
+
+	for i := range field {
+		field[i] = ' '
+	}
+
+	copy(field, str)
+
+	for i := 0; i+1 < len(field); i += 2 {
+		field[i], field[i+1] = field[i+1], field[i]
+	}
+
+	return b
+}
+
+// setChecksum stores the integrity word with the signature and checksum.
+func (b identifyBuilder) setChecksum() identifyBuilder {
+	b[510] = integritySignature
+	b[511] = -sumBytes(b[:511])
+
+	return b
+}
+
+func sampleIdentify() identifyBuilder {
+	return newIdentifyBuilder().
+		setString(10, 10, "S3Z9NB0K123456A").
+		setString(23, 4, "RVT02B6Q").
+		setString(27, 20, "Samsung SSD 860 EVO 500GB").
+		setWord(60, 0xffff).setWord(61, 0x0fff).
+		setWord(80, 0x0ff0).
+		setWord(81, 0x005e).
+		setWord(83, 0x0400). // 48-bit address
+		setQword(100, 976773168).
+		setWord(106, 0x6003). // 8 logical sectors per physical sector
+		setWord(108, 0x5002).setWord(109, 0x538e).setWord(110, 0x4012).setWord(111, 0x3456).
+		setWord(168, 0x0003).
+		setWord(209, 0x4000).
+		setWord(217, 0x0001).
+		setWord(222, 0x10ff)
+}
+
+func TestAtaString(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("ST4000NM0033-9ZM170", ataString([]byte("TS0400MN00339-MZ71 0")))
+	a.Equal("AB", ataString([]byte{'B', 'A', 0x00, 0x00}))
+	a.Equal("", ataString([]byte("    ")))
+}
+
+func TestDevIdentifyDecode(t *testing.T) {
+	a := assert.New(t)
+
+	ident, err := parseDevIdentify(sampleIdentify())
+	a.NoError(err)
+
+	id := ident.Decode()
+	a.Equal("Samsung SSD 860 EVO 500GB", id.Model)
+	a.Equal("RVT02B6Q", id.Firmware)
+	a.Equal("S3Z9NB0K123456A", id.Serial)
+	a.Equal(uint64(0x5002538e40123456), id.WWN)
+	a.Equal(uint32(0x0fffffff), id.Sectors28)
+	a.Equal(uint64(976773168), id.Sectors48)
+	a.Equal(uint64(976773168*512), id.Capacity)
+	a.Equal(uint32(512), id.LogicalSize)
+	a.Equal(uint32(4096), id.PhysicalSize)
+	a.Equal(uint16(0), id.LogicalOffset)
+	a.True(id.IsSSD())
+	a.Equal("2.5 inches", id.FormFactor)
+	a.Equal("ACS-4", id.MajorVersion)
+	a.Equal("ACS-4 T13/BSR INCITS 529 revision 5", id.MinorVersion)
+	a.Equal("SATA 3.2", id.SATAVersion)
+
+	// 4Kn drive with the extended number of user addressable sectors
+	ident, _ = parseDevIdentify(sampleIdentify().
+		setWord(69, 0x0008).
+		setWord(106, 0x5000).
+		setWord(117, 0x0800).
+		setQword(230, 1953506646).
+		setWord(217, 7200))
+
+	id = ident.Decode()
+	a.Equal(uint32(4096), id.LogicalSize)
+	a.Equal(uint32(4096), id.PhysicalSize)
+	a.Equal(uint64(1953506646*4096), id.Capacity)
+	a.False(id.IsSSD())
+	a.Equal(uint16(7200), id.RotationRate)
+
+	_, err = parseDevIdentify(make([]byte, 100))
+	a.Equal(ErrShortIdentify, err)
+}
+
+func TestSATAIdentify(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeTransport(fakeResponse{data: sampleIdentify()})
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	id, err := sata.Identify()
+	a.NoError(err)
+	a.Equal(id, sata.Identity())
+
+	a.Equal(uint8(AtaIdentifyDev), transport.lastCDB()[14])
+	a.Equal(uint8(PIODataIn), transport.lastCDB()[1])
+
+	a.Equal("Samsung SSD 860 EVO 500GB", sata.Model())
+	a.Equal("RVT02B6Q", sata.Firmware())
+	a.Equal("S3Z9NB0K123456A", sata.Serial())
+	a.Equal(id.Capacity, sata.Capacity())
+	a.Equal(fmt.Sprintf("wwn-0x%016x", id.WWN), sata.ID())
+	a.Len(sata.Features(), int(featureCount))
+}
+
+func TestDevIdentifyValidate(t *testing.T) {
+	a := assert.New(t)
+
+	// without the integrity signature
+	ident, _ := parseDevIdentify(sampleIdentify())
+	a.NoError(ident.Validate())
+	a.False(ident.Decode().Checksum)
+
+	ident, _ = parseDevIdentify(sampleIdentify().setChecksum())
+	a.NoError(ident.Validate())
+	a.True(ident.Decode().Checksum)
+
+	tests := map[string]identifyBuilder{
+		"integrity word":        sampleIdentify().setChecksum().setWord(217, 7200),
+		"serial":                sampleIdentify().setString(10, 10, "S3Z9\x01\xffB0K1"),
+		"model":                 sampleIdentify().setString(27, 20, ""),
+		"general configuration": sampleIdentify().setWord(0, 0x8000),
+		"capacity":              sampleIdentify().setWord(60, 0).setWord(61, 0).setQword(100, 0),
+		"logical sector size":   sampleIdentify().setWord(106, 0x5000).setWord(117, 0x0101),
+	}
+
+	for field, data := range tests {
+		ident, _ := parseDevIdentify(data)
+		err := ident.Validate()
+
+		identErr, ok := err.(*IdentifyError)
+		if a.True(ok, field) {
+			a.Equal(field, identErr.Field)
+		}
+	}
+}
+
+func TestSATAIdentifyCorrupted(t *testing.T) {
+	a := assert.New(t)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = newFakeTransport(fakeResponse{data: sampleIdentify().setChecksum().setWord(100, 0)})
+
+	_, err := sata.Identify()
+	a.IsType(&IdentifyError{}, err)
+	a.Nil(sata.Identity())
+	a.Equal("", sata.Serial())
+}
@@ -0,0 +1,279 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	AtaReadLogExt    = 0x2F
+	AtaReadLogDMAExt = 0x47
+
+	ataLogPageSize = 512
+
+	ataLogDirectory = 0x00
+	ataLogAddresses = 256
+
+	// pages transferred by a READ LOG EXT command, the larger logs are read
+	// in chunks to stay below the transfer limit of the HBAs
+	ataLogChunkPages = 128
+
+	// SMART READ LOG has the 8-bit count without the page number
+	smartLogMaxPages = 255
+)
+
+var (
+	ErrGPLUnsupported = errors.New("device does not support general purpose logging")
+	ErrLogNotFound    = errors.New("log is not supported by the device")
+	ErrLogRange       = errors.New("log page range is out of the log")
+)
+
+// LogDirectory is the GPL directory or the SMART log directory, both have the
+// number of pages of each log address.
+type LogDirectory struct {
+	Version uint16
+	pages   [ataLogAddresses]uint16
+}
+
+// Pages is the number of the pages of the log, 0 if the log is not supported.
+func (dir *LogDirectory) Pages(addr uint8) int {
+	if addr == ataLogDirectory {
+		return 1
+	}
+
+	return int(dir.pages[addr])
+}
+
+// Logs lists the log addresses supported by the device.
+func (dir *LogDirectory) Logs() []uint8 {
+	logs := make([]uint8, 0)
+
+	for addr := 1; addr < ataLogAddresses; addr++ {
+		if dir.pages[addr] != 0 {
+			logs = append(logs, uint8(addr))
+		}
+	}
+
+	return logs
+}
+
+// ACS-3 A.2 Log directory
+func parseLogDirectory(buf []byte) *LogDirectory {
+	dir := &LogDirectory{Version: binary.LittleEndian.Uint16(buf[0:2])}
+
+	for addr := 1; addr < ataLogAddresses; addr++ {
+		dir.pages[addr] = binary.LittleEndian.Uint16(buf[addr*2:])
+	}
+
+	return dir
+}
+
+// makeReadLogExtCmd builds READ LOG EXT or READ LOG DMA EXT, the page number
+// is split into LBA 15:8 and LBA 39:32 (ACS-3 7.24.2).
+func makeReadLogExtCmd(addr uint8, page uint16, count uint16) ata48BitCmd {
+	cmd := ata48BitCmd{command: AtaReadLogExt}
+
+	cmd.setCount(count)
+	cmd.setLBA(uint64(page>>8)<<32 | uint64(page&0xff)<<8 | uint64(addr))
+
+	return cmd
+}
+
+func makeDMADataInCDB(cmd ata48BitCmd) ataCDB {
+	cdb := makeAtaCDB()
+
+	cdb.setProtocol(DMA)
+	cdb.devToHostDir()
+	cdb.setBlockSize(TLenCount)
+	cdb.setCommand(cmd)
+
+	return cdb
+}
+
+// SetLogDMA uses READ LOG DMA EXT instead of READ LOG EXT if the device
+// supports it.
+func (sata *SATADevice) SetLogDMA(enable bool) {
+	sata.logDMA = enable
+}
+
+func (sata *SATADevice) useLogDMA() bool {
+	return sata.logDMA && sata.Capabilities().Supports(FeatureLogDMAExt)
+}
+
+// readLogExt reads len(buf)/512 pages of the GPL log from the page by a
+// single command. READ LOG DMA EXT falls back to PIO if the device aborts it.
+func (sata *SATADevice) readLogExt(addr uint8, page uint16, buf []byte) error {
+	return sata.readLogExtFeature(addr, page, 0, buf)
+}
+
+// readLogExtFeature is readLogExt with the log specific feature field.
+func (sata *SATADevice) readLogExtFeature(addr uint8, page uint16, feature uint16, buf []byte) error {
+	cmd := makeReadLogExtCmd(addr, page, uint16(len(buf)/ataLogPageSize))
+	cmd.setFeature(feature)
+
+	if sata.useLogDMA() {
+		cmd.command = AtaReadLogDMAExt
+
+		cdb := makeDMADataInCDB(cmd)
+		cdb.setExtendBit()
+
+		_, err := sata.execute(cdb, buf)
+		if ataErr, ok := err.(*AtaError); !ok || !ataErr.Aborted() {
+			return err
+		}
+
+		cmd.command = AtaReadLogExt
+	}
+
+	cdb := makePIODataInCDB(cmd)
+	cdb.setExtendBit()
+
+	_, err := sata.execute(cdb, buf)
+
+	return err
+}
+
+// smartReadLog reads len(buf)/512 pages of the SMART log by SMART READ LOG.
+func (sata *SATADevice) smartReadLog(addr uint8, buf []byte) error {
+	cmd := makeSmartCmd(SmartReadLog, addr, uint16(len(buf)/ataLogPageSize))
+
+	_, err := sata.execute(makePIODataInCDB(cmd), buf)
+
+	return err
+}
+
+// smartWriteLog writes len(buf)/512 pages to the SMART log by SMART WRITE LOG,
+// and returns the ATA output registers by CK_COND for the logs reporting the
+// result in the registers.
+func (sata *SATADevice) smartWriteLog(addr uint8, buf []byte) (*ataRegisters, error) {
+	cdb := makePIODataOutCDB(makeSmartCmd(SmartWriteLog, addr, uint16(len(buf)/ataLogPageSize)))
+	cdb.setCheckCond()
+
+	resp, err := sata.execute(cdb, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.regs, nil
+}
+
+// ReadGPLDirectory reads the GPL directory by READ LOG EXT.
+func (sata *SATADevice) ReadGPLDirectory() (*LogDirectory, error) {
+	if caps := sata.Capabilities(); caps != nil && !caps.Supports(FeatureGPL) {
+		return nil, ErrGPLUnsupported
+	}
+
+	buf := make([]byte, ataLogPageSize)
+
+	if err := sata.readLogExt(ataLogDirectory, 0, buf); err != nil {
+		return nil, err
+	}
+
+	sata.gplDir = parseLogDirectory(buf)
+
+	return sata.gplDir, nil
+}
+
+// ReadSMARTLogDirectory reads the SMART log directory by SMART READ LOG.
+func (sata *SATADevice) ReadSMARTLogDirectory() (*LogDirectory, error) {
+	buf := make([]byte, ataLogPageSize)
+
+	if err := sata.smartReadLog(ataLogDirectory, buf); err != nil {
+		return nil, err
+	}
+
+	sata.smartLogDir = parseLogDirectory(buf)
+
+	return sata.smartLogDir, nil
+}
+
+// gplPages looks up the pages of the GPL log, and reads the directory at the
+// first time.
+func (sata *SATADevice) gplPages(addr uint8) (int, error) {
+	if sata.gplDir == nil {
+		if _, err := sata.ReadGPLDirectory(); err != nil {
+			return 0, err
+		}
+	}
+
+	return sata.gplDir.Pages(addr), nil
+}
+
+// smartLogPages looks up the pages of the SMART log, and reads the directory
+// at the first time.
+func (sata *SATADevice) smartLogPages(addr uint8) (int, error) {
+	if sata.smartLogDir == nil {
+		if _, err := sata.ReadSMARTLogDirectory(); err != nil {
+			return 0, err
+		}
+	}
+
+	return sata.smartLogDir.Pages(addr), nil
+}
+
+// ReadLog reads the pages of the GPL log from the page, or all pages of the
+// log from the page if pages is 0. The pages are read in chunks.
+func (sata *SATADevice) ReadLog(addr uint8, page uint16, pages int) ([]byte, error) {
+	total, err := sata.gplPages(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if total == 0 {
+		return nil, fmt.Errorf("log 0x%02x: %w", addr, ErrLogNotFound)
+	}
+
+	if pages <= 0 {
+		pages = total - int(page)
+	}
+
+	if pages <= 0 || int(page)+pages > total {
+		return nil, fmt.Errorf("log 0x%02x pages %d+%d of %d: %w", addr, page, pages, total, ErrLogRange)
+	}
+
+	buf := make([]byte, pages*ataLogPageSize)
+
+	for done := 0; done < pages; done += ataLogChunkPages {
+		count := pages - done
+		if count > ataLogChunkPages {
+			count = ataLogChunkPages
+		}
+
+		chunk := buf[done*ataLogPageSize : (done+count)*ataLogPageSize]
+		if err := sata.readLogExt(addr, page+uint16(done), chunk); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf, nil
+}
+
+// ReadSMARTLog reads the pages of the SMART log, or all pages of the log if
+// pages is 0.
+func (sata *SATADevice) ReadSMARTLog(addr uint8, pages int) ([]byte, error) {
+	total, err := sata.smartLogPages(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if total == 0 {
+		return nil, fmt.Errorf("smart log 0x%02x: %w", addr, ErrLogNotFound)
+	}
+
+	if pages <= 0 {
+		pages = total
+	}
+
+	if pages > total || pages > smartLogMaxPages {
+		return nil, fmt.Errorf("smart log 0x%02x pages %d of %d: %w", addr, pages, total, ErrLogRange)
+	}
+
+	buf := make([]byte, pages*ataLogPageSize)
+
+	if err := sata.smartReadLog(addr, buf); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
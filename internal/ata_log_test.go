@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleLogDirectory(pages map[uint8]uint16) []byte {
+	buf := make([]byte, ataLogPageSize)
+	binary.LittleEndian.PutUint16(buf, 0x0001)
+
+	for addr, count := range pages {
+		binary.LittleEndian.PutUint16(buf[int(addr)*2:], count)
+	}
+
+	return buf
+}
+
+func TestParseLogDirectory(t *testing.T) {
+	a := assert.New(t)
+
+	dir := parseLogDirectory(sampleLogDirectory(map[uint8]uint16{0x03: 4, 0x04: 8, 0xa0: 1}))
+
+	a.Equal(uint16(1), dir.Version)
+	a.Equal(1, dir.Pages(ataLogDirectory))
+	a.Equal(4, dir.Pages(0x03))
+	a.Equal(8, dir.Pages(0x04))
+	a.Equal(0, dir.Pages(0x11))
+	a.Equal([]uint8{0x03, 0x04, 0xa0}, dir.Logs())
+}
+
+func TestMakeReadLogExtCmd(t *testing.T) {
+	a := assert.New(t)
+
+	var cdb ataCDB
+	cdb.setCommand(makeReadLogExtCmd(0x04, 0x0102, 3))
+
+	a.Equal(uint8(AtaReadLogExt), cdb[14])
+	a.Equal([]byte{0x00, 0x03}, cdb[5:7])
+	a.Equal([]byte{0x00, 0x04}, cdb[7:9])
+	a.Equal([]byte{0x01, 0x02}, cdb[9:11])
+	a.Equal([]byte{0x00, 0x00}, cdb[11:13])
+}
+
+func TestSATAReadLog(t *testing.T) {
+	a := assert.New(t)
+
+	pages := ataLogChunkPages + 2
+
+	transport := newFakeTransport(
+		fakeResponse{data: sampleLogDirectory(map[uint8]uint16{0x04: uint16(pages)})},
+		fakeResponse{data: []byte{0x01}},
+		fakeResponse{data: []byte{0x02}},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	buf, err := sata.ReadLog(0x04, 0, 0)
+	a.NoError(err)
+	a.Len(buf, pages*ataLogPageSize)
+	a.Equal(uint8(0x01), buf[0])
+	a.Equal(uint8(0x02), buf[ataLogChunkPages*ataLogPageSize])
+
+	// directory
+	cdb := transport.cdbs[0]
+	a.Equal(uint8(AtaReadLogExt), cdb[14])
+	a.Equal(uint8(ataLogDirectory), cdb[8])
+
+	// chunks
+	cdb = transport.cdbs[1]
+	a.Equal(uint8(ataLogChunkPages), cdb[6])
+	a.Equal(uint8(0x00), cdb[10])
+
+	cdb = transport.cdbs[2]
+	a.Equal(uint8(2), cdb[6])
+	a.Equal(uint8(ataLogChunkPages), cdb[10])
+	a.Equal(uint8(PIODataIn), cdb[1]&ProtocolMASK)
+	a.Equal(ExtendMASK, cdb[1]&ExtendMASK)
+
+	// the directory is cached
+	transport.responses = []fakeResponse{{data: []byte{0x03}}}
+	buf, err = sata.ReadLog(0x04, 1, 1)
+	a.NoError(err)
+	a.Len(buf, ataLogPageSize)
+	a.Equal(uint8(1), transport.lastCDB()[10])
+
+	_, err = sata.ReadLog(0x04, 1, pages)
+	a.True(errors.Is(err, ErrLogRange))
+
+	_, err = sata.ReadLog(0x11, 0, 0)
+	a.True(errors.Is(err, ErrLogNotFound))
+
+	// devices without GPL
+	sata = newSATADev("/dev/sda")
+	sata.transport = newFakeTransport(fakeResponse{data: sampleIdentify().setWord(84, 0x4000).setWord(87, 0x4000)})
+	_, err = sata.Identify()
+	a.NoError(err)
+
+	_, err = sata.ReadGPLDirectory()
+	a.Equal(ErrGPLUnsupported, err)
+}
+
+func TestSATAReadLogDMA(t *testing.T) {
+	a := assert.New(t)
+
+	ident := sampleIdentify().setWord(84, 0x4020).setWord(87, 0x4020).setWord(119, 0x4008).setWord(120, 0x4008)
+	aborted := ScsiResponse{Status: ScsiCheckCondition, Sense: ataReturnSense(ataStatusERR|ataStatusDRDY, ataErrorABRT)}
+
+	transport := newFakeTransport(
+		fakeResponse{data: ident},
+		fakeResponse{data: sampleLogDirectory(map[uint8]uint16{0x04: 1})},
+		fakeResponse{resp: aborted},
+		fakeResponse{data: []byte{0x01}},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+	sata.SetLogDMA(true)
+
+	_, err := sata.Identify()
+	a.NoError(err)
+
+	buf, err := sata.ReadLog(0x04, 0, 1)
+	a.NoError(err)
+	a.Equal(uint8(0x01), buf[0])
+
+	// DMA for the directory and the aborted log, PIO for the retry
+	a.Equal(uint8(AtaReadLogDMAExt), transport.cdbs[1][14])
+	a.Equal(uint8(DMA), transport.cdbs[1][1]&ProtocolMASK)
+	a.Equal(uint8(AtaReadLogDMAExt), transport.cdbs[2][14])
+	a.Equal(uint8(AtaReadLogExt), transport.cdbs[3][14])
+	a.Equal(uint8(PIODataIn), transport.cdbs[3][1]&ProtocolMASK)
+}
+
+func TestSATAReadSMARTLog(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeTransport(
+		fakeResponse{data: sampleLogDirectory(map[uint8]uint16{ataLogCompError: 2})},
+		fakeResponse{data: []byte{0x01}},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	buf, err := sata.ReadSMARTLog(ataLogCompError, 0)
+	a.NoError(err)
+	a.Len(buf, 2*ataLogPageSize)
+
+	cdb := transport.cdbs[0]
+	a.Equal(uint8(SmartReadLog), cdb[4])
+	a.Equal(uint8(ataLogDirectory), cdb[8])
+
+	cdb = transport.lastCDB()
+	a.Equal(uint8(SmartReadLog), cdb[4])
+	a.Equal(uint8(2), cdb[6])
+	a.Equal(uint8(ataLogCompError), cdb[8])
+
+	_, err = sata.ReadSMARTLog(ataLogCompError, 3)
+	a.True(errors.Is(err, ErrLogRange))
+
+	_, err = sata.ReadSMARTLog(ataLogSelfTest, 0)
+	a.True(errors.Is(err, ErrLogNotFound))
+}
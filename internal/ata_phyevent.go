@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	ataLogPhyEvents = 0x11
+
+	// READ LOG EXT feature to reset the counters after reading the log
+	phyEventReset = uint16(0x0001)
+
+	phyEventOffset   = 4
+	phyEventIDMASK   = uint16(0x0fff)
+	phyEventVendor   = uint16(0x8000)
+	phyEventSizePos  = 12
+	phyEventSizeMASK = uint16(0x0007)
+)
+
+// SATA 3.3 13.7.3 Phy Event Counters identifiers
+const (
+	PhyEventICRCErrors       = uint16(0x001)
+	PhyEventRErrData         = uint16(0x002)
+	PhyEventRErrD2HData      = uint16(0x003)
+	PhyEventRErrH2DData      = uint16(0x004)
+	PhyEventRErrNonData      = uint16(0x005)
+	PhyEventRErrD2HNonData   = uint16(0x006)
+	PhyEventRErrH2DNonData   = uint16(0x007)
+	PhyEventD2HNonDataRetry  = uint16(0x008)
+	PhyEventPhyRdyToNRdy     = uint16(0x009)
+	PhyEventCOMRESET         = uint16(0x00a)
+	PhyEventH2DCRCErrors     = uint16(0x00b)
+	PhyEventH2DNonCRCErrors  = uint16(0x00d)
+	PhyEventRErrH2DDataCRC   = uint16(0x00f)
+	PhyEventRErrH2DDataOther = uint16(0x010)
+	PhyEventRErrH2DNDCRC     = uint16(0x012)
+	PhyEventRErrH2DNDOther   = uint16(0x013)
+)
+
+var phyEventNames = map[uint16]string{
+	PhyEventICRCErrors:       "Command failed due to ICRC error",
+	PhyEventRErrData:         "R_ERR response for data FIS",
+	PhyEventRErrD2HData:      "R_ERR response for device-to-host data FIS",
+	PhyEventRErrH2DData:      "R_ERR response for host-to-device data FIS",
+	PhyEventRErrNonData:      "R_ERR response for non-data FIS",
+	PhyEventRErrD2HNonData:   "R_ERR response for device-to-host non-data FIS",
+	PhyEventRErrH2DNonData:   "R_ERR response for host-to-device non-data FIS",
+	PhyEventD2HNonDataRetry:  "Device-to-host non-data FIS retries",
+	PhyEventPhyRdyToNRdy:     "Transition from drive PhyRdy to drive PhyNRdy",
+	PhyEventCOMRESET:         "Device-to-host register FISes sent due to a COMRESET",
+	PhyEventH2DCRCErrors:     "CRC errors within host-to-device FIS",
+	PhyEventH2DNonCRCErrors:  "Non-CRC errors within host-to-device FIS",
+	PhyEventRErrH2DDataCRC:   "R_ERR response for host-to-device data FIS, CRC",
+	PhyEventRErrH2DDataOther: "R_ERR response for host-to-device data FIS, non-CRC",
+	PhyEventRErrH2DNDCRC:     "R_ERR response for host-to-device non-data FIS, CRC",
+	PhyEventRErrH2DNDOther:   "R_ERR response for host-to-device non-data FIS, non-CRC",
+}
+
+type PhyEventCounter struct {
+	ID     uint16
+	Vendor bool // vendor specific counter
+	Name   string
+	Bits   int // 16, 32, 48 or 64
+	Value  uint64
+}
+
+// Overflow reports the counter saturated at the maximum value of its width,
+// the counter without the valid width never overflows.
+func (counter *PhyEventCounter) Overflow() bool {
+	if counter.Bits <= 0 || counter.Bits > 64 {
+		return false
+	}
+
+	return counter.Value == uint64(1)<<(counter.Bits-1)<<1-1
+}
+
+// PhyEventCounters is the SATA Phy Event Counters log (11h).
+type PhyEventCounters struct {
+	Counters []PhyEventCounter
+}
+
+// Counter finds the standard counter, or nil if the device does not report.
+func (phy *PhyEventCounters) Counter(id uint16) *PhyEventCounter {
+	for i := range phy.Counters {
+		if phy.Counters[i].ID == id && !phy.Counters[i].Vendor {
+			return &phy.Counters[i]
+		}
+	}
+
+	return nil
+}
+
+// parsePhyEventCounters decodes the counters until the identifier 0, each
+// counter has the 16-bit identifier and the value of the size in 14:12 words.
+func parsePhyEventCounters(buf []byte) (*PhyEventCounters, error) {
+	if len(buf) < ataLogPageSize || !checksum8(buf[:ataLogPageSize]) {
+		return nil, fmt.Errorf("phy event counters: %w", ErrChecksum)
+	}
+
+	phy := &PhyEventCounters{Counters: make([]PhyEventCounter, 0)}
+
+	// the last byte is the checksum
+	end := ataLogPageSize - 1
+
+	for pos := phyEventOffset; pos+2 <= end; {
+		raw := binary.LittleEndian.Uint16(buf[pos:])
+		if raw&(phyEventVendor|phyEventIDMASK) == 0 {
+			break
+		}
+		pos += 2
+
+		size := int((raw>>phyEventSizePos)&phyEventSizeMASK) * 2
+		if size < 2 || size > 8 || pos+size > end {
+			return nil, fmt.Errorf("phy event counter 0x%04x has the invalid size %d", raw, size)
+		}
+
+		counter := PhyEventCounter{
+			ID:     raw & phyEventIDMASK,
+			Vendor: raw&phyEventVendor != 0,
+			Bits:   size * 8,
+		}
+
+		for i := size - 1; i >= 0; i-- {
+			counter.Value = counter.Value<<8 | uint64(buf[pos+i])
+		}
+		pos += size
+
+		if name, ok := phyEventNames[counter.ID]; ok && !counter.Vendor {
+			counter.Name = name
+		} else {
+			counter.Name = fmt.Sprintf("Unknown counter (0x%04x)", raw&(phyEventVendor|phyEventIDMASK))
+		}
+
+		phy.Counters = append(phy.Counters, counter)
+	}
+
+	return phy, nil
+}
+
+// ReadPhyEventCounters reads the SATA Phy Event Counters log. The counters
+// are reset after reading if reset is set, so the next read reports the
+// events of the interval.
+func (sata *SATADevice) ReadPhyEventCounters(reset bool) (*PhyEventCounters, error) {
+	if caps := sata.Capabilities(); caps != nil && !caps.Supports(FeaturePhyEventCounters) {
+		return nil, fmt.Errorf("phy event counters: %w", ErrLogNotFound)
+	}
+
+	pages, err := sata.gplPages(ataLogPhyEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	if pages == 0 {
+		return nil, fmt.Errorf("log 0x%02x: %w", ataLogPhyEvents, ErrLogNotFound)
+	}
+
+	feature := uint16(0)
+	if reset {
+		feature = phyEventReset
+	}
+
+	buf := make([]byte, ataLogPageSize)
+
+	if err := sata.readLogExtFeature(ataLogPhyEvents, 0, feature, buf); err != nil {
+		return nil, err
+	}
+
+	return parsePhyEventCounters(buf)
+}
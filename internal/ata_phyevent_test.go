@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func samplePhyEventCounters() []byte {
+	buf := make([]byte, ataLogPageSize)
+	pos := phyEventOffset
+
+	put := func(id uint16, words int, value uint64) {
+		binary.LittleEndian.PutUint16(buf[pos:], id|uint16(words)<<phyEventSizePos)
+		pos += 2
+
+		raw := make([]byte, 8)
+		binary.LittleEndian.PutUint64(raw, value)
+		copy(buf[pos:], raw[:words*2])
+		pos += words * 2
+	}
+
+	put(PhyEventICRCErrors, 1, 3)
+	put(PhyEventRErrData, 2, 0x00010002)
+	put(PhyEventCOMRESET, 1, 0xffff)
+	put(PhyEventPhyRdyToNRdy, 3, 0x0000_1234_5678)
+	put(0x8123, 4, 7)
+
+	buf[511] = -sumBytes(buf[:511])
+
+	return buf
+}
+
+func TestParsePhyEventCounters(t *testing.T) {
+	a := assert.New(t)
+
+	phy, err := parsePhyEventCounters(samplePhyEventCounters())
+	a.NoError(err)
+	a.Len(phy.Counters, 5)
+
+	icrc := phy.Counter(PhyEventICRCErrors)
+	a.Equal("Command failed due to ICRC error", icrc.Name)
+	a.Equal(16, icrc.Bits)
+	a.Equal(uint64(3), icrc.Value)
+	a.False(icrc.Overflow())
+
+	a.Equal(uint64(0x00010002), phy.Counter(PhyEventRErrData).Value)
+	a.Equal(32, phy.Counter(PhyEventRErrData).Bits)
+	a.True(phy.Counter(PhyEventCOMRESET).Overflow())
+	a.True((&PhyEventCounter{Bits: 64, Value: ^uint64(0)}).Overflow())
+	a.False((&PhyEventCounter{}).Overflow())
+	a.False((&PhyEventCounter{Bits: 72, Value: ^uint64(0)}).Overflow())
+	a.Equal(uint64(0x1234_5678), phy.Counter(PhyEventPhyRdyToNRdy).Value)
+
+	vendor := phy.Counters[4]
+	a.True(vendor.Vendor)
+	a.Equal(uint16(0x123), vendor.ID)
+	a.Equal(64, vendor.Bits)
+	a.Equal("Unknown counter (0x8123)", vendor.Name)
+	a.Nil(phy.Counter(0x123))
+
+	corrupted := samplePhyEventCounters()
+	corrupted[4]++
+	_, err = parsePhyEventCounters(corrupted)
+	a.True(errors.Is(err, ErrChecksum))
+
+	// counter with the invalid size
+	invalid := make([]byte, ataLogPageSize)
+	binary.LittleEndian.PutUint16(invalid[phyEventOffset:], PhyEventICRCErrors|5<<phyEventSizePos)
+	invalid[511] = -sumBytes(invalid[:511])
+	_, err = parsePhyEventCounters(invalid)
+	a.Error(err)
+}
+
+func TestSATAReadPhyEventCounters(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeTransport(
+		fakeResponse{data: sampleLogDirectory(map[uint8]uint16{ataLogPhyEvents: 1})},
+		fakeResponse{data: samplePhyEventCounters()},
+		fakeResponse{data: samplePhyEventCounters()},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	phy, err := sata.ReadPhyEventCounters(false)
+	a.NoError(err)
+	a.Len(phy.Counters, 5)
+
+	cdb := transport.lastCDB()
+	a.Equal(uint8(AtaReadLogExt), cdb[14])
+	a.Equal(uint8(ataLogPhyEvents), cdb[8])
+	a.Equal([]byte{0x00, 0x00}, cdb[3:5])
+
+	_, err = sata.ReadPhyEventCounters(true)
+	a.NoError(err)
+	a.Equal([]byte{0x00, 0x01}, transport.lastCDB()[3:5])
+
+	// not listed in the GPL directory
+	sata = newSATADev("/dev/sda")
+	sata.transport = newFakeTransport(fakeResponse{data: sampleLogDirectory(nil)})
+
+	_, err = sata.ReadPhyEventCounters(false)
+	a.True(errors.Is(err, ErrLogNotFound))
+}
@@ -0,0 +1,50 @@
+package internal
+
+const (
+	AtaCheckPowerMode = 0xE5
+
+	// CHECK POWER MODE count values (ACS-3 Table 49)
+	ataPowerStandbyZ = uint8(0x00)
+	ataPowerStandbyY = uint8(0x01)
+	ataPowerIdle     = uint8(0x80)
+	ataPowerIdleA    = uint8(0x81)
+	ataPowerIdleB    = uint8(0x82)
+	ataPowerIdleC    = uint8(0x83)
+	ataPowerActive   = uint8(0xff)
+)
+
+func ataPowerMode(count uint8) PowerMode {
+	switch count {
+	case ataPowerStandbyZ, ataPowerStandbyY:
+		return PowerModeStandby
+	case ataPowerIdle, ataPowerIdleA, ataPowerIdleB, ataPowerIdleC:
+		return PowerModeIdle
+	case ataPowerActive:
+		return PowerModeActive
+	}
+
+	return PowerModeUnknown
+}
+
+// PowerMode issues CHECK POWER MODE and reads back the count through
+// CK_COND, the command does not spin up the device. For the compatibility
+// with smartd, the device aborting the command is taken as SLEEP because the
+// device in SLEEP does not respond without the reset. The other errors are
+// returned as they are.
+func (sata *SATADevice) PowerMode() (PowerMode, error) {
+	cdb := makeNonDataCDB(ata48BitCmd{command: AtaCheckPowerMode})
+	cdb.setCheckCond()
+
+	resp, err := sata.execute(cdb, nil)
+	if e, ok := err.(*AtaError); ok && e.Aborted() {
+		return PowerModeSleep, nil
+	} else if err != nil {
+		return PowerModeUnknown, err
+	}
+
+	if resp.regs == nil {
+		return PowerModeUnknown, nil
+	}
+
+	return ataPowerMode(uint8(resp.regs.count)), nil
+}
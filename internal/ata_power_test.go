@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSATAPowerMode(t *testing.T) {
+	a := assert.New(t)
+
+	modes := []struct {
+		count uint8
+		mode  PowerMode
+	}{
+		{ataPowerStandbyY, PowerModeStandby},
+		{ataPowerIdleB, PowerModeIdle},
+		{ataPowerActive, PowerModeActive},
+		{0x40, PowerModeUnknown},
+	}
+
+	responses := make([]fakeResponse, 0)
+	for _, m := range modes {
+		regs := ataRegisters{status: ataStatusDRDY, count: uint16(m.count)}
+		responses = append(responses, fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: ataRegistersSense(regs)}})
+	}
+
+	responses = append(responses,
+		// aborted by the device in SLEEP
+		fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: ataReturnSense(ataStatusDRDY|ataStatusERR, ataErrorABRT)}},
+		// no sense data returned by the bridge
+		fakeResponse{resp: ScsiResponse{Status: ScsiGood}},
+		// the error other than ABRT, like UNC, is not taken as SLEEP
+		fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: ataReturnSense(ataStatusDRDY|ataStatusERR, 0x40)}},
+	)
+
+	transport := newFakeTransport(responses...)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	for _, m := range modes {
+		mode, err := sata.PowerMode()
+		a.NoError(err)
+		a.Equal(m.mode, mode)
+	}
+
+	cdb := ataCDB{}
+	copy(cdb[:], transport.lastCDB())
+	a.True(cdb.isCheckCond())
+	a.Equal(NonData, cdb.getProtocol())
+	a.Equal(uint8(AtaCheckPowerMode), cdb.getCommand())
+
+	mode, err := sata.PowerMode()
+	a.NoError(err)
+	a.Equal(PowerModeSleep, mode)
+
+	mode, err = sata.PowerMode()
+	a.NoError(err)
+	a.Equal(PowerModeUnknown, mode)
+
+	mode, err = sata.PowerMode()
+	a.Error(err)
+	a.Equal(PowerModeUnknown, mode)
+}
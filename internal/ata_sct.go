@@ -0,0 +1,301 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// SCT commands are written to the key page of log E0h, the status is read
+	// from the same log and the data of the command from log E1h
+	ataLogSCTCommand = 0xe0
+	ataLogSCTData    = 0xe1
+
+	// SCT action codes (ACS-3 Table 104)
+	sctActionErrorRecovery = uint16(0x0003)
+	sctActionDataTable     = uint16(0x0005)
+
+	// SCT Error Recovery Control function and selection codes
+	sctERCSet        = uint16(0x0001)
+	sctERCGet        = uint16(0x0002)
+	sctERCReadTimer  = uint16(0x0001)
+	sctERCWriteTimer = uint16(0x0002)
+	sctERCUnit       = 100 * time.Millisecond
+	sctERCMax        = 0xffff * sctERCUnit
+
+	// SCT Data Table function and table identifier
+	sctTableRead        = uint16(0x0001)
+	sctTableTempHistory = uint16(0x0002)
+
+	// SCT status (ACS-3 Table 105)
+	sctStatusTemp       = 200
+	sctStatusOverLimit  = 206
+	sctStatusUnderLimit = 210
+
+	// SCT temperature history table (ACS-3 Table 112)
+	sctHistoryLimits  = 6
+	sctHistorySize    = 30
+	sctHistoryIndex   = 32
+	sctHistoryEntries = 34
+
+	sctTempInvalid = -128
+)
+
+var (
+	ErrSCTUnsupported = errors.New("device does not support the SCT command")
+	ErrSCTFormat      = errors.New("unknown SCT data format")
+	ErrSCTNoResult    = errors.New("ATA registers with the SCT result are not returned")
+	ErrERCRange       = errors.New("error recovery timer is out of range")
+)
+
+// SCTTemperature is the temperature in Celsius reported by SCT, 80h if the
+// temperature is not valid.
+type SCTTemperature int8
+
+func (temp SCTTemperature) Valid() bool {
+	return temp != sctTempInvalid
+}
+
+func (temp SCTTemperature) String() string {
+	if !temp.Valid() {
+		return "?"
+	}
+
+	return fmt.Sprintf("%d", int8(temp))
+}
+
+// SCTDeviceState is the state of the device in the SCT status.
+type SCTDeviceState uint8
+
+const (
+	SCTStateActive = SCTDeviceState(iota)
+	SCTStateStandby
+	SCTStateSleep
+	SCTStateSelfTest
+	SCTStateOffline
+	SCTStateCommand
+)
+
+var sctDeviceStateNames = map[SCTDeviceState]string{
+	SCTStateActive:   "Active",
+	SCTStateStandby:  "Stand-by",
+	SCTStateSleep:    "Sleep",
+	SCTStateSelfTest: "DST executing in background",
+	SCTStateOffline:  "SMART Off-line Data Collection executing in background",
+	SCTStateCommand:  "SCT command executing in background",
+}
+
+func (state SCTDeviceState) String() string {
+	if name, ok := sctDeviceStateNames[state]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Unknown (0x%02x)", uint8(state))
+}
+
+// SCTStatus is the SCT status read from log E0h.
+type SCTStatus struct {
+	FormatVersion  uint16
+	SCTVersion     uint16
+	SCTSpec        uint16
+	Flags          uint32
+	DeviceState    SCTDeviceState
+	ExtendedStatus uint16 // status of the last SCT command
+	ActionCode     uint16 // last SCT command
+	FunctionCode   uint16
+
+	Temperature        SCTTemperature
+	MinTemperature     SCTTemperature // since power on
+	MaxTemperature     SCTTemperature
+	LifeMinTemperature SCTTemperature
+	LifeMaxTemperature SCTTemperature
+	OverLimitCount     uint32 // times the temperature exceeded the maximum limit
+	UnderLimitCount    uint32 // times the temperature fell below the minimum limit
+}
+
+// ACS-3 8.2 SCT Status, the format versions 2 and 3 are supported
+func parseSCTStatus(buf []byte) (*SCTStatus, error) {
+	status := &SCTStatus{
+		FormatVersion:  binary.LittleEndian.Uint16(buf[0:2]),
+		SCTVersion:     binary.LittleEndian.Uint16(buf[2:4]),
+		SCTSpec:        binary.LittleEndian.Uint16(buf[4:6]),
+		Flags:          binary.LittleEndian.Uint32(buf[6:10]),
+		DeviceState:    SCTDeviceState(buf[10]),
+		ExtendedStatus: binary.LittleEndian.Uint16(buf[14:16]),
+		ActionCode:     binary.LittleEndian.Uint16(buf[16:18]),
+		FunctionCode:   binary.LittleEndian.Uint16(buf[18:20]),
+	}
+
+	if status.FormatVersion != 2 && status.FormatVersion != 3 {
+		return nil, fmt.Errorf("sct status version %d: %w", status.FormatVersion, ErrSCTFormat)
+	}
+
+	temps := buf[sctStatusTemp:]
+	status.Temperature = SCTTemperature(temps[0])
+	status.MinTemperature = SCTTemperature(temps[1])
+	status.MaxTemperature = SCTTemperature(temps[2])
+	status.LifeMinTemperature = SCTTemperature(temps[3])
+	status.LifeMaxTemperature = SCTTemperature(temps[4])
+	status.OverLimitCount = binary.LittleEndian.Uint32(buf[sctStatusOverLimit:])
+	status.UnderLimitCount = binary.LittleEndian.Uint32(buf[sctStatusUnderLimit:])
+
+	return status, nil
+}
+
+type SCTTempSample struct {
+	Time        time.Time
+	Temperature SCTTemperature
+}
+
+// SCTTempHistory is the SCT temperature history table, the samples are
+// ordered from the oldest and timestamped back from the time of the read.
+type SCTTempHistory struct {
+	FormatVersion  uint16
+	SamplingPeriod time.Duration // between the temperature measurements
+	Interval       time.Duration // between the samples in the history
+	MaxOpLimit     SCTTemperature
+	OverLimit      SCTTemperature
+	MinOpLimit     SCTTemperature
+	UnderLimit     SCTTemperature
+	Samples        []SCTTempSample
+}
+
+// ACS-3 8.6.3 SCT temperature history table, the circular buffer is unwound
+// from the entry after the last updated one.
+func parseSCTTempHistory(buf []byte, now time.Time) (*SCTTempHistory, error) {
+	history := &SCTTempHistory{
+		FormatVersion:  binary.LittleEndian.Uint16(buf[0:2]),
+		SamplingPeriod: time.Duration(binary.LittleEndian.Uint16(buf[2:4])) * time.Minute,
+		Interval:       time.Duration(binary.LittleEndian.Uint16(buf[4:6])) * time.Minute,
+		MaxOpLimit:     SCTTemperature(buf[sctHistoryLimits]),
+		OverLimit:      SCTTemperature(buf[sctHistoryLimits+1]),
+		MinOpLimit:     SCTTemperature(buf[sctHistoryLimits+2]),
+		UnderLimit:     SCTTemperature(buf[sctHistoryLimits+3]),
+	}
+
+	if history.FormatVersion != 2 {
+		return nil, fmt.Errorf("sct temperature history version %d: %w", history.FormatVersion, ErrSCTFormat)
+	}
+
+	size := int(binary.LittleEndian.Uint16(buf[sctHistorySize:]))
+	index := int(binary.LittleEndian.Uint16(buf[sctHistoryIndex:]))
+
+	if size > ataLogPageSize-sctHistoryEntries || (size > 0 && index >= size) {
+		return nil, fmt.Errorf("sct temperature history size %d index %d: %w", size, index, ErrSCTFormat)
+	}
+
+	history.Samples = make([]SCTTempSample, size)
+
+	for n := 0; n < size; n++ {
+		age := time.Duration(size-1-n) * history.Interval
+
+		history.Samples[n] = SCTTempSample{
+			Time:        now.Add(-age),
+			Temperature: SCTTemperature(buf[sctHistoryEntries+(index+1+n)%size]),
+		}
+	}
+
+	return history, nil
+}
+
+// sctCommand writes the key page of the SCT command to log E0h and returns
+// the ATA output registers.
+func (sata *SATADevice) sctCommand(feature Feature, words ...uint16) (*ataRegisters, error) {
+	if caps := sata.Capabilities(); caps != nil && !(caps.Supports(FeatureSCT) && caps.Supports(feature)) {
+		return nil, ErrSCTUnsupported
+	}
+
+	key := make([]byte, ataLogPageSize)
+	for i, word := range words {
+		binary.LittleEndian.PutUint16(key[i*2:], word)
+	}
+
+	return sata.smartWriteLog(ataLogSCTCommand, key)
+}
+
+// ReadSCTStatus reads the SCT status with the current and the lifetime
+// temperatures.
+func (sata *SATADevice) ReadSCTStatus() (*SCTStatus, error) {
+	if caps := sata.Capabilities(); caps != nil && !caps.Supports(FeatureSCT) {
+		return nil, ErrSCTUnsupported
+	}
+
+	buf := make([]byte, ataLogPageSize)
+
+	if err := sata.smartReadLog(ataLogSCTCommand, buf); err != nil {
+		return nil, err
+	}
+
+	return parseSCTStatus(buf)
+}
+
+// ReadSCTTempHistory reads the temperature history by the SCT Data Table
+// command.
+func (sata *SATADevice) ReadSCTTempHistory() (*SCTTempHistory, error) {
+	if _, err := sata.sctCommand(FeatureSCTDataTables, sctActionDataTable, sctTableRead, sctTableTempHistory); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, ataLogPageSize)
+
+	if err := sata.smartReadLog(ataLogSCTData, buf); err != nil {
+		return nil, err
+	}
+
+	return parseSCTTempHistory(buf, time.Now())
+}
+
+func (sata *SATADevice) getERC(selection uint16) (time.Duration, error) {
+	regs, err := sata.sctCommand(FeatureSCTErrorRecovery, sctActionErrorRecovery, sctERCGet, selection)
+	if err != nil {
+		return 0, err
+	}
+
+	if regs == nil {
+		return 0, ErrSCTNoResult
+	}
+
+	// the timer is returned in count 7:0 and LBA 7:0 of the 28-bit registers
+	value := uint16(uint8(regs.lba))<<8 | uint16(uint8(regs.count))
+
+	return time.Duration(value) * sctERCUnit, nil
+}
+
+func (sata *SATADevice) setERC(selection uint16, timer time.Duration) error {
+	_, err := sata.sctCommand(FeatureSCTErrorRecovery, sctActionErrorRecovery, sctERCSet, selection, uint16(timer/sctERCUnit))
+
+	return err
+}
+
+// ErrorRecoveryControl reads the read and the write error recovery timers,
+// 0 if the recovery time is not limited.
+func (sata *SATADevice) ErrorRecoveryControl() (read time.Duration, write time.Duration, err error) {
+	if read, err = sata.getERC(sctERCReadTimer); err != nil {
+		return 0, 0, err
+	}
+
+	if write, err = sata.getERC(sctERCWriteTimer); err != nil {
+		return 0, 0, err
+	}
+
+	return read, write, nil
+}
+
+// SetErrorRecoveryControl sets the read and the write error recovery timers
+// by 100 milliseconds, 0 disables the limit. The timers are volatile and
+// return to the defaults after the power cycle.
+func (sata *SATADevice) SetErrorRecoveryControl(read time.Duration, write time.Duration) error {
+	for _, timer := range []time.Duration{read, write} {
+		if timer < 0 || timer > sctERCMax {
+			return fmt.Errorf("%v: %w", timer, ErrERCRange)
+		}
+	}
+
+	if err := sata.setERC(sctERCReadTimer, read); err != nil {
+		return err
+	}
+
+	return sata.setERC(sctERCWriteTimer, write)
+}
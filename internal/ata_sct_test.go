@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleSCTStatus() []byte {
+	buf := make([]byte, ataLogPageSize)
+
+	binary.LittleEndian.PutUint16(buf[0:], 3)
+	binary.LittleEndian.PutUint16(buf[2:], 0x100)
+	binary.LittleEndian.PutUint16(buf[4:], 1)
+	buf[10] = byte(SCTStateSelfTest)
+	binary.LittleEndian.PutUint16(buf[16:], sctActionErrorRecovery)
+	binary.LittleEndian.PutUint16(buf[18:], sctERCGet)
+
+	copy(buf[sctStatusTemp:], []byte{36, 25, 41, 0xfb, 58})
+	binary.LittleEndian.PutUint32(buf[sctStatusOverLimit:], 2)
+	binary.LittleEndian.PutUint32(buf[sctStatusUnderLimit:], 1)
+
+	return buf
+}
+
+func sampleSCTTempHistory(temps []byte, index int) []byte {
+	buf := make([]byte, ataLogPageSize)
+
+	binary.LittleEndian.PutUint16(buf[0:], 2)
+	binary.LittleEndian.PutUint16(buf[2:], 1)
+	binary.LittleEndian.PutUint16(buf[4:], 10)
+	copy(buf[sctHistoryLimits:], []byte{70, 65, 0, 5})
+	binary.LittleEndian.PutUint16(buf[sctHistorySize:], uint16(len(temps)))
+	binary.LittleEndian.PutUint16(buf[sctHistoryIndex:], uint16(index))
+	copy(buf[sctHistoryEntries:], temps)
+
+	return buf
+}
+
+func TestParseSCTStatus(t *testing.T) {
+	a := assert.New(t)
+
+	status, err := parseSCTStatus(sampleSCTStatus())
+	a.NoError(err)
+	a.Equal(uint16(3), status.FormatVersion)
+	a.Equal(SCTStateSelfTest, status.DeviceState)
+	a.Equal("DST executing in background", status.DeviceState.String())
+	a.Equal(sctActionErrorRecovery, status.ActionCode)
+	a.Equal(SCTTemperature(36), status.Temperature)
+	a.Equal(SCTTemperature(25), status.MinTemperature)
+	a.Equal(SCTTemperature(41), status.MaxTemperature)
+	a.Equal(SCTTemperature(-5), status.LifeMinTemperature)
+	a.Equal("-5", status.LifeMinTemperature.String())
+	a.Equal(SCTTemperature(58), status.LifeMaxTemperature)
+	a.Equal(uint32(2), status.OverLimitCount)
+	a.Equal(uint32(1), status.UnderLimitCount)
+
+	invalid := sampleSCTStatus()
+	invalid[sctStatusTemp] = 0x80
+	status, err = parseSCTStatus(invalid)
+	a.NoError(err)
+	a.False(status.Temperature.Valid())
+	a.Equal("?", status.Temperature.String())
+
+	unknown := sampleSCTStatus()
+	unknown[0] = 1
+	_, err = parseSCTStatus(unknown)
+	a.True(errors.Is(err, ErrSCTFormat))
+}
+
+func TestParseSCTTempHistory(t *testing.T) {
+	a := assert.New(t)
+
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// the last updated entry is 1, the oldest sample is 2
+	history, err := parseSCTTempHistory(sampleSCTTempHistory([]byte{33, 34, 0x80, 31, 32}, 1), now)
+	a.NoError(err)
+	a.Equal(time.Minute, history.SamplingPeriod)
+	a.Equal(10*time.Minute, history.Interval)
+	a.Equal(SCTTemperature(70), history.MaxOpLimit)
+	a.Equal(SCTTemperature(5), history.UnderLimit)
+	a.Len(history.Samples, 5)
+
+	a.False(history.Samples[0].Temperature.Valid())
+	a.Equal(now.Add(-40*time.Minute), history.Samples[0].Time)
+
+	temps := make([]SCTTemperature, 0)
+	for _, sample := range history.Samples[1:] {
+		temps = append(temps, sample.Temperature)
+	}
+	a.Equal([]SCTTemperature{31, 32, 33, 34}, temps)
+	a.Equal(now, history.Samples[4].Time)
+
+	_, err = parseSCTTempHistory(sampleSCTTempHistory([]byte{30, 31}, 2), now)
+	a.True(errors.Is(err, ErrSCTFormat))
+}
+
+func TestSATASCT(t *testing.T) {
+	a := assert.New(t)
+
+	// the timers are returned in LBA 7:0 and count 7:0, read 7.0s and write 25.6s
+	readTimer := ataRegisters{status: ataStatusDRDY, count: 70}
+	writeTimer := ataRegisters{status: ataStatusDRDY, lba: 0x01 | smartSignature, count: 0x00}
+
+	transport := newFakeTransport(
+		fakeResponse{data: sampleSCTStatus()},
+		fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: ataReturnSense(ataStatusDRDY, 0)}},
+		fakeResponse{data: sampleSCTTempHistory([]byte{30, 31}, 0)},
+		fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: ataRegistersSense(readTimer)}},
+		fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: ataRegistersSense(writeTimer)}},
+		fakeResponse{},
+		fakeResponse{},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	status, err := sata.ReadSCTStatus()
+	a.NoError(err)
+	a.Equal(SCTTemperature(36), status.Temperature)
+	a.Equal(uint8(SmartReadLog), transport.lastCDB()[4])
+	a.Equal(uint8(ataLogSCTCommand), transport.lastCDB()[8])
+
+	history, err := sata.ReadSCTTempHistory()
+	a.NoError(err)
+	a.Len(history.Samples, 2)
+	a.Equal(SCTTemperature(31), history.Samples[0].Temperature)
+
+	cdb := ataCDB{}
+	copy(cdb[:], transport.cdbs[1])
+	a.Equal(uint8(SmartWriteLog), cdb[4])
+	a.Equal(uint8(ataLogSCTCommand), cdb[8])
+	a.Equal(PIODataOut, cdb.getProtocol())
+	a.True(cdb.isCheckCond())
+	a.Equal(DataToDev, transport.dirs[1])
+	a.Equal([]byte{0x05, 0x00, 0x01, 0x00, 0x02, 0x00}, transport.written[0][:6])
+	a.Equal(uint8(ataLogSCTData), transport.lastCDB()[8])
+
+	read, write, err := sata.ErrorRecoveryControl()
+	a.NoError(err)
+	a.Equal(7*time.Second, read)
+	a.Equal(25600*time.Millisecond, write)
+	a.Equal([]byte{0x03, 0x00, 0x02, 0x00, 0x02, 0x00}, transport.written[2][:6])
+
+	a.NoError(sata.SetErrorRecoveryControl(7*time.Second, 0))
+	a.Equal([]byte{0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 70, 0x00}, transport.written[3][:8])
+	a.Equal([]byte{0x03, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00}, transport.written[4][:8])
+
+	err = sata.SetErrorRecoveryControl(time.Hour*2, 0)
+	a.True(errors.Is(err, ErrERCRange))
+
+	// the result is not returned by the bridge
+	sata.transport = newFakeTransport(fakeResponse{})
+	_, _, err = sata.ErrorRecoveryControl()
+	a.True(errors.Is(err, ErrSCTNoResult))
+
+	// SCT is not supported by the identified device
+	sata.ident = &DevIdentify{}
+	_, err = sata.ReadSCTStatus()
+	a.True(errors.Is(err, ErrSCTUnsupported))
+	_, _, err = sata.ErrorRecoveryControl()
+	a.True(errors.Is(err, ErrSCTUnsupported))
+}
@@ -0,0 +1,356 @@
+package internal
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const (
+	ataLogSelfTest    = 0x06
+	ataLogExtSelfTest = 0x07
+
+	// Off-line data collection capability (byte 367)
+	offlineCapExecImmediate = uint8(0x01)
+	offlineCapSelfTest      = uint8(0x10)
+	offlineCapConveyance    = uint8(0x20)
+	offlineCapSelective     = uint8(0x40)
+
+	selfTestStatusPos  = 4
+	selfTestRemainMASK = uint8(0x0f)
+
+	// SMART self-test log (ACS-3 A.15)
+	selfTestEntries   = 21
+	selfTestEntryOff  = 2
+	selfTestEntrySize = 24
+	selfTestIndexOff  = 508
+	selfTestLBA32Mask = uint64(0xffffffff)
+
+	// Extended SMART self-test log (ACS-3 A.9)
+	extSelfTestEntries   = 19
+	extSelfTestEntryOff  = 4
+	extSelfTestEntrySize = 26
+	extSelfTestLBA48Mask = uint64(0xffffffffffff)
+)
+
+// AtaSelfTest is the subcommand of SMART EXECUTE OFF-LINE IMMEDIATE put in
+// LBA low, and the self-test logs record it in the same way.
+type AtaSelfTest uint8
+
+const (
+	AtaSelfTestOffline           = AtaSelfTest(0x00)
+	AtaSelfTestShort             = AtaSelfTest(0x01)
+	AtaSelfTestExtended          = AtaSelfTest(0x02)
+	AtaSelfTestConveyance        = AtaSelfTest(0x03)
+	AtaSelfTestSelective         = AtaSelfTest(0x04)
+	AtaSelfTestAbort             = AtaSelfTest(0x7f)
+	AtaSelfTestShortCaptive      = AtaSelfTest(0x81)
+	AtaSelfTestExtendedCaptive   = AtaSelfTest(0x82)
+	AtaSelfTestConveyanceCaptive = AtaSelfTest(0x83)
+	AtaSelfTestSelectiveCaptive  = AtaSelfTest(0x84)
+)
+
+var ataSelfTestNames = map[AtaSelfTest]string{
+	AtaSelfTestOffline:           "Offline",
+	AtaSelfTestShort:             "Short offline",
+	AtaSelfTestExtended:          "Extended offline",
+	AtaSelfTestConveyance:        "Conveyance offline",
+	AtaSelfTestSelective:         "Selective offline",
+	AtaSelfTestAbort:             "Abort offline test",
+	AtaSelfTestShortCaptive:      "Short captive",
+	AtaSelfTestExtendedCaptive:   "Extended captive",
+	AtaSelfTestConveyanceCaptive: "Conveyance captive",
+	AtaSelfTestSelectiveCaptive:  "Selective captive",
+}
+
+func (test AtaSelfTest) String() string {
+	if name, ok := ataSelfTestNames[test]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Vendor (0x%02x)", uint8(test))
+}
+
+// SelfTestStatus is the self-test execution status byte, the status in
+// 7:4 and the percent remaining by 10% in 3:0.
+type SelfTestStatus uint8
+
+const (
+	SelfTestCompleted      = uint8(0x0)
+	SelfTestAbortedByHost  = uint8(0x1)
+	SelfTestInterrupted    = uint8(0x2)
+	SelfTestFatal          = uint8(0x3)
+	SelfTestUnknownFailure = uint8(0x4)
+	SelfTestElectrical     = uint8(0x5)
+	SelfTestServo          = uint8(0x6)
+	SelfTestRead           = uint8(0x7)
+	SelfTestHandling       = uint8(0x8)
+	SelfTestInProgress     = uint8(0xf)
+)
+
+// ACS-3 Table 133 - Self-test execution status values
+var selfTestStatusNames = map[uint8]string{
+	SelfTestCompleted:      "Completed without error",
+	SelfTestAbortedByHost:  "Aborted by host",
+	SelfTestInterrupted:    "Interrupted (host reset)",
+	SelfTestFatal:          "Fatal or unknown error",
+	SelfTestUnknownFailure: "Completed: unknown failure",
+	SelfTestElectrical:     "Completed: electrical failure",
+	SelfTestServo:          "Completed: servo/seek failure",
+	SelfTestRead:           "Completed: read failure",
+	SelfTestHandling:       "Completed: handling damage",
+	SelfTestInProgress:     "Self-test routine in progress",
+}
+
+func (status SelfTestStatus) Code() uint8 {
+	return uint8(status) >> selfTestStatusPos
+}
+
+// Remaining is the percent of the running test left to complete.
+func (status SelfTestStatus) Remaining() int {
+	return int(uint8(status)&selfTestRemainMASK) * 10
+}
+
+func (status SelfTestStatus) InProgress() bool {
+	return status.Code() == SelfTestInProgress
+}
+
+// Failed reports the test completed and found the error.
+func (status SelfTestStatus) Failed() bool {
+	return status.Code() >= SelfTestFatal && status.Code() <= SelfTestHandling
+}
+
+func (status SelfTestStatus) String() string {
+	if name, ok := selfTestStatusNames[status.Code()]; ok {
+		return name
+	}
+
+	return "Reserved"
+}
+
+type AtaSelfTestEntry struct {
+	Test       AtaSelfTest
+	Status     SelfTestStatus
+	LifeTime   uint16 // power-on hours when the test completed
+	Checkpoint uint8
+	FailingLBA uint64
+
+	lbaValid bool
+}
+
+// FailingLBAValid reports FailingLBA has the first failed LBA.
+func (entry *AtaSelfTestEntry) FailingLBAValid() bool {
+	return entry.lbaValid
+}
+
+// AtaSelfTestLog is the SMART self-test log (06h) or the extended self-test
+// log (07h), the entries are ordered from the newest.
+type AtaSelfTestLog struct {
+	Revision uint16
+	Entries  []AtaSelfTestEntry
+}
+
+func isEmptyDescriptor(desc []byte) bool {
+	for _, b := range desc {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newestFirst walks the circular descriptors back from the 1's based index
+// of the newest descriptor.
+func newestFirst(count int, index int, fn func(i int)) {
+	if index < 1 || index > count {
+		return
+	}
+
+	for n := 0; n < count; n++ {
+		fn((index - 1 - n + count) % count)
+	}
+}
+
+func parseSelfTestEntry(desc []byte, lba uint64, unset uint64) AtaSelfTestEntry {
+	entry := AtaSelfTestEntry{
+		Test:       AtaSelfTest(desc[0]),
+		Status:     SelfTestStatus(desc[1]),
+		LifeTime:   binary.LittleEndian.Uint16(desc[2:4]),
+		Checkpoint: desc[4],
+		FailingLBA: lba,
+	}
+
+	entry.lbaValid = entry.Status.Failed() && lba != unset
+
+	return entry
+}
+
+// ACS-3 A.15 SMART Self-Test log
+func parseSelfTestLog(buf []byte) (*AtaSelfTestLog, error) {
+	if len(buf) < ataLogPageSize || !checksum8(buf[:ataLogPageSize]) {
+		return nil, fmt.Errorf("self-test log: %w", ErrChecksum)
+	}
+
+	log := &AtaSelfTestLog{
+		Revision: binary.LittleEndian.Uint16(buf[0:2]),
+		Entries:  make([]AtaSelfTestEntry, 0, selfTestEntries),
+	}
+
+	newestFirst(selfTestEntries, int(buf[selfTestIndexOff]), func(i int) {
+		pos := selfTestEntryOff + i*selfTestEntrySize
+		desc := buf[pos : pos+selfTestEntrySize]
+
+		if isEmptyDescriptor(desc) {
+			return
+		}
+
+		lba := uint64(binary.LittleEndian.Uint32(desc[5:9]))
+		log.Entries = append(log.Entries, parseSelfTestEntry(desc, lba, selfTestLBA32Mask))
+	})
+
+	return log, nil
+}
+
+// ACS-3 A.9 Extended SMART Self-Test log, the descriptors continue over all
+// pages of buf.
+func parseExtSelfTestLog(buf []byte) (*AtaSelfTestLog, error) {
+	pages := len(buf) / ataLogPageSize
+	if pages == 0 {
+		return nil, fmt.Errorf("extended self-test log: %w", ErrChecksum)
+	}
+
+	for page := 0; page < pages; page++ {
+		if !checksum8(buf[page*ataLogPageSize : (page+1)*ataLogPageSize]) {
+			return nil, fmt.Errorf("extended self-test log: %w", ErrChecksum)
+		}
+	}
+
+	log := &AtaSelfTestLog{
+		Revision: uint16(buf[0]),
+		Entries:  make([]AtaSelfTestEntry, 0, pages*extSelfTestEntries),
+	}
+
+	newestFirst(pages*extSelfTestEntries, int(binary.LittleEndian.Uint16(buf[2:4])), func(i int) {
+		page, n := i/extSelfTestEntries, i%extSelfTestEntries
+		pos := page*ataLogPageSize + extSelfTestEntryOff + n*extSelfTestEntrySize
+		desc := buf[pos : pos+extSelfTestEntrySize]
+
+		if isEmptyDescriptor(desc) {
+			return
+		}
+
+		lba := uint48(desc[5:11])
+		log.Entries = append(log.Entries, parseSelfTestEntry(desc, lba, extSelfTestLBA48Mask))
+	})
+
+	return log, nil
+}
+
+// ReadSelfTestLog reads the SMART self-test log by SMART READ LOG.
+func (sata *SATADevice) ReadSelfTestLog() (*AtaSelfTestLog, error) {
+	buf := make([]byte, ataLogPageSize)
+
+	if err := sata.smartReadLog(ataLogSelfTest, buf); err != nil {
+		return nil, err
+	}
+
+	return parseSelfTestLog(buf)
+}
+
+// ReadExtSelfTestLog reads the pages of the extended self-test log by READ
+// LOG EXT, or all pages in the GPL directory if pages is 0. The log records
+// the 48-bit failing LBA.
+func (sata *SATADevice) ReadExtSelfTestLog(pages int) (*AtaSelfTestLog, error) {
+	buf, err := sata.ReadLog(ataLogExtSelfTest, 0, pages)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseExtSelfTestLog(buf)
+}
+
+func (sata *SATADevice) executeOffline(test AtaSelfTest) error {
+	_, err := sata.execute(makeNonDataCDB(makeSmartCmd(SmartExecOffline, uint8(test), 0)), nil)
+
+	return err
+}
+
+// StartSelfTest starts the short, extended or conveyance self-test in the
+// off-line mode. The capability is checked with the SMART data of the last
+// ReadSMART if available.
+func (sata *SATADevice) StartSelfTest(test AtaSelfTest) error {
+	required := offlineCapExecImmediate | offlineCapSelfTest
+
+	switch test {
+	case AtaSelfTestShort, AtaSelfTestExtended:
+	case AtaSelfTestConveyance:
+		required |= offlineCapConveyance
+	default:
+		return ErrInvalidSelfTest
+	}
+
+	if sata.smart != nil && sata.smart.OfflineCapability&required != required {
+		return ErrSelfTestUnsupported
+	}
+
+	return sata.executeOffline(test)
+}
+
+// AbortSelfTest aborts the running off-line self-test.
+func (sata *SATADevice) AbortSelfTest() error {
+	return sata.executeOffline(AtaSelfTestAbort)
+}
+
+// SelfTestProgress reads the self-test execution status from SMART READ DATA.
+func (sata *SATADevice) SelfTestProgress() (SelfTestStatus, error) {
+	data, err := sata.smartReadPage(SmartReadData)
+	if err != nil {
+		return 0, err
+	}
+
+	if !checksum8(data) {
+		return 0, fmt.Errorf("smart data: %w", ErrChecksum)
+	}
+
+	return SelfTestStatus(data[363]), nil
+}
+
+// WaitSelfTest polls the self-test execution status by the interval, from
+// one interval after the call, until the running test finishes or ctx is
+// done, and returns the last status.
+// progress is called with every polled status if not nil.
+func (sata *SATADevice) WaitSelfTest(ctx context.Context, interval time.Duration, progress func(SelfTestStatus)) (SelfTestStatus, error) {
+	if interval <= 0 {
+		interval = defaultSelfTestPoll
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var status SelfTestStatus
+
+	for {
+		// the first poll waits the interval too, the status read just after
+		// StartSelfTest may still be the result of the previous test
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+
+		var err error
+		if status, err = sata.SelfTestProgress(); err != nil {
+			return status, err
+		}
+
+		if progress != nil {
+			progress(status)
+		}
+
+		if !status.InProgress() {
+			return status, nil
+		}
+	}
+}
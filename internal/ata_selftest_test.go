@@ -0,0 +1,246 @@
+package internal
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleSelfTestLog() []byte {
+	buf := make([]byte, ataLogPageSize)
+	binary.LittleEndian.PutUint16(buf, 0x0001)
+
+	// descriptor 1: short test passed
+	desc := buf[selfTestEntryOff:]
+	desc[0] = uint8(AtaSelfTestShort)
+	binary.LittleEndian.PutUint16(desc[2:], 1000)
+	binary.LittleEndian.PutUint32(desc[5:], uint32(selfTestLBA32Mask))
+
+	// descriptor 2: extended test failed by the read failure
+	desc = buf[selfTestEntryOff+selfTestEntrySize:]
+	desc[0] = uint8(AtaSelfTestExtended)
+	desc[1] = SelfTestRead<<4 | 0x09
+	binary.LittleEndian.PutUint16(desc[2:], 1010)
+	binary.LittleEndian.PutUint32(desc[5:], 0x0012_3456)
+
+	buf[selfTestIndexOff] = 2
+	buf[511] = -sumBytes(buf[:511])
+
+	return buf
+}
+
+func sampleExtSelfTestLog(pages int) []byte {
+	buf := make([]byte, pages*ataLogPageSize)
+	buf[0] = 0x01
+
+	// the newest descriptor is the first one of the second page
+	desc := buf[ataLogPageSize+extSelfTestEntryOff:]
+	desc[0] = uint8(AtaSelfTestShort)
+	desc[1] = SelfTestElectrical << 4
+	binary.LittleEndian.PutUint16(desc[2:], 2000)
+	copy(desc[5:11], []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06})
+
+	// the last descriptor of the first page
+	desc = buf[extSelfTestEntryOff+(extSelfTestEntries-1)*extSelfTestEntrySize:]
+	desc[0] = uint8(AtaSelfTestConveyance)
+	binary.LittleEndian.PutUint16(desc[2:], 1990)
+
+	binary.LittleEndian.PutUint16(buf[2:], extSelfTestEntries+1)
+
+	for page := 0; page < pages; page++ {
+		pos := page * ataLogPageSize
+		buf[pos+511] = -sumBytes(buf[pos : pos+511])
+	}
+
+	return buf
+}
+
+func TestSelfTestStatus(t *testing.T) {
+	a := assert.New(t)
+
+	status := SelfTestStatus(0xf3)
+	a.True(status.InProgress())
+	a.False(status.Failed())
+	a.Equal(30, status.Remaining())
+	a.Equal("Self-test routine in progress", status.String())
+
+	status = SelfTestStatus(0x70)
+	a.False(status.InProgress())
+	a.True(status.Failed())
+	a.Equal("Completed: read failure", status.String())
+
+	a.False(SelfTestStatus(0x10).Failed())
+	a.Equal("Reserved", SelfTestStatus(0x90).String())
+
+	a.Equal("Extended offline", AtaSelfTestExtended.String())
+	a.Equal("Vendor (0x40)", AtaSelfTest(0x40).String())
+}
+
+func TestParseSelfTestLog(t *testing.T) {
+	a := assert.New(t)
+
+	log, err := parseSelfTestLog(sampleSelfTestLog())
+	a.NoError(err)
+	a.Equal(uint16(1), log.Revision)
+	a.Len(log.Entries, 2)
+
+	entry := log.Entries[0]
+	a.Equal(AtaSelfTestExtended, entry.Test)
+	a.True(entry.Status.Failed())
+	a.Equal(90, entry.Status.Remaining())
+	a.Equal(uint16(1010), entry.LifeTime)
+	a.True(entry.FailingLBAValid())
+	a.Equal(uint64(0x123456), entry.FailingLBA)
+
+	entry = log.Entries[1]
+	a.Equal(AtaSelfTestShort, entry.Test)
+	a.False(entry.FailingLBAValid())
+
+	// no self-test recorded
+	empty := make([]byte, ataLogPageSize)
+	log, err = parseSelfTestLog(empty)
+	a.NoError(err)
+	a.Empty(log.Entries)
+
+	corrupted := sampleSelfTestLog()
+	corrupted[100]++
+	_, err = parseSelfTestLog(corrupted)
+	a.True(errors.Is(err, ErrChecksum))
+}
+
+func TestParseExtSelfTestLog(t *testing.T) {
+	a := assert.New(t)
+
+	log, err := parseExtSelfTestLog(sampleExtSelfTestLog(2))
+	a.NoError(err)
+	a.Len(log.Entries, 2)
+
+	entry := log.Entries[0]
+	a.Equal(AtaSelfTestShort, entry.Test)
+	a.Equal(uint16(2000), entry.LifeTime)
+	a.True(entry.FailingLBAValid())
+	a.Equal(uint64(0x060504030201), entry.FailingLBA)
+
+	a.Equal(AtaSelfTestConveyance, log.Entries[1].Test)
+	a.Equal(uint16(1990), log.Entries[1].LifeTime)
+
+	corrupted := sampleExtSelfTestLog(2)
+	corrupted[ataLogPageSize+100]++
+	_, err = parseExtSelfTestLog(corrupted)
+	a.True(errors.Is(err, ErrChecksum))
+
+	_, err = parseExtSelfTestLog(nil)
+	a.Error(err)
+}
+
+func TestSATASelfTestLogs(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeTransport(
+		fakeResponse{data: sampleSelfTestLog()},
+		fakeResponse{data: sampleLogDirectory(map[uint8]uint16{ataLogExtSelfTest: 2})},
+		fakeResponse{data: sampleExtSelfTestLog(2)},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	log, err := sata.ReadSelfTestLog()
+	a.NoError(err)
+	a.Len(log.Entries, 2)
+
+	cdb := transport.lastCDB()
+	a.Equal(uint8(AtaSmart), cdb[14])
+	a.Equal(uint8(SmartReadLog), cdb[4])
+	a.Equal(uint8(1), cdb[6])
+	a.Equal(uint8(ataLogSelfTest), cdb[8])
+
+	log, err = sata.ReadExtSelfTestLog(0)
+	a.NoError(err)
+	a.Len(log.Entries, 2)
+
+	cdb = transport.lastCDB()
+	a.Equal(uint8(AtaReadLogExt), cdb[14])
+	a.Equal(ExtendMASK, cdb[1]&ExtendMASK)
+	a.Equal(uint8(2), cdb[6])
+	a.Equal(uint8(ataLogExtSelfTest), cdb[8])
+}
+
+func TestSATAStartSelfTest(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeTransport(fakeResponse{}, fakeResponse{})
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	a.Equal(ErrInvalidSelfTest, sata.StartSelfTest(AtaSelfTestAbort))
+	a.Empty(transport.cdbs)
+
+	a.NoError(sata.StartSelfTest(AtaSelfTestShort))
+	cdb := transport.lastCDB()
+	a.Equal(uint8(AtaSmart), cdb[14])
+	a.Equal(uint8(SmartExecOffline), cdb[4])
+	a.Equal(uint8(AtaSelfTestShort), cdb[8])
+	a.Equal(uint8(NonData), cdb[1]&ProtocolMASK)
+
+	a.NoError(sata.AbortSelfTest())
+	a.Equal(uint8(AtaSelfTestAbort), transport.lastCDB()[8])
+
+	// conveyance test is not supported
+	sata.smart = &SmartData{OfflineCapability: offlineCapExecImmediate | offlineCapSelfTest}
+	a.Equal(ErrSelfTestUnsupported, sata.StartSelfTest(AtaSelfTestConveyance))
+}
+
+func TestSATAWaitSelfTest(t *testing.T) {
+	a := assert.New(t)
+
+	smartPage := func(status uint8) []byte {
+		data, _ := sampleSmartPages(sampleAttrs)
+		data[363] = status
+		data[511] = 0
+		data[511] = -sumBytes(data[:511])
+
+		return data
+	}
+
+	transport := newFakeTransport(
+		fakeResponse{data: smartPage(0xf9)},
+		fakeResponse{data: smartPage(0xf2)},
+		fakeResponse{data: smartPage(0x00)},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	remaining := make([]int, 0)
+	status, err := sata.WaitSelfTest(context.Background(), time.Millisecond, func(status SelfTestStatus) {
+		remaining = append(remaining, status.Remaining())
+	})
+
+	a.NoError(err)
+	a.Equal([]int{90, 20, 0}, remaining)
+	a.Equal(SelfTestCompleted, status.Code())
+
+	// cancelled while the test is running
+	transport = newFakeTransport(fakeResponse{data: smartPage(0xf9)}, fakeResponse{data: smartPage(0x00)})
+	sata.transport = transport
+
+	ctx, cancel := context.WithCancel(context.Background())
+	status, err = sata.WaitSelfTest(ctx, 50*time.Millisecond, func(SelfTestStatus) { cancel() })
+	a.Equal(context.Canceled, err)
+	a.True(status.InProgress())
+	a.Len(transport.cdbs, 1)
+
+	// nothing is polled before the first interval
+	transport = newFakeTransport()
+	sata.transport = transport
+
+	_, err = sata.WaitSelfTest(ctx, time.Hour, nil)
+	a.Equal(context.Canceled, err)
+	a.Len(transport.cdbs, 0)
+}
@@ -0,0 +1,347 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	AtaSmart = 0xB0
+
+	// SMART feature register values (ATA8-ACS 7.52)
+	SmartReadData       = 0xD0
+	SmartReadThresholds = 0xD1 // obsolete but still implemented by most devices
+	SmartExecOffline    = 0xD4
+	SmartReadLog        = 0xD5
+	SmartWriteLog       = 0xD6
+	SmartReturnStatus   = 0xDA
+
+	// LBA mid(4Fh) and high(C2h) signature of the SMART commands
+	smartSignatureMid  = uint8(0x4f)
+	smartSignatureHigh = uint8(0xc2)
+	smartSignature     = uint64(smartSignatureHigh)<<16 | uint64(smartSignatureMid)<<8
+
+	smartDataSize      = 512
+	smartAttrCount     = 30
+	smartAttrEntrySize = 12
+	smartAttrOffset    = 2
+
+	// attribute flags
+	AttrFlagPreFail       = uint16(0x0001)
+	AttrFlagOnline        = uint16(0x0002)
+	AttrFlagPerformance   = uint16(0x0004)
+	AttrFlagErrorRate     = uint16(0x0008)
+	AttrFlagEventCount    = uint16(0x0010)
+	AttrFlagSelfPreserved = uint16(0x0020)
+
+	thresholdAlwaysFail = uint8(0xfe)
+	thresholdAlwaysPass = uint8(0xff)
+
+	// SMART RETURN STATUS LBA mid/high when the threshold is exceeded
+	smartExceededMid  = uint8(0xf4)
+	smartExceededHigh = uint8(0x2c)
+)
+
+var (
+	ErrSMARTUnsupported = errors.New("device does not support SMART")
+	ErrSMARTDisabled    = errors.New("SMART is disabled")
+)
+
+type HealthStatus int
+
+const (
+	HealthUnknown = HealthStatus(iota)
+	HealthPassed
+	HealthFailed
+)
+
+func (health HealthStatus) String() string {
+	switch health {
+	case HealthPassed:
+		return "PASSED"
+	case HealthFailed:
+		return "FAILED"
+	}
+
+	return "UNKNOWN"
+}
+
+func (health HealthStatus) MarshalText() ([]byte, error) {
+	return []byte(health.String()), nil
+}
+
+type AttributeStatus int
+
+const (
+	AttrOK = AttributeStatus(iota)
+	AttrFailedInPast
+	AttrFailingNow
+)
+
+func (status AttributeStatus) String() string {
+	switch status {
+	case AttrOK:
+		return "OK"
+	case AttrFailedInPast:
+		return "FAILED_IN_PAST"
+	case AttrFailingNow:
+		return "FAILING_NOW"
+	}
+
+	return "UNKNOWN"
+}
+
+func (status AttributeStatus) MarshalText() ([]byte, error) {
+	return []byte(status.String()), nil
+}
+
+type SmartAttribute struct {
+	ID        uint8
+	Name      string
+	Flags     uint16
+	Current   uint8
+	Worst     uint8
+	Threshold uint8
+	Raw       [6]byte
+	Reserved  uint8 // some raw formats use the reserved byte as the 7th raw byte
+	Format    RawFormat
+	Status    AttributeStatus
+}
+
+// PreFail reports the attribute predicts the imminent failure, otherwise it
+// is an old-age (usage) attribute.
+func (attr *SmartAttribute) PreFail() bool {
+	return attr.Flags&AttrFlagPreFail == AttrFlagPreFail
+}
+
+func (attr *SmartAttribute) Online() bool {
+	return attr.Flags&AttrFlagOnline == AttrFlagOnline
+}
+
+func (attr *SmartAttribute) RawValue() uint64 {
+	return uint48(attr.Raw[:])
+}
+
+func (attr *SmartAttribute) evaluate() AttributeStatus {
+	switch attr.Threshold {
+	case 0, thresholdAlwaysPass:
+		return AttrOK
+	case thresholdAlwaysFail:
+		return AttrFailingNow
+	}
+
+	// 0x00, 0xfe and 0xff normalized values are not valid
+	valid := func(value uint8) bool { return value > 0 && value < thresholdAlwaysFail }
+
+	switch {
+	case valid(attr.Current) && attr.Current <= attr.Threshold:
+		return AttrFailingNow
+	case valid(attr.Worst) && attr.Worst <= attr.Threshold:
+		return AttrFailedInPast
+	}
+
+	return AttrOK
+}
+
+// SmartData is the SMART READ DATA page merged with SMART READ THRESHOLDS.
+type SmartData struct {
+	Family             string // drive family in the drive database
+	Warning            string // known issue of the drive model or firmware
+	Revision           uint16
+	Attributes         []SmartAttribute
+	OfflineStatus      uint8  // 362 off-line data collection status
+	SelfTestStatus     uint8  // 363 self-test execution status
+	OfflineTime        uint16 // 364..365 seconds to complete off-line data collection
+	OfflineCapability  uint8  // 367 off-line data collection capability
+	SmartCapability    uint16 // 368..369 SMART capability
+	ErrorLogCapability uint8  // 370 error logging capability
+	ShortTestTime      uint8  // 372 short self-test polling time in minutes
+	ExtendedTestTime   uint16 // 373 or 375..376 extended self-test polling time in minutes
+	ConveyanceTestTime uint8  // 374 conveyance self-test polling time in minutes
+}
+
+// Attribute finds the attribute by id, or nil if the device does not report.
+func (smart *SmartData) Attribute(id uint8) *SmartAttribute {
+	for i := range smart.Attributes {
+		if smart.Attributes[i].ID == id {
+			return &smart.Attributes[i]
+		}
+	}
+
+	return nil
+}
+
+// Failing lists the attributes failing now or failed in the past.
+func (smart *SmartData) Failing() []SmartAttribute {
+	failing := make([]SmartAttribute, 0)
+
+	for _, attr := range smart.Attributes {
+		if attr.Status != AttrOK {
+			failing = append(failing, attr)
+		}
+	}
+
+	return failing
+}
+
+func parseSmartData(data, thresholds []byte) (*SmartData, error) {
+	if len(data) < smartDataSize || !checksum8(data[:smartDataSize]) {
+		return nil, fmt.Errorf("smart data: %w", ErrChecksum)
+	}
+
+	if thresholds != nil && (len(thresholds) < smartDataSize || !checksum8(thresholds[:smartDataSize])) {
+		return nil, fmt.Errorf("smart thresholds: %w", ErrChecksum)
+	}
+
+	smart := &SmartData{
+		Revision:           binary.LittleEndian.Uint16(data[0:2]),
+		Attributes:         make([]SmartAttribute, 0, smartAttrCount),
+		OfflineStatus:      data[362],
+		SelfTestStatus:     data[363],
+		OfflineTime:        binary.LittleEndian.Uint16(data[364:366]),
+		OfflineCapability:  data[367],
+		SmartCapability:    binary.LittleEndian.Uint16(data[368:370]),
+		ErrorLogCapability: data[370],
+		ShortTestTime:      data[372],
+		ExtendedTestTime:   uint16(data[373]),
+		ConveyanceTestTime: data[374],
+	}
+
+	if smart.ExtendedTestTime == 0xff {
+		smart.ExtendedTestTime = binary.LittleEndian.Uint16(data[375:377])
+	}
+
+	for i := 0; i < smartAttrCount; i++ {
+		pos := smartAttrOffset + i*smartAttrEntrySize
+		entry := data[pos : pos+smartAttrEntrySize]
+
+		if entry[0] == 0 {
+			continue
+		}
+
+		attr := SmartAttribute{
+			ID:      entry[0],
+			Flags:   binary.LittleEndian.Uint16(entry[1:3]),
+			Current: entry[3],
+			Worst:   entry[4],
+		}
+		copy(attr.Raw[:], entry[5:11])
+		attr.Reserved = entry[11]
+
+		// threshold entries usually have the same order with the attributes,
+		// but the order is not guaranteed by the standard.
+		if thresholds != nil {
+			attr.Threshold = findThreshold(thresholds, attr.ID, i)
+		}
+
+		attr.Status = attr.evaluate()
+
+		smart.Attributes = append(smart.Attributes, attr)
+	}
+
+	return smart, nil
+}
+
+func findThreshold(thresholds []byte, id uint8, hint int) uint8 {
+	pos := smartAttrOffset + hint*smartAttrEntrySize
+	if thresholds[pos] == id {
+		return thresholds[pos+1]
+	}
+
+	for i := 0; i < smartAttrCount; i++ {
+		pos = smartAttrOffset + i*smartAttrEntrySize
+		if thresholds[pos] == id {
+			return thresholds[pos+1]
+		}
+	}
+
+	return 0
+}
+
+func makeSmartCmd(feature uint8, lbaLow uint8, count uint16) ata48BitCmd {
+	cmd := ata48BitCmd{command: AtaSmart}
+
+	cmd.setFeature(uint16(feature))
+	cmd.setCount(count)
+	cmd.setLBA(smartSignature | uint64(lbaLow))
+
+	return cmd
+}
+
+func (sata *SATADevice) smartReadPage(feature uint8) ([]byte, error) {
+	buf := make([]byte, smartDataSize)
+
+	if _, err := sata.execute(makePIODataInCDB(makeSmartCmd(feature, 0, 1)), buf); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// ReadSMART issues SMART READ DATA and SMART READ THRESHOLDS and evaluates
+// each attribute. Devices rejecting READ THRESHOLDS are evaluated without
+// the thresholds.
+func (sata *SATADevice) ReadSMART() (*SmartData, error) {
+	if caps := sata.Capabilities(); caps != nil {
+		if !caps.Supports(FeatureSMART) {
+			return nil, ErrSMARTUnsupported
+		} else if !caps.Enabled(FeatureSMART) {
+			return nil, ErrSMARTDisabled
+		}
+	}
+
+	data, err := sata.smartReadPage(SmartReadData)
+	if err != nil {
+		return nil, err
+	}
+
+	thresholds, err := sata.smartReadPage(SmartReadThresholds)
+	if ataErr, ok := err.(*AtaError); ok && ataErr.Aborted() {
+		thresholds = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	smart, err := parseSmartData(data, thresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	sata.driveDB().apply(sata.model, sata.firmware, smart)
+
+	sata.smart = smart
+
+	return smart, nil
+}
+
+// SMART returns the SMART data of the last ScanSMART or ReadSMART.
+func (sata *SATADevice) SMART() *SmartData {
+	return sata.smart
+}
+
+// HealthStatus issues SMART RETURN STATUS and reads back LBA mid/high through
+// CK_COND. The status is unknown when the device or the bridge does not
+// return the ATA registers.
+func (sata *SATADevice) HealthStatus() (HealthStatus, error) {
+	cdb := makeNonDataCDB(makeSmartCmd(SmartReturnStatus, 0, 0))
+	cdb.setCheckCond()
+
+	resp, err := sata.execute(cdb, nil)
+	if err != nil {
+		return HealthUnknown, err
+	}
+
+	if resp.regs == nil {
+		return HealthUnknown, nil
+	}
+
+	switch mid, high := resp.regs.lbaMid(), resp.regs.lbaHigh(); {
+	case mid == smartSignatureMid && high == smartSignatureHigh:
+		return HealthPassed, nil
+	case mid == smartExceededMid && high == smartExceededHigh:
+		return HealthFailed, nil
+	}
+
+	return HealthUnknown, nil
+}
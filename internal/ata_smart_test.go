@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type smartAttrSample struct {
+	id        uint8
+	flags     uint16
+	current   uint8
+	worst     uint8
+	raw       uint64
+	threshold uint8
+}
+
+var sampleAttrs = []smartAttrSample{
+	{1, 0x000f, 117, 99, 0x0000_0700_1234, 6},
+	{5, 0x0033, 100, 100, 0, 10},
+	{9, 0x0032, 90, 90, 8760, 0},
+	{10, 0x0013, 20, 20, 12, 97},
+	{190, 0x0022, 62, 40, 0x2814_0026, 45},
+	{194, 0x0022, 38, 60, 0x0014_0026, 0},
+}
+
+func sampleSmartPages(attrs []smartAttrSample) (data, thresholds []byte) {
+	data = make([]byte, smartDataSize)
+	thresholds = make([]byte, smartDataSize)
+
+	binary.LittleEndian.PutUint16(data, 0x0010)
+	binary.LittleEndian.PutUint16(thresholds, 0x0010)
+
+	for i, attr := range attrs {
+		entry := data[smartAttrOffset+i*smartAttrEntrySize:]
+		entry[0] = attr.id
+		binary.LittleEndian.PutUint16(entry[1:], attr.flags)
+		entry[3] = attr.current
+		entry[4] = attr.worst
+
+		raw := make([]byte, 8)
+		binary.LittleEndian.PutUint64(raw, attr.raw)
+		copy(entry[5:11], raw)
+
+		// thresholds in the reversed order
+		entry = thresholds[smartAttrOffset+(len(attrs)-1-i)*smartAttrEntrySize:]
+		entry[0] = attr.id
+		entry[1] = attr.threshold
+	}
+
+	data[363] = 0x00
+	data[372] = 2
+	data[373] = 0xff
+	binary.LittleEndian.PutUint16(data[375:], 300)
+	data[374] = 5
+
+	data[511] = -sumBytes(data[:511])
+	thresholds[511] = -sumBytes(thresholds[:511])
+
+	return data, thresholds
+}
+
+func sumBytes(buf []byte) uint8 {
+	sum := uint8(0)
+	for _, b := range buf {
+		sum += b
+	}
+
+	return sum
+}
+
+func TestParseSmartData(t *testing.T) {
+	a := assert.New(t)
+
+	data, thresholds := sampleSmartPages(sampleAttrs)
+
+	smart, err := parseSmartData(data, thresholds)
+	a.NoError(err)
+	a.Len(smart.Attributes, len(sampleAttrs))
+	a.Equal(uint16(0x0010), smart.Revision)
+	a.Equal(uint8(2), smart.ShortTestTime)
+	a.Equal(uint16(300), smart.ExtendedTestTime)
+	a.Equal(uint8(5), smart.ConveyanceTestTime)
+
+	readErr := smart.Attribute(1)
+	a.True(readErr.PreFail())
+	a.True(readErr.Online())
+	a.Equal(uint8(6), readErr.Threshold)
+	a.Equal(uint64(0x0000_0700_1234), readErr.RawValue())
+	a.Equal(AttrOK, readErr.Status)
+
+	a.False(smart.Attribute(9).PreFail())
+	a.Equal(AttrFailingNow, smart.Attribute(10).Status)
+	a.Equal(AttrFailedInPast, smart.Attribute(190).Status)
+	a.Equal(AttrOK, smart.Attribute(194).Status)
+	a.Nil(smart.Attribute(231))
+
+	failing := smart.Failing()
+	a.Len(failing, 2)
+	a.Equal(uint8(10), failing[0].ID)
+	a.Equal("FAILING_NOW", failing[0].Status.String())
+
+	// without thresholds
+	smart, err = parseSmartData(data, nil)
+	a.NoError(err)
+	a.Len(smart.Failing(), 0)
+
+	// checksum
+	data[100]++
+	_, err = parseSmartData(data, thresholds)
+	a.True(errors.Is(err, ErrChecksum))
+}
+
+func TestSATAReadSMART(t *testing.T) {
+	a := assert.New(t)
+
+	data, thresholds := sampleSmartPages(sampleAttrs)
+
+	transport := newFakeTransport(
+		fakeResponse{data: sampleIdentify().setWord(82, 0x0001).setWord(83, 0x4000).setWord(85, 0x0001).setWord(87, 0x4000)},
+		fakeResponse{data: data},
+		fakeResponse{data: thresholds},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	a.NoError(sata.ScanSMART())
+	a.NotNil(sata.SMART())
+	a.Len(sata.SMART().Attributes, len(sampleAttrs))
+
+	cdb := transport.cdbs[1]
+	a.Equal(uint8(AtaSmart), cdb[14])
+	a.Equal(uint8(SmartReadData), cdb[4])
+	a.Equal(uint8(0x4f), cdb[10])
+	a.Equal(uint8(0xc2), cdb[12])
+	a.Equal(uint8(SmartReadThresholds), transport.cdbs[2][4])
+
+	// SMART disabled
+	sata = newSATADev("/dev/sda")
+	sata.transport = newFakeTransport(
+		fakeResponse{data: sampleIdentify().setWord(82, 0x0001).setWord(83, 0x4000)},
+	)
+	a.Equal(ErrSMARTDisabled, sata.ScanSMART())
+}
+
+func TestSATAHealthStatus(t *testing.T) {
+	a := assert.New(t)
+
+	passed := ataRegisters{status: ataStatusDRDY, lba: smartSignature}
+	failed := ataRegisters{status: ataStatusDRDY, lba: uint64(smartExceededHigh)<<16 | uint64(smartExceededMid)<<8}
+
+	transport := newFakeTransport(
+		fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: ataRegistersSense(passed)}},
+		fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: ataRegistersSense(failed)}},
+		fakeResponse{resp: ScsiResponse{Status: ScsiGood}},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	health, err := sata.HealthStatus()
+	a.NoError(err)
+	a.Equal(HealthPassed, health)
+
+	cdb := ataCDB{}
+	copy(cdb[:], transport.lastCDB())
+	a.True(cdb.isCheckCond())
+	a.Equal(NonData, cdb.getProtocol())
+	a.Equal(uint8(SmartReturnStatus), cdb[4])
+
+	health, err = sata.HealthStatus()
+	a.NoError(err)
+	a.Equal(HealthFailed, health)
+	a.Equal("FAILED", health.String())
+
+	// no sense data returned by the bridge
+	health, err = sata.HealthStatus()
+	a.NoError(err)
+	a.Equal(HealthUnknown, health)
+}
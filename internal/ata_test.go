@@ -9,7 +9,7 @@ func TestScanSATA(t *testing.T) {
 	/*
 		a := assert.New(t)
 
-		storage, err := ScanSATA(make(map[string]StorageDevice))
+		storage, err := ScanSATA(make([]StorageDevice, 0))
 
 		a.NoError(err)
 
@@ -56,6 +56,14 @@ func TestAtaCDB(t *testing.T) {
 	a.False(cdb.isDevDir())
 	cdb.hostToDevDir()
 	a.True(cdb.isDevDir())
+
+	// CK_COND
+	a.False(cdb.isCheckCond())
+	cdb.setCheckCond()
+	a.True(cdb.isCheckCond())
+	a.Equal(CkCondMASK, cdb[2])
+	cdb.unsetCheckCond()
+	a.False(cdb.isCheckCond())
 }
 
 func TestAtaCDBReset(t *testing.T) {
@@ -69,3 +77,73 @@ func TestAtaCDBReset(t *testing.T) {
 	a.Equal(ataProtocol(SRST), softReset.getProtocol())
 	a.Equal(uint8(1), softReset[2]>>OfflinePos)
 }
+
+func TestAta48BitCmd(t *testing.T) {
+	a := assert.New(t)
+
+	cmd := ata48BitCmd{device: 0x40, command: 0x2f}
+	cmd.setFeature(0x1234)
+	cmd.setCount(0x0001)
+	cmd.setLBA(0x0000_abcd_ef01_2345)
+
+	cdb := makePIODataInCDB(cmd)
+
+	a.Equal([]byte{
+		scsiAtaPassThrough16, uint8(PIODataIn), TDirMASK | BytBlokMASK | TLenCount,
+		0x12, 0x34, 0x00, 0x01, 0xef, 0x45, 0xcd, 0x23, 0xab, 0x01, 0x40, 0x2f, 0x00,
+	}, cdb[:])
+
+	a.True(cdb.isHostDir())
+	a.True(cdb.isBlockCmd())
+	a.Equal(TLenCount, cdb.getTLen())
+
+	cdb.setByteSize(TLenFeature)
+	a.False(cdb.isBlockCmd())
+	a.Equal(TLenFeature, cdb.getTLen())
+}
+
+func TestSATAExecute(t *testing.T) {
+	a := assert.New(t)
+
+	data := make([]byte, 512)
+	data[0] = 0x5a
+
+	transport := newFakeTransport(
+		fakeResponse{data: data, resp: ScsiResponse{Status: ScsiGood}},
+		fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: ataReturnSense(0x51, ataErrorABRT)}},
+		fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: []byte{0x70, 0x00, 0x05}}},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	// data-in command
+	buf := make([]byte, 512)
+	resp, err := sata.execute(makePIODataInCDB(ata48BitCmd{command: AtaIdentifyDev}), buf)
+	a.NoError(err)
+	a.Equal(ScsiGood, resp.scsiStatus)
+	a.Equal(uint8(0x5a), resp.data[0])
+	a.Equal(DataFromDev, transport.dirs[0])
+	a.Equal(uint8(AtaIdentifyDev), transport.lastCDB()[14])
+
+	// aborted command
+	resp, err = sata.execute(makeNonDataCDB(ata48BitCmd{command: 0xe5}), nil)
+	a.Error(err)
+	a.Equal(DataNone, transport.dirs[1])
+	a.Equal(uint8(0x51), resp.status)
+	a.Equal(ataErrorABRT, resp.error)
+
+	ataErr, ok := err.(*AtaError)
+	a.True(ok)
+	a.True(ataErr.Aborted())
+	a.Equal(uint8(0xe5), ataErr.Command)
+
+	// pass-through rejected by the SCSI layer
+	_, err = sata.execute(makeNonDataCDB(ata48BitCmd{command: 0xe5}), nil)
+	a.Error(err)
+	_, ok = err.(*AtaError)
+	a.False(ok)
+
+	a.NoError(sata.Close())
+	a.True(transport.closed)
+}
@@ -1,5 +1,15 @@
 package internal
 
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"math/bits"
+	"strings"
+)
+
+var ErrChecksum = errors.New("checksum mismatch")
+
 // 7.12.6 Input from the Device to the Host Data Structure
 // 7.12.6.1 Overview
 type word [2]byte    // uint16
@@ -7,6 +17,97 @@ type dword [4]byte   // uint32
 type qword [8]byte   // uint64
 type dqword [16]byte // uint128
 
+func (w word) uint16() uint16 {
+	return binary.LittleEndian.Uint16(w[:])
+}
+
+func (w word) bit(pos uint) bool {
+	return w.uint16()&(0x01<<pos) != 0
+}
+
+func (d dword) uint32() uint32 {
+	return binary.LittleEndian.Uint32(d[:])
+}
+
+func (q qword) uint64() uint64 {
+	return binary.LittleEndian.Uint64(q[:])
+}
+
+func (d dqword) uint128() Uint128 {
+	return Uint128{Lo: binary.LittleEndian.Uint64(d[0:8]), Hi: binary.LittleEndian.Uint64(d[8:16])}
+}
+
+// Uint128 is the 128-bit counter of the NVMe data structures.
+type Uint128 struct {
+	Lo uint64
+	Hi uint64
+}
+
+// mul64 multiplies by the 64-bit value, and the overflow is discarded.
+func (u Uint128) mul64(v uint64) Uint128 {
+	hi, lo := bits.Mul64(u.Lo, v)
+
+	return Uint128{Lo: lo, Hi: u.Hi*v + hi}
+}
+
+func (u Uint128) Big() *big.Int {
+	value := new(big.Int).SetUint64(u.Hi)
+
+	return value.Lsh(value, 64).Or(value, new(big.Int).SetUint64(u.Lo))
+}
+
+// Uint64 returns the value saturated to the maximum of uint64.
+func (u Uint128) Uint64() uint64 {
+	if u.Hi != 0 {
+		return ^uint64(0)
+	}
+
+	return u.Lo
+}
+
+func (u Uint128) String() string {
+	return u.Big().String()
+}
+
+func (u Uint128) MarshalJSON() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// uint48 decodes the little-endian 48-bit value used by the SMART raw value
+// and the ATA log entries.
+func uint48(raw []byte) uint64 {
+	var value uint64
+
+	for i := 5; i >= 0; i-- {
+		value = value<<8 | uint64(raw[i])
+	}
+
+	return value
+}
+
+// checksum8 verifies the 2's complement checksum of the ATA data structure,
+// the sum of all bytes including the checksum byte is zero.
+func checksum8(buf []byte) bool {
+	sum := uint8(0)
+	for _, b := range buf {
+		sum += b
+	}
+
+	return sum == 0
+}
+
+// ataString decodes the ATA string which swaps each byte pair of a word
+// (7.12.6.1), and trims the space padding.
+func ataString(raw []byte) string {
+	swapped := make([]byte, len(raw))
+
+	for i := 0; i+1 < len(raw); i += 2 {
+		swapped[i], swapped[i+1] = raw[i+1], raw[i]
+	}
+
+	return strings.TrimSpace(strings.TrimRight(string(swapped), "\x00"))
+}
+
 type DevIdentify struct {
 	// part 1 of 19 (p140)
 	generic  word     // 0        General configuration (see 7.12.6.2)
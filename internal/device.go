@@ -2,10 +2,13 @@ package internal
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"path"
+	"path/filepath"
 	"regexp"
 	"runtime"
+	"strings"
 )
 
 type DeviceType string
@@ -13,15 +16,13 @@ type DeviceType string
 const (
 	NVMe = DeviceType("nvme")
 	SATA = DeviceType("sata")
+	SCSI = DeviceType("scsi")
+	USB  = DeviceType("usb")
 
 	deviceRoot = "/dev"
 )
 
-var (
-	linuxNvmeMatch, _  = regexp.Compile("^nvme([0-9]*$)")
-	linuxSataMatch, _  = regexp.Compile("^sd([a-z]$)")
-	darwinSataMatch, _ = regexp.Compile("^disk([0-9]*$)")
-)
+var darwinSataMatch, _ = regexp.Compile("^disk([0-9]*$)")
 
 type StorageDevice interface {
 	Type() DeviceType
@@ -29,10 +30,25 @@ type StorageDevice interface {
 	Model() string
 	Firmware() string
 	Serial() string
+	ID() string
+	Capacity() uint64
 
+	HealthStatus() (HealthStatus, error)
+	Features() []Capability
+	PowerMode() (PowerMode, error)
 	ScanSMART() error
+	Close() error
+}
+
+// Capability is the protocol independent state of the device feature.
+type Capability struct {
+	Name      string
+	Supported bool
+	Enabled   bool
 }
 
+var ErrUnsupportedType = errors.New("unsupported device type")
+
 type StorageMeta struct {
 	StorageDevice
 
@@ -51,34 +67,138 @@ func (meta *StorageMeta) Device() string {
 	return meta.devPath
 }
 
-func GetDevFiles(devType DeviceType) ([]string, error) {
-	stats, err := ioutil.ReadDir(deviceRoot)
+func (meta *StorageMeta) Model() string {
+	return meta.model
+}
+
+func (meta *StorageMeta) Firmware() string {
+	return meta.firmware
+}
+
+func (meta *StorageMeta) Serial() string {
+	return meta.serial
+}
+
+// deviceID names the device by the model and the serial in the same way as
+// /dev/disk/by-id, or empty if the device is not identified yet.
+func deviceID(prefix string, model string, serial string) string {
+	if serial == "" {
+		return ""
+	}
+
+	return prefix + "-" + strings.ReplaceAll(model+"_"+serial, " ", "_")
+}
+
+// ScanStorage lists the devices to scan and the disks of the type not
+// supported yet. The devices are found in the sysfs on linux, and only the
+// ATA devices are found by the device file names on darwin.
+func ScanStorage() ([]StorageDevice, map[string]error, error) {
+	if runtime.GOOS != "darwin" {
+		return DefaultDiscovery.Storage()
+	}
+
+	storage, err := ScanSATA(make([]StorageDevice, 0))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	return storage, make(map[string]error), nil
+}
+
+// DetectType looks up the device type of the device file in the sysfs, the
+// path may be the symbolic link like /dev/disk/by-id. The type is guessed by
+// the name if the device is not found in the sysfs.
+func DetectType(devPath string) DeviceType {
+	if resolved, err := filepath.EvalSymlinks(devPath); err == nil {
+		devPath = resolved
+	}
+
+	if candidate, err := DefaultDiscovery.Lookup(devPath); err == nil {
+		return candidate.Type
+	}
+
+	if name := filepath.Base(devPath); nvmeCtrlMatch.MatchString(name) || nvmeNamespaceMatch.MatchString(name) {
+		return NVMe
 	}
 
-	regex := linuxSataMatch
+	return SATA
+}
+
+// OpenDevice opens and identifies the device. The device in the power mode
+// skipped by the policy is not identified and SkippedError is returned.
+// The USB device is opened as SATA through the SAT layer of the bridge.
+func OpenDevice(devPath string, devType DeviceType, policy PowerPolicy) (StorageDevice, error) {
+	var device StorageDevice
+	var identify func() error
+
+	switch devType {
+	case SATA, USB:
+		sata := newSATADev(devPath)
+		device, identify = sata, func() error {
+			_, err := sata.Identify()
+			return err
+		}
+
+	case NVMe:
+		nvme := newNVMeDev(devPath)
+		device, identify = nvme, func() error {
+			_, err := nvme.IdentifyController()
+			return err
+		}
+
+	case SCSI:
+		scsi := newSCSIDev(devPath)
+		device, identify = scsi, func() error {
+			if _, err := scsi.Inquiry(); err != nil {
+				return err
+			}
 
-	if runtime.GOOS == "darwin" {
-		if devType != SATA {
-			return nil, errors.New("unsupported device type on darwin")
+			_, err := scsi.ReadCapacity()
+			return err
 		}
 
-		regex = darwinSataMatch
+	default:
+		return nil, fmt.Errorf("%s: %w", devType, ErrUnsupportedType)
+	}
 
-	} else if runtime.GOOS == "linux" {
-		if devType == NVMe {
-			regex = linuxNvmeMatch
-		} else {
-			regex = linuxSataMatch
+	if policy != PowerPolicyNever {
+		if mode, err := device.PowerMode(); err != nil {
+			_ = device.Close()
+			return nil, err
+		} else if policy.Skip(mode) {
+			_ = device.Close()
+			return nil, &SkippedError{Device: devPath, Mode: mode, Policy: policy}
 		}
+	}
+
+	if err := identify(); err != nil {
+		_ = device.Close()
+		return nil, err
+	}
+
+	return device, nil
+}
+
+// GetDevFiles lists the device files of the type. The devices are found in
+// the sysfs on linux, and by the device file names on darwin.
+func GetDevFiles(devType DeviceType) ([]string, error) {
+	if runtime.GOOS != "darwin" {
+		return DefaultDiscovery.DevFiles(devType)
+	}
+
+	if devType != SATA {
+		return nil, errors.New("unsupported device type on darwin")
+	}
 
+	stats, err := ioutil.ReadDir(deviceRoot)
+	if err != nil {
+		return nil, err
 	}
 
 	files := make([]string, 0)
 
 	for _, stat := range stats {
-		if stat.IsDir() || !regex.MatchString(stat.Name()) {
+		if stat.IsDir() || !darwinSataMatch.MatchString(stat.Name()) {
 			continue
 		}
 
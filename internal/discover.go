@@ -0,0 +1,362 @@
+package internal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiskBus is the transport of the disk found in the sysfs.
+type DiskBus string
+
+const (
+	BusATA     = DiskBus("ata")  // libata, or the ATA disk behind the SAS HBA
+	BusSAS     = DiskBus("sas")  // SAS end device
+	BusSCSI    = DiskBus("scsi") // parallel SCSI, FC and the other SCSI hosts
+	BusUSB     = DiskBus("usb")
+	BusNVMe    = DiskBus("nvme")
+	BusVirtual = DiskBus("virtual") // loop, dm, virtio, iSCSI and the other virtual disks
+
+	defaultSysRoot = "/sys"
+
+	// SCSI peripheral device type of the direct access block device
+	scsiTypeDisk = "0"
+
+	// vendor identification of the SAT layer for the ATA devices
+	satVendor = "ATA"
+
+	sectorSize = 512
+)
+
+var (
+	nvmeCtrlMatch      = regexp.MustCompile(`^nvme([0-9]+)$`)
+	nvmeNamespaceMatch = regexp.MustCompile(`^nvme([0-9]+)n([0-9]+)$`)
+	nvmePathMatch      = regexp.MustCompile(`^nvme[0-9]+c[0-9]+n[0-9]+$`)
+
+	libataMatch  = regexp.MustCompile(`/ata[0-9]+/`)
+	sasMatch     = regexp.MustCompile(`/(end_device|expander|port)-[0-9:]+/`)
+	usbMatch     = regexp.MustCompile(`/usb[0-9]+/`)
+	virtualMatch = regexp.MustCompile(`/(virtual|virtio[0-9]+|session[0-9]+|tcm_loop_[^/]+|pseudo_[0-9]+|vmbus_[^/]+)/`)
+)
+
+// Candidate is the disk or the NVMe controller found in the sysfs.
+type Candidate struct {
+	Name       string     // kernel name, sda or nvme0n1 or nvme0
+	DevPath    string     // device file to open
+	SysPath    string     // resolved sysfs device path
+	Type       DeviceType // device type to open, empty if not supported
+	Bus        DiskBus
+	Vendor     string
+	Model      string
+	Serial     string
+	Firmware   string
+	WWID       string
+	Transport  string // NVMe transport, pcie or the fabrics
+	Controller string // NVMe controller of the namespace
+	Namespace  bool   // NVMe namespace block device
+	Removable  bool
+	Rotational bool
+	Size       uint64 // in bytes
+}
+
+// Discovery finds the disks from /sys/block and the NVMe controllers from
+// /sys/class/nvme. The roots are replaced to read the fixture tree in the
+// tests.
+type Discovery struct {
+	SysRoot string
+	DevRoot string
+}
+
+var DefaultDiscovery = &Discovery{SysRoot: defaultSysRoot, DevRoot: deviceRoot}
+
+func readAttr(path ...string) string {
+	raw, err := ioutil.ReadFile(filepath.Join(path...))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(raw))
+}
+
+func readBoolAttr(path ...string) bool {
+	return readAttr(path...) == "1"
+}
+
+// classifyBus decides the bus by the resolved sysfs path of the block
+// device. The ATA device behind the SAS HBA is reported by the SAT vendor.
+func classifyBus(sysPath string, vendor string) DiskBus {
+	sysPath = filepath.ToSlash(sysPath) + "/"
+
+	switch {
+	case virtualMatch.MatchString(sysPath):
+		return BusVirtual
+	case usbMatch.MatchString(sysPath):
+		return BusUSB
+	case libataMatch.MatchString(sysPath):
+		return BusATA
+	case vendor == satVendor:
+		return BusATA
+	case sasMatch.MatchString(sysPath):
+		return BusSAS
+	}
+
+	return BusSCSI
+}
+
+func busDeviceType(bus DiskBus) DeviceType {
+	switch bus {
+	case BusATA:
+		return SATA
+	case BusSAS, BusSCSI:
+		return SCSI
+	case BusUSB:
+		return USB
+	case BusNVMe:
+		return NVMe
+	}
+
+	return ""
+}
+
+func (d *Discovery) readDir(path ...string) ([]string, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(path...))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+
+	return names, nil
+}
+
+func (d *Discovery) blockCandidate(name string) (*Candidate, bool) {
+	block := filepath.Join(d.SysRoot, "block", name)
+
+	sysPath, err := filepath.EvalSymlinks(block)
+	if err != nil {
+		return nil, false
+	}
+
+	candidate := &Candidate{
+		Name:       name,
+		DevPath:    filepath.Join(d.DevRoot, name),
+		SysPath:    sysPath,
+		Removable:  readBoolAttr(block, "removable"),
+		Rotational: readBoolAttr(block, "queue", "rotational"),
+	}
+
+	if sectors, err := strconv.ParseUint(readAttr(block, "size"), 10, 64); err == nil {
+		candidate.Size = sectors * sectorSize
+	}
+
+	if nvmeNamespaceMatch.MatchString(name) {
+		candidate.Bus = BusNVMe
+		candidate.Namespace = true
+		candidate.Controller = d.namespaceController(block, sysPath)
+		candidate.WWID = readAttr(block, "wwid")
+	} else if _, err := os.Stat(filepath.Join(block, "device")); err != nil {
+		// loop, dm, md, zram and the other block devices without the device
+		candidate.Bus = BusVirtual
+	} else {
+		if devType := readAttr(block, "device", "type"); devType != "" && devType != scsiTypeDisk {
+			// CD-ROM, tape and the other non disk SCSI devices
+			return nil, false
+		}
+
+		candidate.Vendor = readAttr(block, "device", "vendor")
+		candidate.Model = readAttr(block, "device", "model")
+		candidate.Firmware = readAttr(block, "device", "rev")
+		candidate.WWID = readAttr(block, "device", "wwid")
+		candidate.Bus = classifyBus(sysPath, candidate.Vendor)
+	}
+
+	candidate.Type = busDeviceType(candidate.Bus)
+
+	return candidate, true
+}
+
+// namespaceController finds the controller of the namespace from the sysfs
+// instead of the name, nvmeXnY of the native multipath head has the instance
+// of the subsystem in X. The namespace is under the controller, and the
+// multipath head is under the subsystem linking its controllers.
+func (d *Discovery) namespaceController(block string, sysPath string) string {
+	parent := filepath.Dir(sysPath)
+	if name := filepath.Base(parent); nvmeCtrlMatch.MatchString(name) {
+		return name
+	}
+
+	if subsys, err := filepath.EvalSymlinks(filepath.Join(block, "device")); err == nil {
+		parent = subsys
+	}
+
+	names, _ := d.readDir(parent)
+	for _, name := range names {
+		if nvmeCtrlMatch.MatchString(name) {
+			return name
+		}
+	}
+
+	return ""
+}
+
+func (d *Discovery) nvmeCandidate(name string) (*Candidate, bool) {
+	class := filepath.Join(d.SysRoot, "class", "nvme", name)
+
+	sysPath, err := filepath.EvalSymlinks(class)
+	if err != nil {
+		return nil, false
+	}
+
+	candidate := &Candidate{
+		Name:      name,
+		DevPath:   filepath.Join(d.DevRoot, name),
+		SysPath:   sysPath,
+		Type:      NVMe,
+		Bus:       BusNVMe,
+		Model:     readAttr(class, "model"),
+		Serial:    readAttr(class, "serial"),
+		Firmware:  readAttr(class, "firmware_rev"),
+		Transport: readAttr(class, "transport"),
+	}
+
+	if candidate.Transport == "loop" {
+		candidate.Bus = BusVirtual
+		candidate.Type = ""
+	}
+
+	return candidate, true
+}
+
+// Discover lists the block devices and the NVMe controllers sorted by the
+// name. The multipath NVMe controller paths hidden by the kernel are not
+// listed.
+func (d *Discovery) Discover() ([]*Candidate, error) {
+	blocks, err := d.readDir(d.SysRoot, "block")
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*Candidate, 0, len(blocks))
+
+	for _, name := range blocks {
+		if nvmePathMatch.MatchString(name) {
+			continue
+		}
+
+		if candidate, ok := d.blockCandidate(name); ok {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	// the system without NVMe does not have the nvme class
+	ctrls, _ := d.readDir(d.SysRoot, "class", "nvme")
+
+	for _, name := range ctrls {
+		if !nvmeCtrlMatch.MatchString(name) {
+			continue
+		}
+
+		if candidate, ok := d.nvmeCandidate(name); ok {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if !candidate.Namespace {
+			continue
+		}
+
+		// the namespace inherits the identity of the controller
+		for _, ctrl := range candidates {
+			if ctrl.Name == candidate.Controller && !ctrl.Namespace {
+				candidate.Model, candidate.Serial, candidate.Firmware = ctrl.Model, ctrl.Serial, ctrl.Firmware
+				candidate.Transport = ctrl.Transport
+				candidate.Bus, candidate.Type = ctrl.Bus, ctrl.Type
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Name < candidates[j].Name
+	})
+
+	return candidates, nil
+}
+
+// Lookup finds the candidate of the device file.
+func (d *Discovery) Lookup(devPath string) (*Candidate, error) {
+	candidates, err := d.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		if candidate.DevPath == devPath {
+			return candidate, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: %w", devPath, os.ErrNotExist)
+}
+
+// Storage makes the devices of the candidates to scan, the NVMe devices by
+// the controllers, the USB devices as SATA through the SAT layer of the bridge
+// and the SAS and FC disks as SCSI. The disk of the type not supported yet is
+// not dropped but reported in unsupported by the device file, and the virtual
+// disks are left out.
+func (d *Discovery) Storage() (storage []StorageDevice, unsupported map[string]error, err error) {
+	candidates, err := d.Discover()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storage = make([]StorageDevice, 0, len(candidates))
+	unsupported = make(map[string]error)
+
+	for _, candidate := range candidates {
+		if candidate.Namespace {
+			continue
+		}
+
+		switch candidate.Type {
+		case SATA, USB:
+			storage = append(storage, newSATADev(candidate.DevPath))
+		case NVMe:
+			storage = append(storage, newNVMeDev(candidate.DevPath))
+		case SCSI:
+			storage = append(storage, newSCSIDev(candidate.DevPath))
+		case "":
+		default:
+			unsupported[candidate.DevPath] = fmt.Errorf("%s: %w", candidate.Type, ErrUnsupportedType)
+		}
+	}
+
+	return storage, unsupported, nil
+}
+
+// DevFiles lists the device files of the type, the NVMe devices are listed by
+// the controllers.
+func (d *Discovery) DevFiles(devType DeviceType) ([]string, error) {
+	candidates, err := d.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+
+	for _, candidate := range candidates {
+		if candidate.Type == devType && !candidate.Namespace {
+			files = append(files, candidate.DevPath)
+		}
+	}
+
+	return files, nil
+}
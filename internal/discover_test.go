@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sysfsFixture struct {
+	t    *testing.T
+	root string
+}
+
+func (f *sysfsFixture) write(path string, attrs map[string]string) {
+	for name, value := range attrs {
+		file := filepath.Join(f.root, path, name)
+
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			f.t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(file, []byte(value+"\n"), 0644); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+}
+
+func (f *sysfsFixture) link(path string, target string) {
+	link := filepath.Join(f.root, path)
+
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		f.t.Fatal(err)
+	}
+
+	if err := os.Symlink(target, link); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// disk adds the SCSI disk under the host path like the kernel, the block
+// device is linked from /sys/block and links the SCSI device back.
+func (f *sysfsFixture) disk(name string, scsiDev string, device map[string]string, block map[string]string) {
+	blockPath := filepath.Join("devices", scsiDev, "block", name)
+
+	f.write(filepath.Join("devices", scsiDev), device)
+	f.write(blockPath, block)
+	f.link(filepath.Join(blockPath, "device"), "../..")
+	f.link(filepath.Join("block", name), filepath.Join("..", blockPath))
+}
+
+func newSysfsFixture(t *testing.T) *sysfsFixture {
+	root, err := ioutil.TempDir("", "sysfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &sysfsFixture{t: t, root: root}
+	hdd := map[string]string{"removable": "0", "queue/rotational": "1", "size": "7814037168"}
+
+	f.disk("sda", "pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0",
+		map[string]string{"type": "0", "vendor": "ATA     ", "model": "ST4000NM0035-1V4", "rev": "TN03"}, hdd)
+
+	sas := "pci0000:00/0000:00:01.0/0000:01:00.0/host2/port-2:0/end_device-2:0"
+	f.disk("sdaa", filepath.Join(sas, "target2:0:0/2:0:0:0"),
+		map[string]string{"type": "0", "vendor": "SEAGATE ", "model": "ST4000NM0025", "rev": "E002", "wwid": "naa.5000c500a1b2c3d4"}, hdd)
+	f.disk("sdab", filepath.Join(sas, "target2:0:1/2:0:1:0"),
+		map[string]string{"type": "0", "vendor": "ATA     ", "model": "WDC WD40EFRX-68N", "rev": "0A82"}, hdd)
+
+	f.disk("sdb", "pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0",
+		map[string]string{"type": "0", "vendor": "SanDisk ", "model": "Ultra Fit", "rev": "1.00"},
+		map[string]string{"removable": "1", "queue/rotational": "1", "size": "60063744"})
+
+	f.disk("sdc", "platform/host7/session1/target7:0:0/7:0:0:0",
+		map[string]string{"type": "0", "vendor": "LIO-ORG ", "model": "backup", "rev": "4.0"}, hdd)
+
+	f.disk("sr0", "pci0000:00/0000:00:17.0/ata2/host1/target1:0:0/1:0:0:0",
+		map[string]string{"type": "5", "vendor": "HL-DT-ST", "model": "DVD-RAM", "rev": "1.00"},
+		map[string]string{"removable": "1"})
+
+	f.write("devices/virtual/block/loop0", map[string]string{"removable": "0", "queue/rotational": "1", "size": "0"})
+	f.link("block/loop0", "../devices/virtual/block/loop0")
+
+	nvme := "devices/pci0000:00/0000:00:1d.0/0000:3d:00.0/nvme/nvme0"
+	f.write(nvme, map[string]string{"model": "Samsung SSD 970 EVO Plus 1TB", "serial": "S4EWNX0N123456", "firmware_rev": "2B2QEXM7", "transport": "pcie"})
+	f.write(filepath.Join(nvme, "nvme0n1"), map[string]string{"removable": "0", "queue/rotational": "0", "size": "1953525168", "wwid": "eui.0025385391b12345"})
+	f.link("class/nvme/nvme0", filepath.Join("../../", nvme))
+	f.link("block/nvme0n1", filepath.Join("..", nvme, "nvme0n1"))
+	f.link("block/nvme0c0n1", filepath.Join("..", nvme, "nvme0n1"))
+
+	// native multipath, the head nvme1n1 is named by the subsystem instance
+	// and served by the controller nvme2
+	mpath := "devices/pci0000:00/0000:00:1c.0/0000:3e:00.0/nvme/nvme2"
+	subsys := "devices/virtual/nvme-subsystem/nvme-subsys1"
+	f.write(mpath, map[string]string{"model": "INTEL SSDPE2KX040T8", "serial": "PHLJ9123456", "firmware_rev": "VDV10170", "transport": "pcie"})
+	f.write(filepath.Join(mpath, "nvme2c2n1"), map[string]string{"size": "7501476528"})
+	f.write(filepath.Join(subsys, "nvme1n1"), map[string]string{"removable": "0", "queue/rotational": "0", "size": "7501476528"})
+	f.link(filepath.Join(subsys, "nvme1n1", "device"), "..")
+	f.link(filepath.Join(subsys, "nvme2"), filepath.Join("../../..", mpath[len("devices/"):]))
+	f.link("class/nvme/nvme2", filepath.Join("../../", mpath))
+	f.link("block/nvme1n1", filepath.Join("..", subsys, "nvme1n1"))
+	f.link("block/nvme2c2n1", filepath.Join("..", mpath, "nvme2c2n1"))
+
+	return f
+}
+
+func TestClassifyBus(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(BusATA, classifyBus("/sys/devices/pci0000:00/0000:00:17.0/ata3/host2/target2:0:0/2:0:0:0/block/sdc", "ATA"))
+	a.Equal(BusATA, classifyBus("/sys/devices/pci0000:00/0000:00:01.0/host2/port-2:0/end_device-2:0/target2:0:0/2:0:0:0/block/sdb", "ATA"))
+	a.Equal(BusSAS, classifyBus("/sys/devices/pci0000:00/0000:00:01.0/host2/port-2:0/expander-2:0/port-2:0:0/end_device-2:0:0/target2:0:0/2:0:0:0/block/sdb", "HGST"))
+	a.Equal(BusSCSI, classifyBus("/sys/devices/pci0000:00/0000:00:02.0/host3/rport-3:0-2/target3:0:0/3:0:0:0/block/sdd", "NETAPP"))
+	a.Equal(BusUSB, classifyBus("/sys/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0/block/sde", "ATA"))
+	a.Equal(BusVirtual, classifyBus("/sys/devices/pci0000:00/0000:00:05.0/virtio2/host0/target0:0:0/0:0:0:0/block/sda", "QEMU"))
+	a.Equal(BusVirtual, classifyBus("/sys/devices/tcm_loop_0/tcm_loop_adapter_0/host8/target8:0:1/8:0:1:0/block/sdf", "LIO-ORG"))
+}
+
+func TestDiscovery(t *testing.T) {
+	a := assert.New(t)
+
+	f := newSysfsFixture(t)
+	defer os.RemoveAll(f.root)
+
+	discovery := &Discovery{SysRoot: f.root, DevRoot: "/dev"}
+
+	candidates, err := discovery.Discover()
+	a.NoError(err)
+
+	names := make([]string, 0)
+	byName := make(map[string]*Candidate)
+	for _, candidate := range candidates {
+		names = append(names, candidate.Name)
+		byName[candidate.Name] = candidate
+	}
+	a.Equal([]string{"loop0", "nvme0", "nvme0n1", "nvme1n1", "nvme2", "sda", "sdaa", "sdab", "sdb", "sdc"}, names)
+
+	sda := byName["sda"]
+	a.Equal(BusATA, sda.Bus)
+	a.Equal(SATA, sda.Type)
+	a.Equal("/dev/sda", sda.DevPath)
+	a.Equal("ATA", sda.Vendor)
+	a.Equal("ST4000NM0035-1V4", sda.Model)
+	a.Equal("TN03", sda.Firmware)
+	a.True(sda.Rotational)
+	a.False(sda.Removable)
+	a.Equal(uint64(4000787030016), sda.Size)
+
+	a.Equal(BusSAS, byName["sdaa"].Bus)
+	a.Equal(SCSI, byName["sdaa"].Type)
+	a.Equal("naa.5000c500a1b2c3d4", byName["sdaa"].WWID)
+	a.Equal(BusATA, byName["sdab"].Bus)
+
+	a.Equal(BusUSB, byName["sdb"].Bus)
+	a.Equal(USB, byName["sdb"].Type)
+	a.True(byName["sdb"].Removable)
+
+	a.Equal(BusVirtual, byName["sdc"].Bus)
+	a.Equal(DeviceType(""), byName["sdc"].Type)
+	a.Equal(BusVirtual, byName["loop0"].Bus)
+
+	ctrl := byName["nvme0"]
+	a.Equal(NVMe, ctrl.Type)
+	a.False(ctrl.Namespace)
+	a.Equal("S4EWNX0N123456", ctrl.Serial)
+	a.Equal("2B2QEXM7", ctrl.Firmware)
+	a.Equal("pcie", ctrl.Transport)
+
+	ns := byName["nvme0n1"]
+	a.True(ns.Namespace)
+	a.Equal("nvme0", ns.Controller)
+	a.Equal(NVMe, ns.Type)
+	a.Equal("Samsung SSD 970 EVO Plus 1TB", ns.Model)
+	a.Equal("eui.0025385391b12345", ns.WWID)
+	a.False(ns.Rotational)
+
+	head := byName["nvme1n1"]
+	a.True(head.Namespace)
+	a.Equal("nvme2", head.Controller)
+	a.Equal(NVMe, head.Type)
+	a.Equal("PHLJ9123456", head.Serial)
+	a.Equal("INTEL SSDPE2KX040T8", head.Model)
+
+	files, err := discovery.DevFiles(SATA)
+	a.NoError(err)
+	a.Equal([]string{"/dev/sda", "/dev/sdab"}, files)
+
+	files, err = discovery.DevFiles(NVMe)
+	a.NoError(err)
+	a.Equal([]string{"/dev/nvme0", "/dev/nvme2"}, files)
+
+	candidate, err := discovery.Lookup("/dev/sdab")
+	a.NoError(err)
+	a.Equal("WDC WD40EFRX-68N", candidate.Model)
+
+	_, err = discovery.Lookup("/dev/sdz")
+	a.True(errors.Is(err, os.ErrNotExist))
+
+	// the USB disk is scanned as SATA and the SAS disk as SCSI
+	storage, unsupported, err := discovery.Storage()
+	a.NoError(err)
+
+	paths := make([]string, 0)
+	for _, device := range storage {
+		paths = append(paths, device.Device())
+	}
+	a.Equal([]string{"/dev/nvme0", "/dev/nvme2", "/dev/sda", "/dev/sdaa", "/dev/sdab", "/dev/sdb"}, paths)
+	a.IsType(&SCSIDevice{}, storage[3])
+	a.IsType(&SATADevice{}, storage[5])
+	a.Len(unsupported, 0)
+
+	// no sysfs
+	_, err = (&Discovery{SysRoot: filepath.Join(f.root, "none"), DevRoot: "/dev"}).Discover()
+	a.Error(err)
+
+	_, _, err = (&Discovery{SysRoot: filepath.Join(f.root, "none"), DevRoot: "/dev"}).Storage()
+	a.Error(err)
+}
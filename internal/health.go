@@ -0,0 +1,385 @@
+package internal
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Metric is a value of the health summary and the log or the attribute the
+// value came from. The value not reported by the device is unknown, not 0.
+type Metric struct {
+	Value  int64
+	Known  bool
+	Source string
+}
+
+func knownMetric(value int64, source string) Metric {
+	return Metric{Value: value, Known: true, Source: source}
+}
+
+// saturate converts the unsigned counter, the counter beyond int64 is
+// reported as math.MaxInt64.
+func saturate(value uint64) int64 {
+	if value > math.MaxInt64 {
+		return math.MaxInt64
+	}
+
+	return int64(value)
+}
+
+func (m Metric) String() string {
+	if !m.Known {
+		return "unknown"
+	}
+
+	return strconv.FormatInt(m.Value, 10)
+}
+
+// MarshalJSON encodes the unknown metric as null.
+func (m Metric) MarshalJSON() ([]byte, error) {
+	if !m.Known {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(struct {
+		Value  int64  `json:"value"`
+		Source string `json:"source"`
+	}{m.Value, m.Source})
+}
+
+// firstKnown picks the first known metric in the order of the preference.
+func firstKnown(metrics ...Metric) Metric {
+	for _, m := range metrics {
+		if m.Known {
+			return m
+		}
+	}
+
+	return Metric{}
+}
+
+// HealthSummary is the protocol independent view of the device health.
+type HealthSummary struct {
+	Verdict       HealthStatus `json:"verdict"`
+	VerdictSource string       `json:"verdict_source"`
+
+	Temperature        Metric `json:"temperature"` // Celsius
+	PowerOnHours       Metric `json:"power_on_hours"`
+	PowerCycles        Metric `json:"power_cycles"`
+	UnsafeShutdowns    Metric `json:"unsafe_shutdowns"`
+	MediaErrors        Metric `json:"media_errors"`
+	ReallocatedSectors Metric `json:"reallocated_sectors"`
+	PendingSectors     Metric `json:"pending_sectors"`
+	PercentUsed        Metric `json:"percent_used"`
+	BytesWritten       Metric `json:"bytes_written"`
+	BytesRead          Metric `json:"bytes_read"`
+}
+
+// Summarize reads the health logs of the device and normalizes them. The ATA
+// device is summarized from the SMART attributes and the Device Statistics
+// log, the NVMe device from the SMART / Health Information log and the SCSI
+// device from the log pages read by LOG SENSE.
+func Summarize(device StorageDevice) (*HealthSummary, error) {
+	switch dev := device.(type) {
+	case *SATADevice:
+		return dev.summarize()
+	case *NVMeDevice:
+		return dev.summarize()
+	case *SCSIDevice:
+		return dev.summarize()
+	}
+
+	return nil, fmt.Errorf("%s: %w", device.Device(), ErrUnsupportedType)
+}
+
+func attrMetric(smart *SmartData, id uint8) Metric {
+	attr := smart.Attribute(id)
+	if attr == nil {
+		return Metric{}
+	}
+
+	return knownMetric(saturate(attr.RawNumber()), fmt.Sprintf("SMART attribute %d (%s)", attr.ID, attr.Name))
+}
+
+func devStatMetric(ds *DeviceStatistics, id DevStatID) Metric {
+	if ds == nil {
+		return Metric{}
+	}
+
+	value, ok := ds.Value(id)
+	if !ok {
+		return Metric{}
+	}
+
+	return knownMetric(value, fmt.Sprintf("Device Statistics %02xh:%03xh (%s)", id.Page(), id.Offset(), ds.Stat(id).Name))
+}
+
+// sectorsMetric converts the logical sectors statistic to bytes.
+func sectorsMetric(ds *DeviceStatistics, id DevStatID, sectorSize uint32) Metric {
+	m := devStatMetric(ds, id)
+	if m.Known {
+		m.Value = saturate(uint64(m.Value) * uint64(sectorSize))
+	}
+
+	return m
+}
+
+// ataVerdict trusts SMART RETURN STATUS, and falls back on the thresholds of
+// the attributes when the device or the bridge does not return the status.
+func ataVerdict(status HealthStatus, smart *SmartData) (HealthStatus, string) {
+	if status != HealthUnknown {
+		return status, "SMART RETURN STATUS"
+	}
+
+	for _, attr := range smart.Attributes {
+		if attr.Status == AttrFailingNow && attr.PreFail() {
+			return HealthFailed, fmt.Sprintf("SMART attribute %d (%s) below threshold", attr.ID, attr.Name)
+		}
+	}
+
+	return HealthPassed, "SMART attribute thresholds"
+}
+
+// The Device Statistics are preferred to the attributes with the vendor
+// specific meaning. The statistics log is optional, so the attributes are
+// used alone if the device does not support the log.
+func (sata *SATADevice) summarize() (*HealthSummary, error) {
+	smart := sata.smart
+	if smart == nil {
+		var err error
+		if smart, err = sata.ReadSMART(); err != nil {
+			return nil, err
+		}
+	}
+
+	ds, _ := sata.ReadDeviceStatistics()
+
+	sectorSize := uint32(defaultLogicalSize)
+	if sata.identity != nil && sata.identity.LogicalSize != 0 {
+		sectorSize = sata.identity.LogicalSize
+	}
+
+	summary := &HealthSummary{
+		Temperature:        firstKnown(devStatMetric(ds, StatTemperature), attrMetric(smart, 194), attrMetric(smart, 190)),
+		PowerOnHours:       firstKnown(devStatMetric(ds, StatPowerOnHours), attrMetric(smart, 9)),
+		PowerCycles:        firstKnown(devStatMetric(ds, StatPowerOnResets), attrMetric(smart, 12)),
+		UnsafeShutdowns:    firstKnown(attrMetric(smart, 192), attrMetric(smart, 174)),
+		MediaErrors:        firstKnown(devStatMetric(ds, StatReportedUncorrectable), attrMetric(smart, 187)),
+		ReallocatedSectors: firstKnown(devStatMetric(ds, StatReallocatedSectors), attrMetric(smart, 5)),
+		PendingSectors:     firstKnown(devStatMetric(ds, StatReallocCandidates), attrMetric(smart, 197)),
+		PercentUsed:        devStatMetric(ds, StatPercentageUsed),
+		BytesWritten:       sectorsMetric(ds, StatSectorsWritten, sectorSize),
+		BytesRead:          sectorsMetric(ds, StatSectorsRead, sectorSize),
+	}
+
+	// the failure of RETURN STATUS is the same as the status not returned
+	status, _ := sata.HealthStatus()
+	summary.Verdict, summary.VerdictSource = ataVerdict(status, smart)
+
+	return summary, nil
+}
+
+func (nvme *NVMeDevice) summarize() (*HealthSummary, error) {
+	smartLog, err := nvme.ReadSmartLog(NVMeNSIDAll)
+	if err != nil {
+		return nil, err
+	}
+
+	nvme.smartLog = smartLog
+
+	source := func(field string) string {
+		return "SMART / Health Information log (02h) " + field
+	}
+
+	counter := func(value Uint128, field string) Metric {
+		return knownMetric(saturate(value.Uint64()), source(field))
+	}
+
+	summary := &HealthSummary{
+		Verdict:         HealthPassed,
+		VerdictSource:   source("Critical Warning"),
+		PowerOnHours:    counter(smartLog.PowerOnHours, "Power On Hours"),
+		PowerCycles:     counter(smartLog.PowerCycles, "Power Cycles"),
+		UnsafeShutdowns: counter(smartLog.UnsafeShutdowns, "Unsafe Shutdowns"),
+		MediaErrors:     counter(smartLog.MediaErrors, "Media and Data Integrity Errors"),
+		PercentUsed:     knownMetric(int64(smartLog.PercentageUsed), source("Percentage Used")),
+		BytesWritten:    counter(smartLog.BytesWritten(), "Data Units Written"),
+		BytesRead:       counter(smartLog.BytesRead(), "Data Units Read"),
+	}
+
+	// the controller without the temperature sensor reports 0 Kelvin
+	if smartLog.Temperature != 0 {
+		summary.Temperature = knownMetric(int64(smartLog.TemperatureCelsius()), source("Composite Temperature"))
+	}
+
+	if smartLog.CriticalWarning != 0 {
+		summary.Verdict = HealthFailed
+	}
+
+	return summary, nil
+}
+
+var scsiLogNames = map[uint8]string{
+	ScsiLogWriteErrors:     "Write Error Counter",
+	ScsiLogReadErrors:      "Read Error Counter",
+	ScsiLogTemperature:     "Temperature",
+	ScsiLogStartStop:       "Start-Stop Cycle Counter",
+	ScsiLogSolidStateMedia: "Solid State Media",
+	ScsiLogBackgroundScan:  "Background Scan Results",
+	ScsiLogInfoExceptions:  "Informational Exceptions",
+}
+
+func scsiLogSource(page uint8, code uint16, field string) string {
+	return fmt.Sprintf("%s log (%02Xh) parameter %04Xh (%s)", scsiLogNames[page], page, code, field)
+}
+
+// scsiLogValue decodes the value of the log parameter, false if the value is
+// not available.
+type scsiLogValue func(param ScsiLogParam) (int64, bool)
+
+func scsiCounter(param ScsiLogParam) (int64, bool) {
+	return saturate(param.Uint64()), len(param.Value) != 0
+}
+
+// scsiTemperature decodes the temperature byte, FFh is not available.
+func scsiTemperature(pos int) scsiLogValue {
+	return func(param ScsiLogParam) (int64, bool) {
+		if len(param.Value) <= pos || param.Value[pos] == ScsiTemperatureInvalid {
+			return 0, false
+		}
+
+		return int64(param.Value[pos]), true
+	}
+}
+
+func scsiByte(pos int) scsiLogValue {
+	return func(param ScsiLogParam) (int64, bool) {
+		if len(param.Value) <= pos {
+			return 0, false
+		}
+
+		return int64(param.Value[pos]), true
+	}
+}
+
+// scsiPowerOnHours decodes the accumulated power on minutes.
+func scsiPowerOnHours(param ScsiLogParam) (int64, bool) {
+	if len(param.Value) < 4 {
+		return 0, false
+	}
+
+	return int64(binary.BigEndian.Uint32(param.Value[0:4]) / 60), true
+}
+
+// scsiLogMetric is the value of the log parameter read by LOG SENSE.
+func scsiLogMetric(pages map[uint8]*ScsiLogPage, page uint8, code uint16, field string, decode scsiLogValue) Metric {
+	log, ok := pages[page]
+	if !ok {
+		return Metric{}
+	}
+
+	param, ok := log.Param(code)
+	if !ok {
+		return Metric{}
+	}
+
+	value, ok := decode(param)
+	if !ok {
+		return Metric{}
+	}
+
+	return knownMetric(value, scsiLogSource(page, code, field))
+}
+
+// sumMetrics adds the known metrics, and the source names all of them.
+func sumMetrics(metrics ...Metric) Metric {
+	sum := Metric{}
+
+	for _, m := range metrics {
+		if !m.Known {
+			continue
+		}
+
+		if sum.Known {
+			sum.Value, sum.Source = saturate(uint64(sum.Value)+uint64(m.Value)), sum.Source+" + "+m.Source
+		} else {
+			sum = m
+		}
+	}
+
+	return sum
+}
+
+// scsiSummaryLogs are the log pages read to summarize the SCSI device.
+var scsiSummaryLogs = []uint8{
+	ScsiLogWriteErrors, ScsiLogReadErrors, ScsiLogTemperature, ScsiLogStartStop,
+	ScsiLogSolidStateMedia, ScsiLogBackgroundScan, ScsiLogInfoExceptions,
+}
+
+// The SCSI device reports the log pages it supports, and the page not
+// supported or failed to read leaves its metrics unknown. The unsafe shutdowns are not counted
+// by SCSI, and the reallocated and the pending sectors are in the defect
+// lists which are not summarized. The power cycles are the start-stop cycles
+// of the spindle, and the verdict is unknown without the Informational
+// Exceptions log page.
+func (scsi *SCSIDevice) summarize() (*HealthSummary, error) {
+	supported, err := scsi.SupportedLogPages()
+	if e, ok := err.(*ScsiError); ok && e.IllegalRequest() {
+		supported = make([]uint8, 0)
+	} else if err != nil {
+		return nil, err
+	}
+
+	scsi.logPages = supported
+
+	listed := make(map[uint8]bool, len(supported))
+	for _, code := range supported {
+		listed[code] = true
+	}
+
+	pages := make(map[uint8]*ScsiLogPage)
+
+	for _, code := range scsiSummaryLogs {
+		if !listed[code] {
+			continue
+		}
+
+		// the page failed to read is left out like the page not supported
+		if page, err := scsi.ReadLogPage(code, 0); err == nil {
+			pages[code] = page
+		}
+	}
+
+	metric := func(page uint8, code uint16, field string, decode scsiLogValue) Metric {
+		return scsiLogMetric(pages, page, code, field, decode)
+	}
+
+	summary := &HealthSummary{
+		Temperature: firstKnown(
+			metric(ScsiLogTemperature, 0x0000, "Temperature", scsiTemperature(1)),
+			metric(ScsiLogInfoExceptions, 0x0000, "Most Recent Temperature Reading", scsiTemperature(2)),
+		),
+		PowerOnHours: metric(ScsiLogBackgroundScan, 0x0000, "Accumulated Power On Minutes", scsiPowerOnHours),
+		PowerCycles:  metric(ScsiLogStartStop, 0x0004, "Accumulated Start-Stop Cycles", scsiCounter),
+		MediaErrors: sumMetrics(
+			metric(ScsiLogReadErrors, 0x0006, "Total Uncorrected Errors", scsiCounter),
+			metric(ScsiLogWriteErrors, 0x0006, "Total Uncorrected Errors", scsiCounter),
+		),
+		PercentUsed:  metric(ScsiLogSolidStateMedia, 0x0001, "Percentage Used Endurance Indicator", scsiByte(3)),
+		BytesWritten: metric(ScsiLogWriteErrors, 0x0005, "Total Bytes Processed", scsiCounter),
+		BytesRead:    metric(ScsiLogReadErrors, 0x0005, "Total Bytes Processed", scsiCounter),
+	}
+
+	// the ASC other than 0 is the failure predicted by the device
+	if verdict := metric(ScsiLogInfoExceptions, 0x0000, "Additional Sense Code", scsiByte(0)); verdict.Known {
+		summary.Verdict, summary.VerdictSource = HealthPassed, verdict.Source
+		if verdict.Value != 0 {
+			summary.Verdict = HealthFailed
+		}
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,226 @@
+package internal
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetric(t *testing.T) {
+	a := assert.New(t)
+
+	unknown := Metric{}
+	a.Equal("unknown", unknown.String())
+
+	raw, err := json.Marshal(unknown)
+	a.NoError(err)
+	a.Equal("null", string(raw))
+
+	known := knownMetric(38, "SMART attribute 194 (Temperature_Celsius)")
+	a.Equal("38", known.String())
+
+	raw, err = json.Marshal(known)
+	a.NoError(err)
+	a.JSONEq(`{"value": 38, "source": "SMART attribute 194 (Temperature_Celsius)"}`, string(raw))
+
+	a.Equal(known, firstKnown(unknown, known, knownMetric(40, "other")))
+	a.False(firstKnown(unknown, unknown).Known)
+
+	a.Equal(int64(math.MaxInt64), saturate(math.MaxUint64))
+}
+
+func TestSATASummarize(t *testing.T) {
+	a := assert.New(t)
+
+	data, thresholds := sampleSmartPages(sampleAttrs)
+	passed := ataRegisters{status: ataStatusDRDY, lba: smartSignature}
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = newFakeTransport(
+		fakeResponse{data: data},
+		fakeResponse{data: thresholds},
+		fakeResponse{data: sampleLogDirectory(map[uint8]uint16{ataLogDevStats: 8})},
+		fakeResponse{data: sampleDeviceStatistics()},
+		fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: ataRegistersSense(passed)}},
+	)
+
+	summary, err := Summarize(sata)
+	a.NoError(err)
+	a.Equal(HealthPassed, summary.Verdict)
+	a.Equal("SMART RETURN STATUS", summary.VerdictSource)
+
+	// the statistics are preferred to the attributes
+	a.Equal(knownMetric(-5, "Device Statistics 05h:008h (Current Temperature)"), summary.Temperature)
+	a.Equal(int64(12345), summary.PowerOnHours.Value)
+	a.Equal(int64(52), summary.PowerCycles.Value)
+	a.Equal(int64(12), summary.PercentUsed.Value)
+	a.Equal(int64(0x1234_5678_9abc*512), summary.BytesWritten.Value)
+
+	// the attribute is used for the statistic not reported
+	a.Equal(knownMetric(0, "SMART attribute 5 (Reallocated_Sector_Ct)"), summary.ReallocatedSectors)
+
+	// neither the statistic nor the attribute
+	a.False(summary.PendingSectors.Known)
+	a.False(summary.UnsafeShutdowns.Known)
+	a.False(summary.MediaErrors.Known)
+	a.False(summary.BytesRead.Known)
+
+	// no Device Statistics log and no RETURN STATUS registers
+	sata = newSATADev("/dev/sda")
+	sata.transport = newFakeTransport(
+		fakeResponse{data: data},
+		fakeResponse{data: thresholds},
+		fakeResponse{data: sampleLogDirectory(map[uint8]uint16{})},
+		fakeResponse{resp: ScsiResponse{Status: ScsiGood}},
+	)
+
+	summary, err = Summarize(sata)
+	a.NoError(err)
+	a.Equal(HealthFailed, summary.Verdict)
+	a.Equal("SMART attribute 10 (Spin_Retry_Count) below threshold", summary.VerdictSource)
+	a.Equal(knownMetric(38, "SMART attribute 194 (Temperature_Celsius)"), summary.Temperature)
+	a.Equal(knownMetric(8760, "SMART attribute 9 (Power_On_Hours)"), summary.PowerOnHours)
+	a.False(summary.PercentUsed.Known)
+}
+
+func TestNVMeSummarize(t *testing.T) {
+	a := assert.New(t)
+
+	nvme := newNVMeDev("/dev/nvme0")
+	nvme.transport = newFakeNVMeTransport(
+		fakeAdminResponse{data: sampleNVMeSmartLog()},
+		fakeAdminResponse{data: make([]byte, nvmeSmartLogSize)},
+		fakeAdminResponse{status: NVMeStatus(0x0006)},
+	)
+
+	summary, err := Summarize(nvme)
+	a.NoError(err)
+	a.Equal(HealthFailed, summary.Verdict)
+	a.Equal("SMART / Health Information log (02h) Critical Warning", summary.VerdictSource)
+	a.Equal(knownMetric(37, "SMART / Health Information log (02h) Composite Temperature"), summary.Temperature)
+	a.Equal(int64(1234), summary.PowerOnHours.Value)
+	a.Equal(int64(52), summary.PowerCycles.Value)
+	a.Equal(int64(7), summary.UnsafeShutdowns.Value)
+	a.Equal(knownMetric(0, "SMART / Health Information log (02h) Media and Data Integrity Errors"), summary.MediaErrors)
+	a.Equal(int64(3), summary.PercentUsed.Value)
+	a.Equal(int64(12345678*512000), summary.BytesRead.Value)
+	a.Equal(int64(math.MaxInt64), summary.BytesWritten.Value)
+	a.NotNil(nvme.SmartLog())
+
+	// not reported by NVMe
+	a.False(summary.ReallocatedSectors.Known)
+	a.False(summary.PendingSectors.Known)
+
+	// no temperature sensor
+	summary, err = Summarize(nvme)
+	a.NoError(err)
+	a.Equal(HealthPassed, summary.Verdict)
+	a.False(summary.Temperature.Known)
+
+	_, err = Summarize(nvme)
+	a.Error(err)
+}
+
+func TestSCSISummarize(t *testing.T) {
+	a := assert.New(t)
+
+	supported := sampleVPD(ScsiLogSupported, []byte{
+		ScsiLogSupported, ScsiLogWriteErrors, ScsiLogReadErrors, ScsiLogTemperature,
+		ScsiLogStartStop, ScsiLogBackgroundScan, ScsiLogInfoExceptions,
+	})
+
+	scsi := newSCSIDev("/dev/sdaa")
+	scsi.transport = newFakeTransport(
+		fakeResponse{data: supported},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogWriteErrors, be64Param(0x0005, 0x1000), be64Param(0x0006, 1))},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogReadErrors, be64Param(0x0005, 0x2000), be64Param(0x0006, 2))},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogTemperature, ScsiLogParam{Code: 0x0000, Value: []byte{0x00, 0x25}})},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogStartStop, be32Param(0x0004, 61))},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogBackgroundScan, ScsiLogParam{Code: 0x0000, Value: []byte{0x00, 0x01, 0x2d, 0x54, 0x08, 0x00}})},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogInfoExceptions, ScsiLogParam{Code: 0x0000, Value: []byte{0x5d, 0x10, 0x26}})},
+	)
+
+	summary, err := Summarize(scsi)
+	a.NoError(err)
+	a.Equal(HealthFailed, summary.Verdict)
+	a.Equal("Informational Exceptions log (2Fh) parameter 0000h (Additional Sense Code)", summary.VerdictSource)
+	a.Equal(knownMetric(37, "Temperature log (0Dh) parameter 0000h (Temperature)"), summary.Temperature)
+	a.Equal(knownMetric(1285, "Background Scan Results log (15h) parameter 0000h (Accumulated Power On Minutes)"), summary.PowerOnHours)
+	a.Equal(knownMetric(61, "Start-Stop Cycle Counter log (0Eh) parameter 0004h (Accumulated Start-Stop Cycles)"), summary.PowerCycles)
+	a.Equal(knownMetric(3, "Read Error Counter log (03h) parameter 0006h (Total Uncorrected Errors) + "+
+		"Write Error Counter log (02h) parameter 0006h (Total Uncorrected Errors)"), summary.MediaErrors)
+	a.Equal(knownMetric(0x1000, "Write Error Counter log (02h) parameter 0005h (Total Bytes Processed)"), summary.BytesWritten)
+	a.Equal(int64(0x2000), summary.BytesRead.Value)
+	a.Len(scsi.Features(), len(scsiLogFeatures))
+
+	// not reported by SCSI or the page not supported
+	a.False(summary.UnsafeShutdowns.Known)
+	a.False(summary.ReallocatedSectors.Known)
+	a.False(summary.PendingSectors.Known)
+	a.False(summary.PercentUsed.Known)
+
+	// the temperature of the Informational Exceptions log page
+	scsi.transport = newFakeTransport(
+		fakeResponse{data: sampleVPD(ScsiLogSupported, []byte{ScsiLogSupported, ScsiLogTemperature, ScsiLogSolidStateMedia, ScsiLogInfoExceptions})},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogTemperature, ScsiLogParam{Code: 0x0000, Value: []byte{0x00, 0xff}})},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogSolidStateMedia, ScsiLogParam{Code: 0x0001, Value: []byte{0x00, 0x00, 0x00, 0x04}})},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogInfoExceptions, ScsiLogParam{Code: 0x0000, Value: []byte{0x00, 0x00, 0x26}})},
+	)
+
+	summary, err = Summarize(scsi)
+	a.NoError(err)
+	a.Equal(HealthPassed, summary.Verdict)
+	a.Equal(knownMetric(38, "Informational Exceptions log (2Fh) parameter 0000h (Most Recent Temperature Reading)"), summary.Temperature)
+	a.Equal(knownMetric(4, "Solid State Media log (11h) parameter 0001h (Percentage Used Endurance Indicator)"), summary.PercentUsed)
+	a.False(summary.MediaErrors.Known)
+
+	// no log page is supported
+	scsi.transport = newFakeTransport(fakeResponse{resp: checkCondition(senseKeyIllegalRequest, 0x24, 0x00)})
+
+	summary, err = Summarize(scsi)
+	a.NoError(err)
+	a.Equal(HealthUnknown, summary.Verdict)
+	a.Empty(summary.VerdictSource)
+	a.False(summary.Temperature.Known)
+
+	// the page failed to read leaves its metrics unknown
+	scsi.transport = newFakeTransport(
+		fakeResponse{data: sampleVPD(ScsiLogSupported, []byte{ScsiLogSupported, ScsiLogWriteErrors, ScsiLogTemperature, ScsiLogInfoExceptions})},
+		fakeResponse{resp: checkCondition(senseKeyIllegalRequest, 0x24, 0x00)},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogTemperature, ScsiLogParam{Code: 0x0000, Value: []byte{0x00, 0x25}})},
+		fakeResponse{resp: ScsiResponse{Status: ScsiBusy}},
+	)
+
+	summary, err = Summarize(scsi)
+	a.NoError(err)
+	a.Equal(HealthUnknown, summary.Verdict)
+	a.Equal(int64(37), summary.Temperature.Value)
+	a.False(summary.BytesWritten.Known)
+	a.False(summary.MediaErrors.Known)
+
+	// the failure to read the supported log pages
+	scsi.transport = newFakeTransport(fakeResponse{resp: ScsiResponse{Status: ScsiBusy}})
+
+	_, err = Summarize(scsi)
+	a.Error(err)
+}
+
+func TestHealthSummaryJSON(t *testing.T) {
+	a := assert.New(t)
+
+	summary := &HealthSummary{
+		Verdict:      HealthPassed,
+		PowerOnHours: knownMetric(10, "source"),
+	}
+
+	raw, err := json.Marshal(summary)
+	a.NoError(err)
+
+	decoded := make(map[string]interface{})
+	a.NoError(json.Unmarshal(raw, &decoded))
+	a.Equal("PASSED", decoded["verdict"])
+	a.Nil(decoded["temperature"])
+	a.Equal(map[string]interface{}{"value": 10.0, "source": "source"}, decoded["power_on_hours"])
+}
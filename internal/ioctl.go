@@ -5,9 +5,18 @@ import (
 )
 
 func IoCtl(fd, cmd, ptr uintptr) error {
-	if _, _, err := unix.Syscall(unix.SYS_IOCTL, fd, cmd, ptr); err != 0 {
-		return err
+	_, err := IoCtlResult(fd, cmd, ptr)
+
+	return err
+}
+
+// IoCtlResult returns the non-negative return value of the ioctl, which some
+// drivers use to report the command status.
+func IoCtlResult(fd, cmd, ptr uintptr) (uintptr, error) {
+	ret, _, err := unix.Syscall(unix.SYS_IOCTL, fd, cmd, ptr)
+	if err != 0 {
+		return 0, err
 	}
 
-	return nil
+	return ret, nil
 }
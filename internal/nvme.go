@@ -1,7 +1,57 @@
 package internal
 
+import "time"
+
+const (
+	// NVM Express 1.4 (Figure 139 - Opcodes for Admin Commands)
+	NVMeAdminGetLogPage   = 0x02
+	NVMeAdminIdentify     = 0x06
+	NVMeAdminSetFeatures  = 0x09
+	NVMeAdminGetFeatures  = 0x0A
+	NVMeAdminFwCommit     = 0x10
+	NVMeAdminFwDownload   = 0x11
+	NVMeAdminDevSelfTest  = 0x14
+	NVMeAdminFormatNVM    = 0x80
+	NVMeAdminSanitize     = 0x84
+	NVMeAdminSecurityRecv = 0x82
+
+	NVMeNSIDAll = uint32(0xffffffff)
+)
+
+// NVMeAdminCmd is the admin command submitted through NVME_IOCTL_ADMIN_CMD.
+// Data is transferred in the direction of the opcode bits 1:0.
+type NVMeAdminCmd struct {
+	Opcode  uint8
+	Flags   uint8
+	NSID    uint32
+	CDW2    uint32
+	CDW3    uint32
+	CDW10   uint32
+	CDW11   uint32
+	CDW12   uint32
+	CDW13   uint32
+	CDW14   uint32
+	CDW15   uint32
+	Data    []byte
+	Timeout time.Duration
+}
+
+// NVMeTransport submits the admin commands to the controller. It returns
+// the completion queue entry dword 0, and NVMeStatusError if the command
+// completes with the error status.
+type NVMeTransport interface {
+	AdminCommand(cmd *NVMeAdminCmd) (uint32, error)
+	Close() error
+}
+
 type NVMeDevice struct {
 	StorageMeta
+
+	transport  NVMeTransport
+	controller *NVMeController
+	namespaces []*NVMeNamespace
+	smartLog   *NVMeSmartLog
+	firmwares  *NVMeFirmwareInventory
 }
 
 func newNVMeDev(path string) *NVMeDevice {
@@ -13,11 +63,70 @@ func newNVMeDev(path string) *NVMeDevice {
 	return nvme
 }
 
-func ScanNVMe(storage map[string]StorageDevice) (map[string]StorageDevice, error) {
+func (nvme *NVMeDevice) open() error {
+	if nvme.transport != nil {
+		return nil
+	}
+
+	transport, err := OpenNVMeTransport(nvme.devPath)
+	if err != nil {
+		return err
+	}
+
+	nvme.transport = transport
+
+	return nil
+}
+
+func (nvme *NVMeDevice) Close() error {
+	if nvme.transport == nil {
+		return nil
+	}
 
-	return storage, nil
+	err := nvme.transport.Close()
+	nvme.transport = nil
+
+	return err
+}
+
+func (nvme *NVMeDevice) adminCommand(cmd *NVMeAdminCmd) (uint32, error) {
+	if err := nvme.open(); err != nil {
+		return 0, err
+	}
+
+	return nvme.transport.AdminCommand(cmd)
+}
+
+// ID is the model and the serial of the identified controller.
+func (nvme *NVMeDevice) ID() string {
+	return deviceID("nvme", nvme.model, nvme.serial)
 }
 
 /*
  * inherited interface methods
  */
+func (nvme *NVMeDevice) ScanSMART() error {
+	if _, err := nvme.IdentifyController(); err != nil {
+		return err
+	}
+
+	if err := nvme.scanNamespaces(); err != nil {
+		return err
+	}
+
+	smartLog, err := nvme.ReadSmartLog(NVMeNSIDAll)
+	if err != nil {
+		return err
+	}
+
+	nvme.smartLog = smartLog
+
+	firmwares, err := nvme.FirmwareInventory()
+	if err != nil {
+		return err
+	}
+
+	nvme.firmwares = firmwares
+
+	return nil
+}
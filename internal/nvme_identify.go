@@ -0,0 +1,507 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	nvmeIdentifySize = 4096
+
+	// NVM Express 1.4 (Figure 244 - CNS Values)
+	cnsNamespace        = 0x00
+	cnsController       = 0x01
+	cnsActiveNamespaces = 0x02
+
+	// Optional Admin Command Support
+	OACSSecurity      = uint16(0x0001)
+	OACSFormatNVM     = uint16(0x0002)
+	OACSFirmware      = uint16(0x0004)
+	OACSNamespaceMgmt = uint16(0x0008)
+	OACSSelfTest      = uint16(0x0010)
+	OACSDirectives    = uint16(0x0020)
+	OACSNVMeMI        = uint16(0x0040)
+	OACSVirtualMgmt   = uint16(0x0080)
+	OACSDoorbellBuf   = uint16(0x0100)
+	OACSGetLBAStatus  = uint16(0x0200)
+
+	// Log Page Attributes
+	LPASmartPerNamespace = uint8(0x01)
+	LPACommandEffects    = uint8(0x02)
+	LPAExtendedData      = uint8(0x04)
+	LPATelemetry         = uint8(0x08)
+	LPAPersistentEvent   = uint8(0x10)
+
+	// Optional NVM Command Support
+	ONCSCompare        = uint16(0x0001)
+	ONCSWriteUncorrect = uint16(0x0002)
+	ONCSDatasetMgmt    = uint16(0x0004)
+	ONCSWriteZeroes    = uint16(0x0008)
+	ONCSSaveSelect     = uint16(0x0010)
+	ONCSReservations   = uint16(0x0020)
+	ONCSTimestamp      = uint16(0x0040)
+	ONCSVerify         = uint16(0x0080)
+
+	// Firmware Updates
+	frmwSlot1ReadOnly = uint8(0x01)
+	frmwSlotsPos      = 1
+	frmwSlotsMASK     = uint8(0x07)
+	frmwNoResetActive = uint8(0x10)
+
+	powerStateOffset = 2048
+	powerStateSize   = 32
+	maxPowerStates   = 32
+
+	lbaFormatOffset = 128
+	lbaFormatSize   = 4
+	maxLBAFormats   = 16
+	flbasIndexMASK  = uint8(0x0f)
+
+	activeNSListLength = 1024
+)
+
+var ErrShortNVMeIdentify = errors.New("nvme identify data is shorter than 4096 bytes")
+
+type NVMePowerState struct {
+	MaxPower         uint16 // in MaxPowerScale units
+	MaxPowerScale    bool   // false: 0.01W, true: 0.0001W
+	NonOperational   bool
+	EntryLatency     uint32 // microseconds
+	ExitLatency      uint32 // microseconds
+	ReadThroughput   uint8  // relative
+	ReadLatency      uint8  // relative
+	WriteThroughput  uint8  // relative
+	WriteLatency     uint8  // relative
+	IdlePower        uint16
+	IdlePowerScale   uint8 // 0: not reported, 1: 0.0001W, 2: 0.01W
+	ActivePower      uint16
+	ActivePowerScale uint8 // 0: not reported, 1: 0.0001W, 2: 0.01W
+}
+
+// MaxPowerWatts converts the maximum power to watts.
+func (ps *NVMePowerState) MaxPowerWatts() float64 {
+	if ps.MaxPowerScale {
+		return float64(ps.MaxPower) / 10000
+	}
+
+	return float64(ps.MaxPower) / 100
+}
+
+// NVMeController is the decoded Identify Controller data structure.
+type NVMeController struct {
+	VendorID             uint16
+	SubsystemVendorID    uint16
+	Serial               string
+	Model                string
+	Firmware             string
+	IEEEOUI              uint32
+	ControllerID         uint16
+	Version              uint32
+	MDTS                 uint8 // maximum data transfer size in the power of two of the minimum page size
+	OACS                 uint16
+	FRMW                 uint8
+	LPA                  uint8
+	ELPE                 uint8 // error log page entries, 0's based value
+	WarningTemp          uint16
+	CriticalTemp         uint16
+	TotalCapacity        Uint128
+	UnallocatedCapacity  Uint128
+	NumNamespaces        uint32
+	ONCS                 uint16
+	VolatileWriteCache   bool
+	FirmwareSlots        uint8
+	Slot1ReadOnly        bool
+	ActivateWithoutReset bool
+	PowerStates          []NVMePowerState
+}
+
+// VersionString formats VER as "major.minor[.tertiary]".
+func (ctrl *NVMeController) VersionString() string {
+	if ctrl.Version == 0 {
+		return ""
+	}
+
+	major, minor, tertiary := ctrl.Version>>16, (ctrl.Version>>8)&0xff, ctrl.Version&0xff
+	if tertiary == 0 {
+		return fmt.Sprintf("%d.%d", major, minor)
+	}
+
+	return fmt.Sprintf("%d.%d.%d", major, minor, tertiary)
+}
+
+func (ctrl *NVMeController) SupportsAdmin(oacs uint16) bool {
+	return ctrl.OACS&oacs == oacs
+}
+
+func (ctrl *NVMeController) SupportsLogPage(lpa uint8) bool {
+	return ctrl.LPA&lpa == lpa
+}
+
+func (ctrl *NVMeController) SupportsNVM(oncs uint16) bool {
+	return ctrl.ONCS&oncs == oncs
+}
+
+// ErrorLogEntries is the number of Error Information log entries.
+func (ctrl *NVMeController) ErrorLogEntries() int {
+	return int(ctrl.ELPE) + 1
+}
+
+// WarningTempCelsius converts WCTEMP from Kelvin, 0 if not reported.
+func (ctrl *NVMeController) WarningTempCelsius() int {
+	return kelvinToCelsius(ctrl.WarningTemp)
+}
+
+// CriticalTempCelsius converts CCTEMP from Kelvin, 0 if not reported.
+func (ctrl *NVMeController) CriticalTempCelsius() int {
+	return kelvinToCelsius(ctrl.CriticalTemp)
+}
+
+func kelvinToCelsius(kelvin uint16) int {
+	if kelvin == 0 {
+		return 0
+	}
+
+	return int(kelvin) - 273
+}
+
+type NVMeLBAFormat struct {
+	MetadataSize        uint16
+	DataSize            uint32
+	RelativePerformance uint8 // 0: best, 3: degraded
+}
+
+// NVMeNamespace is the decoded Identify Namespace data structure.
+type NVMeNamespace struct {
+	NSID         uint32
+	Size         uint64 // NSZE in logical blocks
+	Capacity     uint64 // NCAP in logical blocks
+	Utilization  uint64 // NUSE in logical blocks
+	Features     uint8
+	NGUID        [16]byte
+	EUI64        uint64
+	LBAFormats   []NVMeLBAFormat
+	ActiveFormat int
+}
+
+// BlockSize is the data size of the active LBA format.
+func (ns *NVMeNamespace) BlockSize() uint32 {
+	if ns.ActiveFormat >= len(ns.LBAFormats) {
+		return 0
+	}
+
+	return ns.LBAFormats[ns.ActiveFormat].DataSize
+}
+
+func (ns *NVMeNamespace) SizeBytes() uint64 {
+	return ns.Size * uint64(ns.BlockSize())
+}
+
+func (ns *NVMeNamespace) UtilizationBytes() uint64 {
+	return ns.Utilization * uint64(ns.BlockSize())
+}
+
+func nvmeString(raw []byte) string {
+	return strings.TrimSpace(strings.TrimRight(string(raw), "\x00"))
+}
+
+func le128(raw []byte) Uint128 {
+	var value dqword
+	copy(value[:], raw)
+
+	return value.uint128()
+}
+
+func parsePowerState(raw []byte) NVMePowerState {
+	return NVMePowerState{
+		MaxPower:         binary.LittleEndian.Uint16(raw[0:2]),
+		MaxPowerScale:    raw[3]&0x01 != 0,
+		NonOperational:   raw[3]&0x02 != 0,
+		EntryLatency:     binary.LittleEndian.Uint32(raw[4:8]),
+		ExitLatency:      binary.LittleEndian.Uint32(raw[8:12]),
+		ReadThroughput:   raw[12] & 0x1f,
+		ReadLatency:      raw[13] & 0x1f,
+		WriteThroughput:  raw[14] & 0x1f,
+		WriteLatency:     raw[15] & 0x1f,
+		IdlePower:        binary.LittleEndian.Uint16(raw[16:18]),
+		IdlePowerScale:   raw[18] >> 6,
+		ActivePower:      binary.LittleEndian.Uint16(raw[20:22]),
+		ActivePowerScale: raw[22] >> 6,
+	}
+}
+
+// NVM Express 1.4 (Figure 247 - Identify Controller Data Structure)
+func parseNVMeController(buf []byte) (*NVMeController, error) {
+	if len(buf) < nvmeIdentifySize {
+		return nil, ErrShortNVMeIdentify
+	}
+
+	ctrl := &NVMeController{
+		VendorID:            binary.LittleEndian.Uint16(buf[0:2]),
+		SubsystemVendorID:   binary.LittleEndian.Uint16(buf[2:4]),
+		Serial:              nvmeString(buf[4:24]),
+		Model:               nvmeString(buf[24:64]),
+		Firmware:            nvmeString(buf[64:72]),
+		IEEEOUI:             uint32(buf[73]) | uint32(buf[74])<<8 | uint32(buf[75])<<16,
+		MDTS:                buf[77],
+		ControllerID:        binary.LittleEndian.Uint16(buf[78:80]),
+		Version:             binary.LittleEndian.Uint32(buf[80:84]),
+		OACS:                binary.LittleEndian.Uint16(buf[256:258]),
+		FRMW:                buf[260],
+		LPA:                 buf[261],
+		ELPE:                buf[262],
+		WarningTemp:         binary.LittleEndian.Uint16(buf[266:268]),
+		CriticalTemp:        binary.LittleEndian.Uint16(buf[268:270]),
+		TotalCapacity:       le128(buf[280:296]),
+		UnallocatedCapacity: le128(buf[296:312]),
+		NumNamespaces:       binary.LittleEndian.Uint32(buf[516:520]),
+		ONCS:                binary.LittleEndian.Uint16(buf[520:522]),
+		VolatileWriteCache:  buf[525]&0x01 != 0,
+	}
+
+	ctrl.Slot1ReadOnly = ctrl.FRMW&frmwSlot1ReadOnly != 0
+	ctrl.FirmwareSlots = (ctrl.FRMW >> frmwSlotsPos) & frmwSlotsMASK
+	ctrl.ActivateWithoutReset = ctrl.FRMW&frmwNoResetActive != 0
+
+	// NPSS is 0's based value, and the descriptors over the spec maximum of
+	// 32 are not in the identify data
+	npss := int(buf[263]) + 1
+	if npss > maxPowerStates {
+		npss = maxPowerStates
+	}
+	ctrl.PowerStates = make([]NVMePowerState, 0, npss)
+
+	for i := 0; i < npss; i++ {
+		pos := powerStateOffset + i*powerStateSize
+		ctrl.PowerStates = append(ctrl.PowerStates, parsePowerState(buf[pos:pos+powerStateSize]))
+	}
+
+	return ctrl, nil
+}
+
+// NVM Express 1.4 (Figure 245 - Identify Namespace Data Structure)
+func parseNVMeNamespace(nsid uint32, buf []byte) (*NVMeNamespace, error) {
+	if len(buf) < nvmeIdentifySize {
+		return nil, ErrShortNVMeIdentify
+	}
+
+	ns := &NVMeNamespace{
+		NSID:         nsid,
+		Size:         binary.LittleEndian.Uint64(buf[0:8]),
+		Capacity:     binary.LittleEndian.Uint64(buf[8:16]),
+		Utilization:  binary.LittleEndian.Uint64(buf[16:24]),
+		Features:     buf[24],
+		EUI64:        binary.BigEndian.Uint64(buf[120:128]),
+		ActiveFormat: int(buf[26] & flbasIndexMASK),
+	}
+
+	copy(ns.NGUID[:], buf[104:120])
+
+	// NLBAF is 0's based value
+	nlbaf := int(buf[25]) + 1
+	if nlbaf > maxLBAFormats {
+		nlbaf = maxLBAFormats
+	}
+
+	ns.LBAFormats = make([]NVMeLBAFormat, 0, nlbaf)
+
+	for i := 0; i < nlbaf; i++ {
+		raw := buf[lbaFormatOffset+i*lbaFormatSize:]
+
+		format := NVMeLBAFormat{
+			MetadataSize:        binary.LittleEndian.Uint16(raw[0:2]),
+			RelativePerformance: raw[3] & 0x03,
+		}
+
+		if raw[2] > 0 {
+			format.DataSize = 1 << raw[2]
+		}
+
+		ns.LBAFormats = append(ns.LBAFormats, format)
+	}
+
+	return ns, nil
+}
+
+func (nvme *NVMeDevice) identify(cns uint8, nsid uint32) ([]byte, error) {
+	buf := make([]byte, nvmeIdentifySize)
+
+	cmd := &NVMeAdminCmd{
+		Opcode: NVMeAdminIdentify,
+		NSID:   nsid,
+		CDW10:  uint32(cns),
+		Data:   buf,
+	}
+
+	if _, err := nvme.adminCommand(cmd); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// IdentifyController issues Identify (CNS 01h) and updates the model,
+// firmware and serial of the device.
+func (nvme *NVMeDevice) IdentifyController() (*NVMeController, error) {
+	buf, err := nvme.identify(cnsController, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl, err := parseNVMeController(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	nvme.controller = ctrl
+
+	nvme.model = ctrl.Model
+	nvme.firmware = ctrl.Firmware
+	nvme.serial = ctrl.Serial
+
+	return ctrl, nil
+}
+
+// IdentifyNamespace issues Identify (CNS 00h) on the namespace.
+func (nvme *NVMeDevice) IdentifyNamespace(nsid uint32) (*NVMeNamespace, error) {
+	buf, err := nvme.identify(cnsNamespace, nsid)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNVMeNamespace(nsid, buf)
+}
+
+// ActiveNamespaces issues Identify (CNS 02h) and lists the active NSIDs.
+func (nvme *NVMeDevice) ActiveNamespaces() ([]uint32, error) {
+	buf, err := nvme.identify(cnsActiveNamespaces, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	nsids := make([]uint32, 0)
+
+	for i := 0; i < activeNSListLength; i++ {
+		nsid := binary.LittleEndian.Uint32(buf[i*4:])
+		if nsid == 0 {
+			break
+		}
+
+		nsids = append(nsids, nsid)
+	}
+
+	return nsids, nil
+}
+
+func (nvme *NVMeDevice) Controller() *NVMeController {
+	return nvme.controller
+}
+
+func (nvme *NVMeDevice) Namespaces() []*NVMeNamespace {
+	return nvme.namespaces
+}
+
+// Capacity is the total NVM capacity of the controller, or the sum of the
+// namespace sizes if the controller does not report it.
+func (nvme *NVMeDevice) Capacity() uint64 {
+	if nvme.controller != nil && nvme.controller.TotalCapacity != (Uint128{}) {
+		return nvme.controller.TotalCapacity.Uint64()
+	}
+
+	capacity := uint64(0)
+	for _, ns := range nvme.namespaces {
+		capacity += ns.SizeBytes()
+	}
+
+	return capacity
+}
+
+var nvmeOptionalAdmin = []struct {
+	name string
+	oacs uint16
+}{
+	{"Security Send and Receive", OACSSecurity},
+	{"Format NVM", OACSFormatNVM},
+	{"Firmware Commit and Image Download", OACSFirmware},
+	{"Namespace Management", OACSNamespaceMgmt},
+	{"Device Self-test", OACSSelfTest},
+	{"Directives", OACSDirectives},
+	{"NVMe-MI Send and Receive", OACSNVMeMI},
+	{"Virtualization Management", OACSVirtualMgmt},
+	{"Doorbell Buffer Config", OACSDoorbellBuf},
+	{"Get LBA Status", OACSGetLBAStatus},
+}
+
+var nvmeOptionalNVM = []struct {
+	name string
+	oncs uint16
+}{
+	{"Compare", ONCSCompare},
+	{"Write Uncorrectable", ONCSWriteUncorrect},
+	{"Dataset Management", ONCSDatasetMgmt},
+	{"Write Zeroes", ONCSWriteZeroes},
+	{"Save field in Set Features", ONCSSaveSelect},
+	{"Reservations", ONCSReservations},
+	{"Timestamp", ONCSTimestamp},
+	{"Verify", ONCSVerify},
+}
+
+// Features lists the optional admin and NVM commands of the identified
+// controller, the supported commands are always enabled.
+func (nvme *NVMeDevice) Features() []Capability {
+	if nvme.controller == nil {
+		return nil
+	}
+
+	features := make([]Capability, 0, len(nvmeOptionalAdmin)+len(nvmeOptionalNVM))
+
+	for _, cmd := range nvmeOptionalAdmin {
+		supported := nvme.controller.SupportsAdmin(cmd.oacs)
+		features = append(features, Capability{Name: cmd.name, Supported: supported, Enabled: supported})
+	}
+
+	for _, cmd := range nvmeOptionalNVM {
+		supported := nvme.controller.SupportsNVM(cmd.oncs)
+		features = append(features, Capability{Name: cmd.name, Supported: supported, Enabled: supported})
+	}
+
+	return features
+}
+
+// scanNamespaces identifies all active namespaces. Controllers before NVMe
+// 1.1 do not support the active namespace list, then namespaces are
+// identified from 1 to NN.
+func (nvme *NVMeDevice) scanNamespaces() error {
+	nsids, err := nvme.ActiveNamespaces()
+	if err != nil {
+		if _, ok := err.(*NVMeStatusError); !ok {
+			return err
+		}
+
+		nsids = make([]uint32, 0, nvme.controller.NumNamespaces)
+		for nsid := uint32(1); nsid <= nvme.controller.NumNamespaces; nsid++ {
+			nsids = append(nsids, nsid)
+		}
+	}
+
+	namespaces := make([]*NVMeNamespace, 0, len(nsids))
+
+	for _, nsid := range nsids {
+		ns, err := nvme.IdentifyNamespace(nsid)
+		if err != nil {
+			return err
+		}
+
+		// inactive namespaces return zero filled data
+		if ns.Size == 0 {
+			continue
+		}
+
+		namespaces = append(namespaces, ns)
+	}
+
+	nvme.namespaces = namespaces
+
+	return nil
+}
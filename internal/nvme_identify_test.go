@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func padString(buf []byte, str string) {
+	for i := range buf {
+		buf[i] = ' '
+	}
+
+	copy(buf, str)
+}
+
+func sampleNVMeController() []byte {
+	buf := make([]byte, nvmeIdentifySize)
+
+	binary.LittleEndian.PutUint16(buf[0:], 0x144d)
+	binary.LittleEndian.PutUint16(buf[2:], 0x144d)
+	padString(buf[4:24], "S4EWNX0N123456")
+	padString(buf[24:64], "Samsung SSD 970 EVO Plus 1TB")
+	padString(buf[64:72], "2B2QEXM7")
+	buf[73], buf[74], buf[75] = 0x38, 0x25, 0x00
+	buf[77] = 9
+	binary.LittleEndian.PutUint16(buf[78:], 0x0004)
+	binary.LittleEndian.PutUint32(buf[80:], 0x00010300)
+	binary.LittleEndian.PutUint16(buf[256:], 0x0017)
+	buf[260] = 0x16 // 3 slots, activation without reset
+	buf[261] = 0x03
+	buf[262] = 63
+	buf[263] = 4
+	binary.LittleEndian.PutUint16(buf[266:], 358)
+	binary.LittleEndian.PutUint16(buf[268:], 358)
+	binary.LittleEndian.PutUint64(buf[280:], 1000204886016)
+	binary.LittleEndian.PutUint32(buf[516:], 1)
+	binary.LittleEndian.PutUint16(buf[520:], 0x005f)
+	buf[525] = 0x01
+
+	// power state 0 and 4
+	binary.LittleEndian.PutUint16(buf[2048:], 750)
+	binary.LittleEndian.PutUint16(buf[2048+4*32:], 50)
+	buf[2048+4*32+3] = 0x03
+	binary.LittleEndian.PutUint32(buf[2048+4*32+4:], 500)
+	binary.LittleEndian.PutUint32(buf[2048+4*32+8:], 5000)
+
+	return buf
+}
+
+func sampleNVMeNamespace() []byte {
+	buf := make([]byte, nvmeIdentifySize)
+
+	binary.LittleEndian.PutUint64(buf[0:], 1953525168)
+	binary.LittleEndian.PutUint64(buf[8:], 1953525168)
+	binary.LittleEndian.PutUint64(buf[16:], 406210160)
+	buf[25] = 1 // 2 formats
+	buf[26] = 0x01
+	binary.BigEndian.PutUint64(buf[120:], 0x002538b591b0a1c2)
+
+	buf[128+2] = 9
+	buf[128+3] = 0x02
+	buf[132+2] = 12
+
+	return buf
+}
+
+func TestParseNVMeController(t *testing.T) {
+	a := assert.New(t)
+
+	ctrl, err := parseNVMeController(sampleNVMeController())
+	a.NoError(err)
+
+	a.Equal(uint16(0x144d), ctrl.VendorID)
+	a.Equal("S4EWNX0N123456", ctrl.Serial)
+	a.Equal("Samsung SSD 970 EVO Plus 1TB", ctrl.Model)
+	a.Equal("2B2QEXM7", ctrl.Firmware)
+	a.Equal(uint32(0x002538), ctrl.IEEEOUI)
+	a.Equal(uint16(4), ctrl.ControllerID)
+	a.Equal("1.3", ctrl.VersionString())
+	a.True(ctrl.SupportsAdmin(OACSSelfTest | OACSFirmware))
+	a.False(ctrl.SupportsAdmin(OACSDirectives))
+	a.True(ctrl.SupportsLogPage(LPASmartPerNamespace))
+	a.True(ctrl.SupportsNVM(ONCSDatasetMgmt))
+	a.False(ctrl.SupportsNVM(ONCSReservations))
+	a.Equal(64, ctrl.ErrorLogEntries())
+	a.Equal(85, ctrl.WarningTempCelsius())
+	a.Equal(uint64(1000204886016), ctrl.TotalCapacity.Uint64())
+	a.Equal("0", ctrl.UnallocatedCapacity.String())
+	a.Equal(uint32(1), ctrl.NumNamespaces)
+	a.True(ctrl.VolatileWriteCache)
+	a.Equal(uint8(3), ctrl.FirmwareSlots)
+	a.False(ctrl.Slot1ReadOnly)
+	a.True(ctrl.ActivateWithoutReset)
+
+	a.Len(ctrl.PowerStates, 5)
+	a.Equal(7.5, ctrl.PowerStates[0].MaxPowerWatts())
+	a.True(ctrl.PowerStates[4].NonOperational)
+	a.Equal(0.005, ctrl.PowerStates[4].MaxPowerWatts())
+	a.Equal(uint32(5000), ctrl.PowerStates[4].ExitLatency)
+
+	// NPSS over the spec maximum is capped to the descriptors in the data
+	buf := sampleNVMeController()
+	buf[263] = 0xff
+
+	ctrl, err = parseNVMeController(buf)
+	a.NoError(err)
+	a.Len(ctrl.PowerStates, maxPowerStates)
+
+	_, err = parseNVMeController(make([]byte, 512))
+	a.Equal(ErrShortNVMeIdentify, err)
+}
+
+func TestParseNVMeNamespace(t *testing.T) {
+	a := assert.New(t)
+
+	ns, err := parseNVMeNamespace(1, sampleNVMeNamespace())
+	a.NoError(err)
+
+	a.Equal(uint32(1), ns.NSID)
+	a.Equal(uint64(1953525168), ns.Size)
+	a.Len(ns.LBAFormats, 2)
+	a.Equal(uint32(512), ns.LBAFormats[0].DataSize)
+	a.Equal(uint8(2), ns.LBAFormats[0].RelativePerformance)
+	a.Equal(1, ns.ActiveFormat)
+	a.Equal(uint32(4096), ns.BlockSize())
+	a.Equal(uint64(1953525168*4096), ns.SizeBytes())
+	a.Equal(uint64(406210160*4096), ns.UtilizationBytes())
+	a.Equal(uint64(0x002538b591b0a1c2), ns.EUI64)
+}
+
+func TestUint128(t *testing.T) {
+	a := assert.New(t)
+
+	value := le128([]byte{0x01, 0, 0, 0, 0, 0, 0, 0, 0x01})
+	a.Equal(Uint128{Lo: 1, Hi: 1}, value)
+	a.Equal("18446744073709551617", value.String())
+	a.Equal(^uint64(0), value.Uint64())
+
+	json, err := value.MarshalJSON()
+	a.NoError(err)
+	a.Equal("18446744073709551617", string(json))
+}
+
+func TestNVMeScanSMARTIdentify(t *testing.T) {
+	a := assert.New(t)
+
+	nsList := make([]byte, nvmeIdentifySize)
+	binary.LittleEndian.PutUint32(nsList[0:], 1)
+
+	transport := newFakeNVMeTransport(
+		fakeAdminResponse{data: sampleNVMeController()},
+		fakeAdminResponse{data: nsList},
+		fakeAdminResponse{data: sampleNVMeNamespace()},
+		fakeAdminResponse{data: make([]byte, nvmeSmartLogSize)},
+		fakeAdminResponse{data: sampleNVMeFirmwareSlotLog()},
+	)
+
+	nvme := newNVMeDev("/dev/nvme0")
+	nvme.transport = transport
+
+	a.NoError(nvme.ScanSMART())
+
+	a.Equal("Samsung SSD 970 EVO Plus 1TB", nvme.Model())
+	a.Equal("2B2QEXM7", nvme.Firmware())
+	a.Equal("S4EWNX0N123456", nvme.Serial())
+	a.Equal(NVMe, nvme.Type())
+	a.NotNil(nvme.Controller())
+	a.Len(nvme.Namespaces(), 1)
+	a.NotNil(nvme.SmartLog())
+	a.Equal("2B2QEXM7", nvme.Firmwares().Active().Revision)
+	a.Equal("nvme-Samsung_SSD_970_EVO_Plus_1TB_S4EWNX0N123456", nvme.ID())
+	a.Equal(uint64(1000204886016), nvme.Capacity())
+
+	features := make(map[string]bool)
+	for _, feature := range nvme.Features() {
+		features[feature.Name] = feature.Supported
+	}
+	a.True(features["Device Self-test"])
+	a.False(features["Namespace Management"])
+
+	a.Equal(uint8(NVMeAdminIdentify), transport.cmds[0].Opcode)
+	a.Equal(uint32(cnsController), transport.cmds[0].CDW10)
+	a.Equal(uint32(cnsActiveNamespaces), transport.cmds[1].CDW10)
+	a.Equal(uint32(1), transport.cmds[2].NSID)
+
+	// controllers without the active namespace list
+	transport = newFakeNVMeTransport(
+		fakeAdminResponse{data: sampleNVMeController()},
+		fakeAdminResponse{status: NVMeStatus(0x4002)},
+		fakeAdminResponse{data: sampleNVMeNamespace()},
+		fakeAdminResponse{data: make([]byte, nvmeSmartLogSize)},
+		fakeAdminResponse{data: sampleNVMeFirmwareSlotLog()},
+	)
+
+	nvme = newNVMeDev("/dev/nvme0")
+	nvme.transport = transport
+
+	a.NoError(nvme.ScanSMART())
+	a.Len(nvme.Namespaces(), 1)
+}
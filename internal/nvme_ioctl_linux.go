@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"runtime"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// _IOWR('N', 0x41, struct nvme_admin_cmd) in linux/include/uapi/linux/nvme_ioctl.h
+	nvmeIoctlAdminCmd = 0xC0484E41
+)
+
+// struct nvme_passthru_cmd in linux/include/uapi/linux/nvme_ioctl.h
+type nvmePassthruCmd struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMs   uint32
+	result      uint32
+}
+
+type nvmeIoctlTransport struct {
+	fd      int
+	timeout time.Duration
+}
+
+func OpenNVMeTransport(path string) (NVMeTransport, error) {
+	fd, err := unix.Open(path, unix.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nvmeIoctlTransport{fd: fd, timeout: defaultTimeout}, nil
+}
+
+func (t *nvmeIoctlTransport) AdminCommand(cmd *NVMeAdminCmd) (uint32, error) {
+	if t.fd < 0 {
+		return 0, ErrNotOpened
+	}
+
+	passthru := nvmePassthruCmd{
+		opcode:    cmd.Opcode,
+		flags:     cmd.Flags,
+		nsid:      cmd.NSID,
+		cdw2:      cmd.CDW2,
+		cdw3:      cmd.CDW3,
+		cdw10:     cmd.CDW10,
+		cdw11:     cmd.CDW11,
+		cdw12:     cmd.CDW12,
+		cdw13:     cmd.CDW13,
+		cdw14:     cmd.CDW14,
+		cdw15:     cmd.CDW15,
+		timeoutMs: uint32(t.timeout / time.Millisecond),
+	}
+
+	if cmd.Timeout > 0 {
+		passthru.timeoutMs = uint32(cmd.Timeout / time.Millisecond)
+	}
+
+	if len(cmd.Data) > 0 {
+		passthru.addr = uint64(uintptr(unsafe.Pointer(&cmd.Data[0])))
+		passthru.dataLen = uint32(len(cmd.Data))
+	}
+
+	status, err := IoCtlResult(uintptr(t.fd), nvmeIoctlAdminCmd, uintptr(unsafe.Pointer(&passthru)))
+
+	// data buffer is only referenced through addr
+	runtime.KeepAlive(cmd.Data)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if status != 0 {
+		return passthru.result, &NVMeStatusError{Opcode: cmd.Opcode, Status: NVMeStatus(status)}
+	}
+
+	return passthru.result, nil
+}
+
+func (t *nvmeIoctlTransport) Close() error {
+	if t.fd < 0 {
+		return nil
+	}
+
+	err := unix.Close(t.fd)
+	t.fd = -1
+
+	return err
+}
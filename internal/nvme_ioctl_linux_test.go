@@ -0,0 +1,15 @@
+package internal
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSizeOfNVMePassthruCmd(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(72, int(unsafe.Sizeof(nvmePassthruCmd{})))
+	a.Equal(uintptr(0x48), uintptr(nvmeIoctlAdminCmd>>16)&0x3fff)
+}
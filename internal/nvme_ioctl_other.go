@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package internal
+
+func OpenNVMeTransport(path string) (NVMeTransport, error) {
+	return nil, ErrUnsupported
+}
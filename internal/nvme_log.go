@@ -0,0 +1,395 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+const (
+	// NVM Express 1.4 (Figure 191 - Log Page Identifiers)
+	nvmeLogError     = 0x01
+	nvmeLogSmart     = 0x02
+	nvmeLogFwSlot    = 0x03
+	nvmeLogSelfTest  = 0x06
+	nvmeLogPageAlign = 4
+
+	nvmeSmartLogSize = 512
+	nvmeErrorLogSize = 64
+	nvmeFwSlotSize   = 512
+
+	// Active Firmware Info
+	afiActiveMASK = uint8(0x07)
+	afiNextPos    = 4
+	afiNextMASK   = uint8(0x07)
+
+	nvmeFwSlots       = 7
+	nvmeFwSlotOffset  = 8
+	nvmeFwRevisionLen = 8
+
+	// Critical Warning
+	CriticalSpare           = uint8(0x01)
+	CriticalTemperature     = uint8(0x02)
+	CriticalReliability     = uint8(0x04)
+	CriticalReadOnly        = uint8(0x08)
+	CriticalVolatileBackup  = uint8(0x10)
+	CriticalPersistentMedia = uint8(0x20)
+
+	nvmeTempSensors = 8
+
+	// a data unit is 1,000 units of 512 bytes
+	nvmeDataUnitBytes = 512 * 1000
+)
+
+var ErrLogPageSize = errors.New("log page size shall be a multiple of 4 bytes")
+
+// getLogPage issues Get Log Page and fills buf from the byte offset.
+func (nvme *NVMeDevice) getLogPage(lid uint8, nsid uint32, offset uint64, buf []byte) error {
+	if len(buf) == 0 || len(buf)%nvmeLogPageAlign != 0 {
+		return ErrLogPageSize
+	}
+
+	// NUMD is 0's based number of dwords
+	numd := uint32(len(buf)/nvmeLogPageAlign - 1)
+
+	cmd := &NVMeAdminCmd{
+		Opcode: NVMeAdminGetLogPage,
+		NSID:   nsid,
+		CDW10:  (numd&0xffff)<<16 | uint32(lid),
+		CDW11:  numd >> 16,
+		CDW12:  uint32(offset),
+		CDW13:  uint32(offset >> 32),
+		Data:   buf,
+	}
+
+	_, err := nvme.adminCommand(cmd)
+
+	return err
+}
+
+// NVMeSmartLog is the SMART / Health Information log page (02h).
+type NVMeSmartLog struct {
+	CriticalWarning         uint8
+	Temperature             uint16 // composite temperature in Kelvin
+	AvailableSpare          uint8  // percent
+	AvailableSpareThreshold uint8  // percent
+	PercentageUsed          uint8
+	EnduranceGroupWarning   uint8
+	DataUnitsRead           Uint128
+	DataUnitsWritten        Uint128
+	HostReadCommands        Uint128
+	HostWriteCommands       Uint128
+	ControllerBusyTime      Uint128 // minutes
+	PowerCycles             Uint128
+	PowerOnHours            Uint128
+	UnsafeShutdowns         Uint128
+	MediaErrors             Uint128
+	ErrorLogEntries         Uint128
+	WarningTempTime         uint32 // minutes
+	CriticalTempTime        uint32 // minutes
+	TempSensors             [nvmeTempSensors]uint16
+	ThermalMgmtTemp1Count   uint32
+	ThermalMgmtTemp2Count   uint32
+	ThermalMgmtTemp1Time    uint32 // seconds
+	ThermalMgmtTemp2Time    uint32 // seconds
+}
+
+// TemperatureCelsius converts the composite temperature.
+func (log *NVMeSmartLog) TemperatureCelsius() int {
+	return kelvinToCelsius(log.Temperature)
+}
+
+// SensorsCelsius lists the implemented temperature sensors in Celsius, the
+// sensor reporting 0 is not implemented.
+func (log *NVMeSmartLog) SensorsCelsius() []int {
+	sensors := make([]int, 0, nvmeTempSensors)
+
+	for _, kelvin := range log.TempSensors {
+		if kelvin != 0 {
+			sensors = append(sensors, kelvinToCelsius(kelvin))
+		}
+	}
+
+	return sensors
+}
+
+func (log *NVMeSmartLog) Warning(mask uint8) bool {
+	return log.CriticalWarning&mask != 0
+}
+
+func (log *NVMeSmartLog) BytesRead() Uint128 {
+	return log.DataUnitsRead.mul64(nvmeDataUnitBytes)
+}
+
+func (log *NVMeSmartLog) BytesWritten() Uint128 {
+	return log.DataUnitsWritten.mul64(nvmeDataUnitBytes)
+}
+
+// NVM Express 1.4 (Figure 194 - SMART / Health Information Log Page)
+func parseNVMeSmartLog(buf []byte) *NVMeSmartLog {
+	log := &NVMeSmartLog{
+		CriticalWarning:         buf[0],
+		Temperature:             binary.LittleEndian.Uint16(buf[1:3]),
+		AvailableSpare:          buf[3],
+		AvailableSpareThreshold: buf[4],
+		PercentageUsed:          buf[5],
+		EnduranceGroupWarning:   buf[6],
+		DataUnitsRead:           le128(buf[32:48]),
+		DataUnitsWritten:        le128(buf[48:64]),
+		HostReadCommands:        le128(buf[64:80]),
+		HostWriteCommands:       le128(buf[80:96]),
+		ControllerBusyTime:      le128(buf[96:112]),
+		PowerCycles:             le128(buf[112:128]),
+		PowerOnHours:            le128(buf[128:144]),
+		UnsafeShutdowns:         le128(buf[144:160]),
+		MediaErrors:             le128(buf[160:176]),
+		ErrorLogEntries:         le128(buf[176:192]),
+		WarningTempTime:         binary.LittleEndian.Uint32(buf[192:196]),
+		CriticalTempTime:        binary.LittleEndian.Uint32(buf[196:200]),
+		ThermalMgmtTemp1Count:   binary.LittleEndian.Uint32(buf[216:220]),
+		ThermalMgmtTemp2Count:   binary.LittleEndian.Uint32(buf[220:224]),
+		ThermalMgmtTemp1Time:    binary.LittleEndian.Uint32(buf[224:228]),
+		ThermalMgmtTemp2Time:    binary.LittleEndian.Uint32(buf[228:232]),
+	}
+
+	for i := range log.TempSensors {
+		log.TempSensors[i] = binary.LittleEndian.Uint16(buf[200+i*2:])
+	}
+
+	return log
+}
+
+// ReadSmartLog reads the SMART / Health Information log of the namespace,
+// or the controller wide log with NVMeNSIDAll. The per namespace log is
+// available when the controller reports LPASmartPerNamespace.
+func (nvme *NVMeDevice) ReadSmartLog(nsid uint32) (*NVMeSmartLog, error) {
+	buf := make([]byte, nvmeSmartLogSize)
+
+	if err := nvme.getLogPage(nvmeLogSmart, nsid, 0, buf); err != nil {
+		return nil, err
+	}
+
+	return parseNVMeSmartLog(buf), nil
+}
+
+// HealthStatus reads the controller wide SMART / Health Information log, and
+// fails if any critical warning is reported.
+func (nvme *NVMeDevice) HealthStatus() (HealthStatus, error) {
+	smartLog, err := nvme.ReadSmartLog(NVMeNSIDAll)
+	if err != nil {
+		return HealthUnknown, err
+	}
+
+	nvme.smartLog = smartLog
+
+	if smartLog.CriticalWarning != 0 {
+		return HealthFailed, nil
+	}
+
+	return HealthPassed, nil
+}
+
+// SmartLog returns the controller wide log of the last ScanSMART.
+func (nvme *NVMeDevice) SmartLog() *NVMeSmartLog {
+	return nvme.smartLog
+}
+
+// NVMeErrorEntry is an entry of the Error Information log page (01h).
+type NVMeErrorEntry struct {
+	ErrorCount         uint64 // unique identifier of the error, 0 is an empty entry
+	SQID               uint16
+	CommandID          uint16
+	Phase              bool
+	Status             NVMeStatus
+	ParamErrorLocation uint16
+	LBA                uint64
+	NSID               uint32
+	VendorLogPage      uint8 // log page of the vendor specific information, 0 if not available
+	TransportType      uint8
+	CommandInfo        uint64 // command specific information
+	TransportInfo      uint16 // transport type specific information
+}
+
+// ParamErrorValid reports the parameter error location is applicable to the
+// error, FFFFh is not applicable.
+func (entry *NVMeErrorEntry) ParamErrorValid() bool {
+	return entry.ParamErrorLocation != 0xffff
+}
+
+// ParamErrorByte is the byte in the command of the error parameter.
+func (entry *NVMeErrorEntry) ParamErrorByte() uint8 {
+	return uint8(entry.ParamErrorLocation)
+}
+
+// ParamErrorBit is the bit in the byte of the error parameter.
+func (entry *NVMeErrorEntry) ParamErrorBit() uint8 {
+	return uint8(entry.ParamErrorLocation>>8) & 0x7
+}
+
+func (entry *NVMeErrorEntry) StatusName() string {
+	return entry.Status.Name()
+}
+
+// NVM Express 1.4 (Figure 193 - Error Information Log Entry Data Structure)
+func parseNVMeErrorEntry(buf []byte) NVMeErrorEntry {
+	status := binary.LittleEndian.Uint16(buf[12:14])
+
+	return NVMeErrorEntry{
+		ErrorCount:         binary.LittleEndian.Uint64(buf[0:8]),
+		SQID:               binary.LittleEndian.Uint16(buf[8:10]),
+		CommandID:          binary.LittleEndian.Uint16(buf[10:12]),
+		Phase:              status&0x1 != 0,
+		Status:             NVMeStatus(status >> 1),
+		ParamErrorLocation: binary.LittleEndian.Uint16(buf[14:16]),
+		LBA:                binary.LittleEndian.Uint64(buf[16:24]),
+		NSID:               binary.LittleEndian.Uint32(buf[24:28]),
+		VendorLogPage:      buf[28],
+		TransportType:      buf[29],
+		CommandInfo:        binary.LittleEndian.Uint64(buf[32:40]),
+		TransportInfo:      binary.LittleEndian.Uint16(buf[40:42]),
+	}
+}
+
+// parseNVMeErrorLog decodes the entries skipping the empty ones, and sorts
+// them by the error count from the newest.
+func parseNVMeErrorLog(buf []byte) []NVMeErrorEntry {
+	entries := make([]NVMeErrorEntry, 0)
+
+	for pos := 0; pos+nvmeErrorLogSize <= len(buf); pos += nvmeErrorLogSize {
+		entry := parseNVMeErrorEntry(buf[pos : pos+nvmeErrorLogSize])
+		if entry.ErrorCount == 0 {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ErrorCount > entries[j].ErrorCount
+	})
+
+	return entries
+}
+
+// ReadErrorLog reads all Error Information log entries advertised by the
+// Identify Controller ELPE field, and returns the valid entries newest first.
+func (nvme *NVMeDevice) ReadErrorLog() ([]NVMeErrorEntry, error) {
+	if nvme.controller == nil {
+		if _, err := nvme.IdentifyController(); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := make([]byte, nvme.controller.ErrorLogEntries()*nvmeErrorLogSize)
+
+	if err := nvme.getLogPage(nvmeLogError, NVMeNSIDAll, 0, buf); err != nil {
+		return nil, err
+	}
+
+	return parseNVMeErrorLog(buf), nil
+}
+
+// NVMeFirmwareSlotLog is the Firmware Slot Information log page (03h).
+type NVMeFirmwareSlotLog struct {
+	ActiveSlot uint8 // slot of the running firmware
+	NextSlot   uint8 // slot activated at the next reset, 0 if not indicated
+	Revisions  [nvmeFwSlots]string
+}
+
+// NVM Express 1.4 (Figure 195 - Firmware Slot Information Log)
+func parseNVMeFirmwareSlotLog(buf []byte) *NVMeFirmwareSlotLog {
+	log := &NVMeFirmwareSlotLog{
+		ActiveSlot: buf[0] & afiActiveMASK,
+		NextSlot:   (buf[0] >> afiNextPos) & afiNextMASK,
+	}
+
+	for i := range log.Revisions {
+		pos := nvmeFwSlotOffset + i*nvmeFwRevisionLen
+		log.Revisions[i] = nvmeString(buf[pos : pos+nvmeFwRevisionLen])
+	}
+
+	return log
+}
+
+// ReadFirmwareSlotLog reads the Firmware Slot Information log.
+func (nvme *NVMeDevice) ReadFirmwareSlotLog() (*NVMeFirmwareSlotLog, error) {
+	buf := make([]byte, nvmeFwSlotSize)
+
+	if err := nvme.getLogPage(nvmeLogFwSlot, NVMeNSIDAll, 0, buf); err != nil {
+		return nil, err
+	}
+
+	return parseNVMeFirmwareSlotLog(buf), nil
+}
+
+type NVMeFirmwareSlot struct {
+	Slot       uint8
+	Revision   string // empty if no firmware is stored in the slot
+	ReadOnly   bool
+	Active     bool
+	NextActive bool
+}
+
+// NVMeFirmwareInventory is the Firmware Slot Information log combined with
+// the firmware update capabilities of the controller.
+type NVMeFirmwareInventory struct {
+	ActiveSlot           uint8
+	NextSlot             uint8
+	ActivateWithoutReset bool
+	Slots                []NVMeFirmwareSlot
+}
+
+// Active returns the slot of the running firmware, or nil if the log does
+// not report the valid slot.
+func (inv *NVMeFirmwareInventory) Active() *NVMeFirmwareSlot {
+	for i := range inv.Slots {
+		if inv.Slots[i].Active {
+			return &inv.Slots[i]
+		}
+	}
+
+	return nil
+}
+
+func makeNVMeFirmwareInventory(ctrl *NVMeController, log *NVMeFirmwareSlotLog) *NVMeFirmwareInventory {
+	inv := &NVMeFirmwareInventory{
+		ActiveSlot:           log.ActiveSlot,
+		NextSlot:             log.NextSlot,
+		ActivateWithoutReset: ctrl.ActivateWithoutReset,
+		Slots:                make([]NVMeFirmwareSlot, 0, ctrl.FirmwareSlots),
+	}
+
+	for slot := uint8(1); slot <= ctrl.FirmwareSlots && slot <= nvmeFwSlots; slot++ {
+		inv.Slots = append(inv.Slots, NVMeFirmwareSlot{
+			Slot:       slot,
+			Revision:   log.Revisions[slot-1],
+			ReadOnly:   slot == 1 && ctrl.Slot1ReadOnly,
+			Active:     slot == log.ActiveSlot,
+			NextActive: slot == log.NextSlot,
+		})
+	}
+
+	return inv
+}
+
+// FirmwareInventory reads the Firmware Slot Information log and lists the
+// slots supported by the controller.
+func (nvme *NVMeDevice) FirmwareInventory() (*NVMeFirmwareInventory, error) {
+	if nvme.controller == nil {
+		if _, err := nvme.IdentifyController(); err != nil {
+			return nil, err
+		}
+	}
+
+	log, err := nvme.ReadFirmwareSlotLog()
+	if err != nil {
+		return nil, err
+	}
+
+	return makeNVMeFirmwareInventory(nvme.controller, log), nil
+}
+
+// Firmwares returns the firmware inventory of the last ScanSMART.
+func (nvme *NVMeDevice) Firmwares() *NVMeFirmwareInventory {
+	return nvme.firmwares
+}
@@ -0,0 +1,289 @@
+package internal
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func putUint128(buf []byte, lo, hi uint64) {
+	binary.LittleEndian.PutUint64(buf[0:], lo)
+	binary.LittleEndian.PutUint64(buf[8:], hi)
+}
+
+func sampleNVMeSmartLog() []byte {
+	buf := make([]byte, nvmeSmartLogSize)
+
+	buf[0] = CriticalSpare | CriticalTemperature
+	binary.LittleEndian.PutUint16(buf[1:], 310)
+	buf[3] = 100
+	buf[4] = 10
+	buf[5] = 3
+
+	putUint128(buf[32:], 12345678, 0)
+	putUint128(buf[48:], 1, 1)
+	putUint128(buf[64:], 200, 0)
+	putUint128(buf[80:], 300, 0)
+	putUint128(buf[96:], 400, 0)
+	putUint128(buf[112:], 52, 0)
+	putUint128(buf[128:], 1234, 0)
+	putUint128(buf[144:], 7, 0)
+	putUint128(buf[160:], 0, 0)
+	putUint128(buf[176:], 15, 0)
+
+	binary.LittleEndian.PutUint32(buf[192:], 5)
+	binary.LittleEndian.PutUint32(buf[196:], 1)
+	binary.LittleEndian.PutUint16(buf[200:], 310)
+	binary.LittleEndian.PutUint16(buf[202:], 320)
+	binary.LittleEndian.PutUint32(buf[216:], 2)
+	binary.LittleEndian.PutUint32(buf[220:], 1)
+	binary.LittleEndian.PutUint32(buf[224:], 60)
+	binary.LittleEndian.PutUint32(buf[228:], 30)
+
+	return buf
+}
+
+func TestParseNVMeSmartLog(t *testing.T) {
+	a := assert.New(t)
+
+	log := parseNVMeSmartLog(sampleNVMeSmartLog())
+
+	a.True(log.Warning(CriticalSpare))
+	a.True(log.Warning(CriticalTemperature))
+	a.False(log.Warning(CriticalReadOnly))
+	a.Equal(37, log.TemperatureCelsius())
+	a.Equal(uint8(100), log.AvailableSpare)
+	a.Equal(uint8(10), log.AvailableSpareThreshold)
+	a.Equal(uint8(3), log.PercentageUsed)
+
+	a.Equal(Uint128{Lo: 12345678}, log.DataUnitsRead)
+	a.Equal(Uint128{Lo: 1, Hi: 1}, log.DataUnitsWritten)
+	a.Equal(uint64(200), log.HostReadCommands.Uint64())
+	a.Equal(uint64(300), log.HostWriteCommands.Uint64())
+	a.Equal(uint64(400), log.ControllerBusyTime.Uint64())
+	a.Equal(uint64(52), log.PowerCycles.Uint64())
+	a.Equal(uint64(1234), log.PowerOnHours.Uint64())
+	a.Equal(uint64(7), log.UnsafeShutdowns.Uint64())
+	a.Equal(uint64(0), log.MediaErrors.Uint64())
+	a.Equal(uint64(15), log.ErrorLogEntries.Uint64())
+
+	a.Equal(uint32(5), log.WarningTempTime)
+	a.Equal(uint32(1), log.CriticalTempTime)
+	a.Equal([]int{37, 47}, log.SensorsCelsius())
+	a.Equal(uint32(2), log.ThermalMgmtTemp1Count)
+	a.Equal(uint32(1), log.ThermalMgmtTemp2Count)
+	a.Equal(uint32(60), log.ThermalMgmtTemp1Time)
+	a.Equal(uint32(30), log.ThermalMgmtTemp2Time)
+
+	a.Equal("6320987136000", log.BytesRead().String())
+	a.Equal("9444732965739290427904000", log.BytesWritten().String())
+}
+
+func TestNVMeReadSmartLog(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeNVMeTransport(
+		fakeAdminResponse{data: sampleNVMeSmartLog()},
+		fakeAdminResponse{data: sampleNVMeSmartLog()},
+		fakeAdminResponse{status: NVMeStatus(0x4109)},
+	)
+
+	nvme := newNVMeDev("/dev/nvme0")
+	nvme.transport = transport
+
+	// controller wide log
+	log, err := nvme.ReadSmartLog(NVMeNSIDAll)
+	a.NoError(err)
+	a.Equal(uint64(1234), log.PowerOnHours.Uint64())
+
+	cmd := transport.lastCmd()
+	a.Equal(uint8(NVMeAdminGetLogPage), cmd.Opcode)
+	a.Equal(uint32(NVMeNSIDAll), cmd.NSID)
+	a.Equal(uint32(127<<16|nvmeLogSmart), cmd.CDW10)
+	a.Equal(uint32(0), cmd.CDW11)
+	a.Len(cmd.Data, nvmeSmartLogSize)
+
+	// per namespace log
+	_, err = nvme.ReadSmartLog(1)
+	a.NoError(err)
+	a.Equal(uint32(1), transport.lastCmd().NSID)
+
+	// invalid log page
+	_, err = nvme.ReadSmartLog(1)
+	a.Error(err)
+	a.IsType(&NVMeStatusError{}, err)
+
+	a.Equal(ErrLogPageSize, nvme.getLogPage(nvmeLogSmart, NVMeNSIDAll, 0, make([]byte, 3)))
+}
+
+func putNVMeErrorEntry(buf []byte, count uint64, status NVMeStatus, lba uint64) {
+	binary.LittleEndian.PutUint64(buf[0:], count)
+	binary.LittleEndian.PutUint16(buf[8:], 1)
+	binary.LittleEndian.PutUint16(buf[10:], uint16(count))
+	binary.LittleEndian.PutUint16(buf[12:], uint16(status)<<1|0x1)
+	binary.LittleEndian.PutUint16(buf[14:], 0x0228)
+	binary.LittleEndian.PutUint64(buf[16:], lba)
+	binary.LittleEndian.PutUint32(buf[24:], 1)
+	buf[29] = 0x1
+	binary.LittleEndian.PutUint64(buf[32:], 0xabcd)
+}
+
+func TestParseNVMeErrorLog(t *testing.T) {
+	a := assert.New(t)
+
+	buf := make([]byte, 4*nvmeErrorLogSize)
+	putNVMeErrorEntry(buf[0:], 14, NVMeStatus(0x0281), 0x1000)
+	putNVMeErrorEntry(buf[2*nvmeErrorLogSize:], 15, NVMeStatus(0x0002), 0)
+
+	entries := parseNVMeErrorLog(buf)
+	a.Len(entries, 2)
+
+	// newest first
+	a.Equal(uint64(15), entries[0].ErrorCount)
+	a.Equal("Invalid Field in Command", entries[0].StatusName())
+
+	entry := entries[1]
+	a.Equal(uint64(14), entry.ErrorCount)
+	a.Equal(uint16(1), entry.SQID)
+	a.Equal(uint16(14), entry.CommandID)
+	a.True(entry.Phase)
+	a.Equal(SctMedia, entry.Status.SCT())
+	a.Equal("Unrecovered Read Error", entry.StatusName())
+	a.True(entry.ParamErrorValid())
+	a.Equal(uint8(0x28), entry.ParamErrorByte())
+	a.Equal(uint8(2), entry.ParamErrorBit())
+	a.Equal(uint64(0x1000), entry.LBA)
+	a.Equal(uint32(1), entry.NSID)
+	a.Equal(uint8(0), entry.VendorLogPage)
+	a.Equal(uint8(1), entry.TransportType)
+	a.Equal(uint64(0xabcd), entry.CommandInfo)
+
+	binary.LittleEndian.PutUint16(buf[14:], 0xffff)
+	a.False(parseNVMeErrorLog(buf)[1].ParamErrorValid())
+
+	a.Empty(parseNVMeErrorLog(make([]byte, nvmeErrorLogSize)))
+}
+
+func TestNVMeReadErrorLog(t *testing.T) {
+	a := assert.New(t)
+
+	ctrl := sampleNVMeController()
+	ctrl[262] = 3
+
+	logs := make([]byte, 4*nvmeErrorLogSize)
+	putNVMeErrorEntry(logs[nvmeErrorLogSize:], 3, NVMeStatus(0x0002), 0)
+
+	transport := newFakeNVMeTransport(
+		fakeAdminResponse{data: ctrl},
+		fakeAdminResponse{data: logs},
+	)
+
+	nvme := newNVMeDev("/dev/nvme0")
+	nvme.transport = transport
+
+	entries, err := nvme.ReadErrorLog()
+	a.NoError(err)
+	a.Len(entries, 1)
+
+	cmd := transport.lastCmd()
+	a.Equal(uint8(NVMeAdminGetLogPage), cmd.Opcode)
+	a.Equal(uint32(63<<16|nvmeLogError), cmd.CDW10)
+	a.Len(cmd.Data, 4*nvmeErrorLogSize)
+
+	// identify failure
+	nvme = newNVMeDev("/dev/nvme0")
+	nvme.transport = newFakeNVMeTransport(fakeAdminResponse{status: NVMeStatus(0x0002)})
+
+	_, err = nvme.ReadErrorLog()
+	a.Error(err)
+}
+
+func sampleNVMeFirmwareSlotLog() []byte {
+	buf := make([]byte, nvmeFwSlotSize)
+
+	buf[0] = 0x32 // active slot 2, slot 3 at the next reset
+	padString(buf[8:16], "1B2QEXM7")
+	padString(buf[16:24], "2B2QEXM7")
+	padString(buf[24:32], "3B2QEXM7")
+
+	return buf
+}
+
+func TestParseNVMeFirmwareSlotLog(t *testing.T) {
+	a := assert.New(t)
+
+	log := parseNVMeFirmwareSlotLog(sampleNVMeFirmwareSlotLog())
+
+	a.Equal(uint8(2), log.ActiveSlot)
+	a.Equal(uint8(3), log.NextSlot)
+	a.Equal("1B2QEXM7", log.Revisions[0])
+	a.Equal("3B2QEXM7", log.Revisions[2])
+	a.Equal("", log.Revisions[3])
+}
+
+func TestNVMeFirmwareInventory(t *testing.T) {
+	a := assert.New(t)
+
+	ctrl := sampleNVMeController()
+	ctrl[260] |= frmwSlot1ReadOnly
+
+	transport := newFakeNVMeTransport(
+		fakeAdminResponse{data: ctrl},
+		fakeAdminResponse{data: sampleNVMeFirmwareSlotLog()},
+	)
+
+	nvme := newNVMeDev("/dev/nvme0")
+	nvme.transport = transport
+
+	inv, err := nvme.FirmwareInventory()
+	a.NoError(err)
+
+	cmd := transport.lastCmd()
+	a.Equal(uint8(NVMeAdminGetLogPage), cmd.Opcode)
+	a.Equal(uint32(127<<16|nvmeLogFwSlot), cmd.CDW10)
+
+	a.Equal(uint8(2), inv.ActiveSlot)
+	a.Equal(uint8(3), inv.NextSlot)
+	a.True(inv.ActivateWithoutReset)
+	a.Len(inv.Slots, 3)
+
+	a.Equal(NVMeFirmwareSlot{Slot: 1, Revision: "1B2QEXM7", ReadOnly: true}, inv.Slots[0])
+	a.Equal(NVMeFirmwareSlot{Slot: 2, Revision: "2B2QEXM7", Active: true}, inv.Slots[1])
+	a.Equal(NVMeFirmwareSlot{Slot: 3, Revision: "3B2QEXM7", NextActive: true}, inv.Slots[2])
+	a.Equal(uint8(2), inv.Active().Slot)
+
+	// log without the valid active slot
+	inv = makeNVMeFirmwareInventory(nvme.Controller(), &NVMeFirmwareSlotLog{})
+	a.Nil(inv.Active())
+}
+
+func TestNVMeHealthStatus(t *testing.T) {
+	a := assert.New(t)
+
+	warning := sampleNVMeSmartLog()
+	warning[0] = CriticalSpare
+
+	transport := newFakeNVMeTransport(
+		fakeAdminResponse{data: make([]byte, nvmeSmartLogSize)},
+		fakeAdminResponse{data: warning},
+		fakeAdminResponse{status: NVMeStatus(0x0006)},
+	)
+
+	nvme := newNVMeDev("/dev/nvme0")
+	nvme.transport = transport
+
+	health, err := nvme.HealthStatus()
+	a.NoError(err)
+	a.Equal(HealthPassed, health)
+	a.Equal(uint32(NVMeNSIDAll), transport.lastCmd().NSID)
+
+	health, err = nvme.HealthStatus()
+	a.NoError(err)
+	a.Equal(HealthFailed, health)
+	a.Equal(CriticalSpare, nvme.SmartLog().CriticalWarning)
+
+	health, err = nvme.HealthStatus()
+	a.Error(err)
+	a.Equal(HealthUnknown, health)
+}
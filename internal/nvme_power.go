@@ -0,0 +1,49 @@
+package internal
+
+const (
+	// NVM Express 1.4 (Figure 271 - Feature Identifiers)
+	nvmeFeaturePowerManagement = uint8(0x02)
+
+	nvmePowerStateMASK = uint32(0x1f)
+)
+
+// PowerState reads the current power state by Get Features of Power
+// Management.
+func (nvme *NVMeDevice) PowerState() (int, error) {
+	result, err := nvme.adminCommand(&NVMeAdminCmd{
+		Opcode: NVMeAdminGetFeatures,
+		CDW10:  uint32(nvmeFeaturePowerManagement),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(result & nvmePowerStateMASK), nil
+}
+
+// PowerMode maps the current power state to the power mode. The power state
+// 0 is ACTIVE, the other operational states are IDLE and the non-operational
+// states are STANDBY.
+func (nvme *NVMeDevice) PowerMode() (PowerMode, error) {
+	if nvme.controller == nil {
+		if _, err := nvme.IdentifyController(); err != nil {
+			return PowerModeUnknown, err
+		}
+	}
+
+	ps, err := nvme.PowerState()
+	if err != nil {
+		return PowerModeUnknown, err
+	}
+
+	switch states := nvme.controller.PowerStates; {
+	case ps == 0:
+		return PowerModeActive, nil
+	case ps >= len(states):
+		return PowerModeUnknown, nil
+	case states[ps].NonOperational:
+		return PowerModeStandby, nil
+	}
+
+	return PowerModeIdle, nil
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNVMePowerMode(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeNVMeTransport(
+		fakeAdminResponse{data: sampleNVMeController()},
+		fakeAdminResponse{result: 0},
+		fakeAdminResponse{result: 2},
+		fakeAdminResponse{result: 4},
+		fakeAdminResponse{result: 7},
+	)
+
+	nvme := newNVMeDev("/dev/nvme0")
+	nvme.transport = transport
+
+	mode, err := nvme.PowerMode()
+	a.NoError(err)
+	a.Equal(PowerModeActive, mode)
+
+	cmd := transport.lastCmd()
+	a.Equal(uint8(NVMeAdminGetFeatures), cmd.Opcode)
+	a.Equal(uint32(nvmeFeaturePowerManagement), cmd.CDW10)
+
+	mode, err = nvme.PowerMode()
+	a.NoError(err)
+	a.Equal(PowerModeIdle, mode)
+
+	// power state 4 is non-operational
+	mode, err = nvme.PowerMode()
+	a.NoError(err)
+	a.Equal(PowerModeStandby, mode)
+
+	// out of the power state descriptors
+	mode, err = nvme.PowerMode()
+	a.NoError(err)
+	a.Equal(PowerModeUnknown, mode)
+	a.Len(transport.cmds, 5)
+}
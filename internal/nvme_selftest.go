@@ -0,0 +1,269 @@
+package internal
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+const (
+	nvmeSelfTestLogSize    = 564
+	nvmeSelfTestResults    = 20
+	nvmeSelfTestOffset     = 4
+	nvmeSelfTestResultSize = 28
+
+	nvmeSelfTestCodeMASK    = uint8(0x0f)
+	nvmeSelfTestPercentMASK = uint8(0x7f)
+	nvmeSelfTestCodePos     = 4
+
+	// Valid Diagnostic Information
+	selfTestNSIDValid = uint8(0x01)
+	selfTestFLBAValid = uint8(0x02)
+	selfTestSCTValid  = uint8(0x04)
+	selfTestSCValid   = uint8(0x08)
+
+	defaultSelfTestPoll = 10 * time.Second
+)
+
+var (
+	ErrSelfTestUnsupported = errors.New("device does not support self-test")
+	ErrInvalidSelfTest     = errors.New("invalid self-test code")
+)
+
+// NVMeSelfTestCode is the Self-test Code of the Device Self-test command.
+type NVMeSelfTestCode uint8
+
+const (
+	NVMeSelfTestNone     = NVMeSelfTestCode(0x0)
+	NVMeSelfTestShort    = NVMeSelfTestCode(0x1)
+	NVMeSelfTestExtended = NVMeSelfTestCode(0x2)
+	NVMeSelfTestVendor   = NVMeSelfTestCode(0xe)
+	NVMeSelfTestAbort    = NVMeSelfTestCode(0xf)
+)
+
+func (code NVMeSelfTestCode) String() string {
+	switch code {
+	case NVMeSelfTestNone:
+		return "None"
+	case NVMeSelfTestShort:
+		return "Short"
+	case NVMeSelfTestExtended:
+		return "Extended"
+	case NVMeSelfTestVendor:
+		return "Vendor Specific"
+	case NVMeSelfTestAbort:
+		return "Abort"
+	}
+
+	return "Reserved"
+}
+
+// NVMeSelfTestResultCode is the result of the Self-test Result Data.
+type NVMeSelfTestResultCode uint8
+
+const (
+	NVMeSelfTestCompleted = NVMeSelfTestResultCode(iota)
+	NVMeSelfTestAborted
+	NVMeSelfTestAbortedReset
+	NVMeSelfTestAbortedNamespace
+	NVMeSelfTestAbortedFormat
+	NVMeSelfTestFatal
+	NVMeSelfTestFailedUnknown
+	NVMeSelfTestFailedSegment
+	NVMeSelfTestAbortedUnknown
+	NVMeSelfTestAbortedSanitize
+
+	NVMeSelfTestUnused = NVMeSelfTestResultCode(0xf)
+)
+
+// NVM Express 1.4 (Figure 206 - Self-test Result Data Structure)
+var nvmeSelfTestResultNames = map[NVMeSelfTestResultCode]string{
+	NVMeSelfTestCompleted:        "Completed without error",
+	NVMeSelfTestAborted:          "Aborted by a Device Self-test command",
+	NVMeSelfTestAbortedReset:     "Aborted by a Controller Level Reset",
+	NVMeSelfTestAbortedNamespace: "Aborted due to a removal of a namespace",
+	NVMeSelfTestAbortedFormat:    "Aborted due to the processing of a Format NVM command",
+	NVMeSelfTestFatal:            "Fatal or unknown test error",
+	NVMeSelfTestFailedUnknown:    "Completed with a failed segment, the failed segment is unknown",
+	NVMeSelfTestFailedSegment:    "Completed with one or more failed segments",
+	NVMeSelfTestAbortedUnknown:   "Aborted for unknown reason",
+	NVMeSelfTestAbortedSanitize:  "Aborted due to a sanitize operation",
+	NVMeSelfTestUnused:           "Entry not used",
+}
+
+func (code NVMeSelfTestResultCode) String() string {
+	if name, ok := nvmeSelfTestResultNames[code]; ok {
+		return name
+	}
+
+	return "Reserved"
+}
+
+// Failed reports the test completed and found the error.
+func (code NVMeSelfTestResultCode) Failed() bool {
+	switch code {
+	case NVMeSelfTestFatal, NVMeSelfTestFailedUnknown, NVMeSelfTestFailedSegment:
+		return true
+	}
+
+	return false
+}
+
+type NVMeSelfTestResult struct {
+	Code        NVMeSelfTestCode
+	Result      NVMeSelfTestResultCode
+	Segment     uint8 // first failed segment, 0 if no segment failed
+	PowerOnHour uint64
+	NSID        uint32
+	FailingLBA  uint64
+	Status      NVMeStatus
+
+	valid uint8
+}
+
+// NSIDValid reports NSID has the namespace of the failure.
+func (result *NVMeSelfTestResult) NSIDValid() bool {
+	return result.valid&selfTestNSIDValid != 0
+}
+
+// FailingLBAValid reports FailingLBA has the first failed LBA.
+func (result *NVMeSelfTestResult) FailingLBAValid() bool {
+	return result.valid&selfTestFLBAValid != 0
+}
+
+// StatusValid reports Status has the status code of the failed command.
+func (result *NVMeSelfTestResult) StatusValid() bool {
+	return result.valid&(selfTestSCTValid|selfTestSCValid) == selfTestSCTValid|selfTestSCValid
+}
+
+// NVMeSelfTestLog is the Device Self-test log page (06h).
+type NVMeSelfTestLog struct {
+	Current    NVMeSelfTestCode // running test, NVMeSelfTestNone if no test is in progress
+	Completion uint8            // percent of the running test
+	Results    []NVMeSelfTestResult
+}
+
+func (log *NVMeSelfTestLog) InProgress() bool {
+	return log.Current != NVMeSelfTestNone
+}
+
+// NVM Express 1.4 (Figure 205 - Device Self-test Log)
+func parseNVMeSelfTestLog(buf []byte) *NVMeSelfTestLog {
+	log := &NVMeSelfTestLog{
+		Current:    NVMeSelfTestCode(buf[0] & nvmeSelfTestCodeMASK),
+		Completion: buf[1] & nvmeSelfTestPercentMASK,
+		Results:    make([]NVMeSelfTestResult, 0, nvmeSelfTestResults),
+	}
+
+	// results are already sorted from the newest
+	for i := 0; i < nvmeSelfTestResults; i++ {
+		pos := nvmeSelfTestOffset + i*nvmeSelfTestResultSize
+		entry := buf[pos : pos+nvmeSelfTestResultSize]
+
+		result := NVMeSelfTestResult{
+			Code:        NVMeSelfTestCode(entry[0] >> nvmeSelfTestCodePos),
+			Result:      NVMeSelfTestResultCode(entry[0] & nvmeSelfTestCodeMASK),
+			Segment:     entry[1],
+			PowerOnHour: binary.LittleEndian.Uint64(entry[4:12]),
+			NSID:        binary.LittleEndian.Uint32(entry[12:16]),
+			FailingLBA:  binary.LittleEndian.Uint64(entry[16:24]),
+			Status:      NVMeStatus(uint16(entry[24]&uint8(nvmeStatusSCTMASK))<<nvmeStatusSCTPos | uint16(entry[25])),
+			valid:       entry[2],
+		}
+
+		if result.Result == NVMeSelfTestUnused {
+			continue
+		}
+
+		log.Results = append(log.Results, result)
+	}
+
+	return log
+}
+
+// ReadSelfTestLog reads the Device Self-test log.
+func (nvme *NVMeDevice) ReadSelfTestLog() (*NVMeSelfTestLog, error) {
+	buf := make([]byte, nvmeSelfTestLogSize)
+
+	if err := nvme.getLogPage(nvmeLogSelfTest, NVMeNSIDAll, 0, buf); err != nil {
+		return nil, err
+	}
+
+	return parseNVMeSelfTestLog(buf), nil
+}
+
+func (nvme *NVMeDevice) selfTest(nsid uint32, code NVMeSelfTestCode) error {
+	if nvme.controller == nil {
+		if _, err := nvme.IdentifyController(); err != nil {
+			return err
+		}
+	}
+
+	if !nvme.controller.SupportsAdmin(OACSSelfTest) {
+		return ErrSelfTestUnsupported
+	}
+
+	_, err := nvme.adminCommand(&NVMeAdminCmd{
+		Opcode: NVMeAdminDevSelfTest,
+		NSID:   nsid,
+		CDW10:  uint32(code),
+	})
+
+	return err
+}
+
+// StartSelfTest starts the self-test on the namespace, or on the controller
+// and all namespaces with NVMeNSIDAll.
+func (nvme *NVMeDevice) StartSelfTest(nsid uint32, code NVMeSelfTestCode) error {
+	if code != NVMeSelfTestShort && code != NVMeSelfTestExtended && code != NVMeSelfTestVendor {
+		return ErrInvalidSelfTest
+	}
+
+	return nvme.selfTest(nsid, code)
+}
+
+// AbortSelfTest aborts the running self-test.
+func (nvme *NVMeDevice) AbortSelfTest() error {
+	return nvme.selfTest(NVMeNSIDAll, NVMeSelfTestAbort)
+}
+
+// WaitSelfTest polls the Device Self-test log by the interval, from one
+// interval after the call, until the running test finishes or ctx is done,
+// and returns the newest result.
+// progress is called with every polled log if not nil.
+func (nvme *NVMeDevice) WaitSelfTest(ctx context.Context, interval time.Duration, progress func(*NVMeSelfTestLog)) (*NVMeSelfTestResult, error) {
+	if interval <= 0 {
+		interval = defaultSelfTestPoll
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// the first poll waits the interval too, the log read just after
+		// StartSelfTest may not have the test in progress yet
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		log, err := nvme.ReadSelfTestLog()
+		if err != nil {
+			return nil, err
+		}
+
+		if progress != nil {
+			progress(log)
+		}
+
+		if !log.InProgress() {
+			if len(log.Results) == 0 {
+				return nil, nil
+			}
+
+			return &log.Results[0], nil
+		}
+	}
+}
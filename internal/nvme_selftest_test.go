@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleNVMeSelfTestLog(current NVMeSelfTestCode, completion uint8) []byte {
+	buf := make([]byte, nvmeSelfTestLogSize)
+
+	buf[0] = uint8(current)
+	buf[1] = completion
+
+	for i := 0; i < nvmeSelfTestResults; i++ {
+		buf[nvmeSelfTestOffset+i*nvmeSelfTestResultSize] = uint8(NVMeSelfTestUnused)
+	}
+
+	// newest: extended test failed at segment 2
+	entry := buf[nvmeSelfTestOffset:]
+	entry[0] = uint8(NVMeSelfTestExtended)<<4 | uint8(NVMeSelfTestFailedSegment)
+	entry[1] = 2
+	entry[2] = selfTestNSIDValid | selfTestFLBAValid | selfTestSCTValid | selfTestSCValid
+	binary.LittleEndian.PutUint64(entry[4:], 1234)
+	binary.LittleEndian.PutUint32(entry[12:], 1)
+	binary.LittleEndian.PutUint64(entry[16:], 0x2000)
+	entry[24] = SctMedia
+	entry[25] = 0x81
+
+	// short test completed
+	entry = buf[nvmeSelfTestOffset+nvmeSelfTestResultSize:]
+	entry[0] = uint8(NVMeSelfTestShort)<<4 | uint8(NVMeSelfTestCompleted)
+	binary.LittleEndian.PutUint64(entry[4:], 1200)
+
+	return buf
+}
+
+func TestParseNVMeSelfTestLog(t *testing.T) {
+	a := assert.New(t)
+
+	log := parseNVMeSelfTestLog(sampleNVMeSelfTestLog(NVMeSelfTestShort, 0x80|42))
+	a.True(log.InProgress())
+	a.Equal(NVMeSelfTestShort, log.Current)
+	a.Equal(uint8(42), log.Completion)
+	a.Len(log.Results, 2)
+
+	result := log.Results[0]
+	a.Equal(NVMeSelfTestExtended, result.Code)
+	a.Equal("Extended", result.Code.String())
+	a.Equal(NVMeSelfTestFailedSegment, result.Result)
+	a.True(result.Result.Failed())
+	a.Equal(uint8(2), result.Segment)
+	a.Equal(uint64(1234), result.PowerOnHour)
+	a.True(result.NSIDValid())
+	a.Equal(uint32(1), result.NSID)
+	a.True(result.FailingLBAValid())
+	a.Equal(uint64(0x2000), result.FailingLBA)
+	a.True(result.StatusValid())
+	a.Equal("Unrecovered Read Error", result.Status.Name())
+
+	result = log.Results[1]
+	a.Equal(NVMeSelfTestShort, result.Code)
+	a.Equal("Completed without error", result.Result.String())
+	a.False(result.Result.Failed())
+	a.False(result.NSIDValid())
+	a.False(result.FailingLBAValid())
+	a.False(result.StatusValid())
+
+	a.False(parseNVMeSelfTestLog(sampleNVMeSelfTestLog(NVMeSelfTestNone, 0)).InProgress())
+	a.Equal("Reserved", NVMeSelfTestResultCode(0xa).String())
+}
+
+func TestNVMeStartSelfTest(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeNVMeTransport(
+		fakeAdminResponse{data: sampleNVMeController()},
+		fakeAdminResponse{},
+		fakeAdminResponse{},
+	)
+
+	nvme := newNVMeDev("/dev/nvme0")
+	nvme.transport = transport
+
+	a.Equal(ErrInvalidSelfTest, nvme.StartSelfTest(1, NVMeSelfTestAbort))
+	a.Empty(transport.cmds)
+
+	a.NoError(nvme.StartSelfTest(1, NVMeSelfTestShort))
+	cmd := transport.lastCmd()
+	a.Equal(uint8(NVMeAdminDevSelfTest), cmd.Opcode)
+	a.Equal(uint32(1), cmd.NSID)
+	a.Equal(uint32(NVMeSelfTestShort), cmd.CDW10)
+
+	a.NoError(nvme.AbortSelfTest())
+	cmd = transport.lastCmd()
+	a.Equal(uint32(NVMeNSIDAll), cmd.NSID)
+	a.Equal(uint32(NVMeSelfTestAbort), cmd.CDW10)
+
+	// controller without the Device Self-test command
+	ctrl := sampleNVMeController()
+	binary.LittleEndian.PutUint16(ctrl[256:], 0x0007)
+
+	nvme = newNVMeDev("/dev/nvme0")
+	nvme.transport = newFakeNVMeTransport(fakeAdminResponse{data: ctrl})
+
+	a.Equal(ErrSelfTestUnsupported, nvme.StartSelfTest(1, NVMeSelfTestExtended))
+}
+
+func TestNVMeWaitSelfTest(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeNVMeTransport(
+		fakeAdminResponse{data: sampleNVMeSelfTestLog(NVMeSelfTestExtended, 10)},
+		fakeAdminResponse{data: sampleNVMeSelfTestLog(NVMeSelfTestExtended, 90)},
+		fakeAdminResponse{data: sampleNVMeSelfTestLog(NVMeSelfTestNone, 0)},
+	)
+
+	nvme := newNVMeDev("/dev/nvme0")
+	nvme.transport = transport
+
+	completions := make([]uint8, 0)
+	result, err := nvme.WaitSelfTest(context.Background(), time.Millisecond, func(log *NVMeSelfTestLog) {
+		completions = append(completions, log.Completion)
+	})
+
+	a.NoError(err)
+	a.Equal([]uint8{10, 90, 0}, completions)
+	a.Equal(NVMeSelfTestFailedSegment, result.Result)
+	a.Equal(uint8(nvmeLogSelfTest), uint8(transport.lastCmd().CDW10))
+
+	// cancelled while the test is running
+	transport = newFakeNVMeTransport(
+		fakeAdminResponse{data: sampleNVMeSelfTestLog(NVMeSelfTestExtended, 10)},
+		fakeAdminResponse{data: sampleNVMeSelfTestLog(NVMeSelfTestNone, 0)},
+	)
+	nvme.transport = transport
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	result, err = nvme.WaitSelfTest(ctx, 50*time.Millisecond, func(*NVMeSelfTestLog) { cancel() })
+	a.Nil(result)
+	a.Equal(context.Canceled, err)
+	a.Len(transport.cmds, 1)
+
+	// nothing is polled before the first interval
+	transport = newFakeNVMeTransport()
+	nvme.transport = transport
+
+	_, err = nvme.WaitSelfTest(ctx, time.Hour, nil)
+	a.Equal(context.Canceled, err)
+	a.Len(transport.cmds, 0)
+}
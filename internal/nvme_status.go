@@ -0,0 +1,198 @@
+package internal
+
+import "fmt"
+
+const (
+	// NVM Express 1.4 (4.6.1.2 Status Code Type)
+	SctGeneric        = uint8(0x0)
+	SctCommand        = uint8(0x1)
+	SctMedia          = uint8(0x2)
+	SctPath           = uint8(0x3)
+	SctVendorSpecific = uint8(0x7)
+
+	// status field without the phase tag
+	nvmeStatusSCMASK  = uint16(0x00ff)
+	nvmeStatusSCTPos  = 8
+	nvmeStatusSCTMASK = uint16(0x0007)
+	nvmeStatusCRDPos  = 11
+	nvmeStatusCRDMASK = uint16(0x0003)
+	nvmeStatusMore    = uint16(0x2000)
+	nvmeStatusDNR     = uint16(0x4000)
+)
+
+var nvmeStatusTypes = map[uint8]string{
+	SctGeneric:        "Generic Command Status",
+	SctCommand:        "Command Specific Status",
+	SctMedia:          "Media and Data Integrity Errors",
+	SctPath:           "Path Related Status",
+	SctVendorSpecific: "Vendor Specific",
+}
+
+// NVM Express 1.4 (Figure 127 - 130, 4.6.1.2.1 - 4.6.1.2.4)
+var nvmeStatusNames = map[uint8]map[uint8]string{
+	SctGeneric: {
+		0x00: "Successful Completion",
+		0x01: "Invalid Command Opcode",
+		0x02: "Invalid Field in Command",
+		0x03: "Command ID Conflict",
+		0x04: "Data Transfer Error",
+		0x05: "Commands Aborted due to Power Loss Notification",
+		0x06: "Internal Error",
+		0x07: "Command Abort Requested",
+		0x08: "Command Aborted due to SQ Deletion",
+		0x09: "Command Aborted due to Failed Fused Command",
+		0x0a: "Command Aborted due to Missing Fused Command",
+		0x0b: "Invalid Namespace or Format",
+		0x0c: "Command Sequence Error",
+		0x0d: "Invalid SGL Segment Descriptor",
+		0x0e: "Invalid Number of SGL Descriptors",
+		0x0f: "Data SGL Length Invalid",
+		0x10: "Metadata SGL Length Invalid",
+		0x11: "SGL Descriptor Type Invalid",
+		0x12: "Invalid Use of Controller Memory Buffer",
+		0x13: "PRP Offset Invalid",
+		0x14: "Atomic Write Unit Exceeded",
+		0x15: "Operation Denied",
+		0x16: "SGL Offset Invalid",
+		0x18: "Host Identifier Inconsistent Format",
+		0x19: "Keep Alive Timer Expired",
+		0x1a: "Keep Alive Timeout Invalid",
+		0x1b: "Command Aborted due to Preempt and Abort",
+		0x1c: "Sanitize Failed",
+		0x1d: "Sanitize In Progress",
+		0x1e: "SGL Data Block Granularity Invalid",
+		0x1f: "Command Not Supported for Queue in CMB",
+		0x20: "Namespace is Write Protected",
+		0x21: "Command Interrupted",
+		0x22: "Transient Transport Error",
+		0x80: "LBA Out of Range",
+		0x81: "Capacity Exceeded",
+		0x82: "Namespace Not Ready",
+		0x83: "Reservation Conflict",
+		0x84: "Format In Progress",
+	},
+	SctCommand: {
+		0x00: "Completion Queue Invalid",
+		0x01: "Invalid Queue Identifier",
+		0x02: "Invalid Queue Size",
+		0x03: "Abort Command Limit Exceeded",
+		0x05: "Asynchronous Event Request Limit Exceeded",
+		0x06: "Invalid Firmware Slot",
+		0x07: "Invalid Firmware Image",
+		0x08: "Invalid Interrupt Vector",
+		0x09: "Invalid Log Page",
+		0x0a: "Invalid Format",
+		0x0b: "Firmware Activation Requires Conventional Reset",
+		0x0c: "Invalid Queue Deletion",
+		0x0d: "Feature Identifier Not Saveable",
+		0x0e: "Feature Not Changeable",
+		0x0f: "Feature Not Namespace Specific",
+		0x10: "Firmware Activation Requires NVM Subsystem Reset",
+		0x11: "Firmware Activation Requires Controller Level Reset",
+		0x12: "Firmware Activation Requires Maximum Time Violation",
+		0x13: "Firmware Activation Prohibited",
+		0x14: "Overlapping Range",
+		0x15: "Namespace Insufficient Capacity",
+		0x16: "Namespace Identifier Unavailable",
+		0x18: "Namespace Already Attached",
+		0x19: "Namespace Is Private",
+		0x1a: "Namespace Not Attached",
+		0x1b: "Thin Provisioning Not Supported",
+		0x1c: "Controller List Invalid",
+		0x1d: "Device Self-test In Progress",
+		0x1e: "Boot Partition Write Prohibited",
+		0x1f: "Invalid Controller Identifier",
+		0x20: "Invalid Secondary Controller State",
+		0x21: "Invalid Number of Controller Resources",
+		0x22: "Invalid Resource Identifier",
+		0x23: "Sanitize Prohibited While Persistent Memory Region is Enabled",
+		0x24: "ANA Group Identifier Invalid",
+		0x25: "ANA Attach Failed",
+		0x80: "Conflicting Attributes",
+		0x81: "Invalid Protection Information",
+		0x82: "Attempted Write to Read Only Range",
+	},
+	SctMedia: {
+		0x80: "Write Fault",
+		0x81: "Unrecovered Read Error",
+		0x82: "End-to-end Guard Check Error",
+		0x83: "End-to-end Application Tag Check Error",
+		0x84: "End-to-end Reference Tag Check Error",
+		0x85: "Compare Failure",
+		0x86: "Access Denied",
+		0x87: "Deallocated or Unwritten Logical Block",
+	},
+	SctPath: {
+		0x00: "Internal Path Error",
+		0x01: "Asymmetric Access Persistent Loss",
+		0x02: "Asymmetric Access Inaccessible",
+		0x03: "Asymmetric Access Transition",
+		0x60: "Controller Pathing Error",
+		0x70: "Host Pathing Error",
+		0x71: "Command Aborted By Host",
+	},
+}
+
+// NVMeStatus is the status field of the completion queue entry without the
+// phase tag.
+type NVMeStatus uint16
+
+func (status NVMeStatus) SC() uint8 {
+	return uint8(uint16(status) & nvmeStatusSCMASK)
+}
+
+func (status NVMeStatus) SCT() uint8 {
+	return uint8((uint16(status) >> nvmeStatusSCTPos) & nvmeStatusSCTMASK)
+}
+
+// CRD is the command retry delay index.
+func (status NVMeStatus) CRD() uint8 {
+	return uint8((uint16(status) >> nvmeStatusCRDPos) & nvmeStatusCRDMASK)
+}
+
+func (status NVMeStatus) More() bool {
+	return uint16(status)&nvmeStatusMore != 0
+}
+
+// DNR is the Do Not Retry bit, the same command fails again if set.
+func (status NVMeStatus) DNR() bool {
+	return uint16(status)&nvmeStatusDNR != 0
+}
+
+func (status NVMeStatus) Success() bool {
+	return status.SCT() == SctGeneric && status.SC() == 0x00
+}
+
+func (status NVMeStatus) TypeName() string {
+	if name, ok := nvmeStatusTypes[status.SCT()]; ok {
+		return name
+	}
+
+	return "Reserved"
+}
+
+func (status NVMeStatus) Name() string {
+	if name, ok := nvmeStatusNames[status.SCT()][status.SC()]; ok {
+		return name
+	}
+
+	if status.SCT() == SctVendorSpecific || status.SC() >= 0xc0 {
+		return "Vendor Specific"
+	}
+
+	return "Unknown Status"
+}
+
+func (status NVMeStatus) String() string {
+	return fmt.Sprintf("%s (SCT: 0x%x, SC: 0x%02x)", status.Name(), status.SCT(), status.SC())
+}
+
+// NVMeStatusError is the admin command completed with the error status.
+type NVMeStatusError struct {
+	Opcode uint8
+	Status NVMeStatus
+}
+
+func (e *NVMeStatusError) Error() string {
+	return fmt.Sprintf("nvme admin command 0x%02x failed: %s", e.Opcode, e.Status)
+}
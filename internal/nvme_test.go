@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAdminResponse struct {
+	data   []byte
+	result uint32
+	status NVMeStatus
+	err    error
+}
+
+// fakeNVMeTransport records every admin command and returns the queued
+// responses in order.
+type fakeNVMeTransport struct {
+	cmds      []NVMeAdminCmd
+	responses []fakeAdminResponse
+	closed    bool
+}
+
+func newFakeNVMeTransport(responses ...fakeAdminResponse) *fakeNVMeTransport {
+	return &fakeNVMeTransport{responses: responses}
+}
+
+func (t *fakeNVMeTransport) AdminCommand(cmd *NVMeAdminCmd) (uint32, error) {
+	t.cmds = append(t.cmds, *cmd)
+
+	if len(t.responses) == 0 {
+		return 0, errors.New("unexpected command")
+	}
+
+	next := t.responses[0]
+	t.responses = t.responses[1:]
+
+	copy(cmd.Data, next.data)
+
+	if next.err != nil {
+		return 0, next.err
+	}
+
+	if next.status != 0 {
+		return next.result, &NVMeStatusError{Opcode: cmd.Opcode, Status: next.status}
+	}
+
+	return next.result, nil
+}
+
+func (t *fakeNVMeTransport) Close() error {
+	t.closed = true
+
+	return nil
+}
+
+func (t *fakeNVMeTransport) lastCmd() NVMeAdminCmd {
+	return t.cmds[len(t.cmds)-1]
+}
+
+func TestNVMeStatus(t *testing.T) {
+	a := assert.New(t)
+
+	// DNR, Command Specific Status, Invalid Log Page
+	status := NVMeStatus(0x4109)
+	a.Equal(SctCommand, status.SCT())
+	a.Equal(uint8(0x09), status.SC())
+	a.True(status.DNR())
+	a.False(status.More())
+	a.False(status.Success())
+	a.Equal("Invalid Log Page", status.Name())
+	a.Equal("Command Specific Status", status.TypeName())
+	a.Equal("Invalid Log Page (SCT: 0x1, SC: 0x09)", status.String())
+
+	a.Equal("Unrecovered Read Error", NVMeStatus(0x0281).Name())
+	a.Equal("Vendor Specific", NVMeStatus(0x0701).Name())
+	a.Equal("Vendor Specific", NVMeStatus(0x00c1).Name())
+	a.Equal("Unknown Status", NVMeStatus(0x0250).Name())
+	a.Equal(uint8(2), NVMeStatus(0x1000).CRD())
+	a.True(NVMeStatus(0).Success())
+}
+
+func TestNVMeAdminCommand(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeNVMeTransport(
+		fakeAdminResponse{data: []byte{0x01, 0x02}, result: 0x10},
+		fakeAdminResponse{status: NVMeStatus(0x4002)},
+	)
+
+	nvme := newNVMeDev("/dev/nvme0")
+	nvme.transport = transport
+
+	buf := make([]byte, 4096)
+	result, err := nvme.adminCommand(&NVMeAdminCmd{Opcode: NVMeAdminIdentify, CDW10: 0x01, Data: buf})
+	a.NoError(err)
+	a.Equal(uint32(0x10), result)
+	a.Equal([]byte{0x01, 0x02}, buf[:2])
+	a.Equal(uint32(0x01), transport.lastCmd().CDW10)
+
+	_, err = nvme.adminCommand(&NVMeAdminCmd{Opcode: NVMeAdminGetFeatures})
+
+	statusErr, ok := err.(*NVMeStatusError)
+	a.True(ok)
+	a.Equal(uint8(NVMeAdminGetFeatures), statusErr.Opcode)
+	a.Equal("Invalid Field in Command", statusErr.Status.Name())
+
+	a.NoError(nvme.Close())
+	a.True(transport.closed)
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PowerMode is the power mode of the device ordered from the most awake.
+type PowerMode int
+
+const (
+	PowerModeUnknown = PowerMode(iota)
+	PowerModeActive
+	PowerModeIdle
+	PowerModeStandby
+	PowerModeSleep
+)
+
+var powerModeNames = map[PowerMode]string{
+	PowerModeUnknown: "UNKNOWN",
+	PowerModeActive:  "ACTIVE",
+	PowerModeIdle:    "IDLE",
+	PowerModeStandby: "STANDBY",
+	PowerModeSleep:   "SLEEP",
+}
+
+func (mode PowerMode) String() string {
+	if name, ok := powerModeNames[mode]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("PowerMode(%d)", int(mode))
+}
+
+// PowerPolicy selects the power modes not to wake up by the SMART commands,
+// in the same way as the -n directive of smartd.
+type PowerPolicy int
+
+const (
+	PowerPolicyNever   = PowerPolicy(iota) // always check the device
+	PowerPolicySleep                       // skip the device in SLEEP
+	PowerPolicyStandby                     // skip the device in SLEEP or STANDBY
+	PowerPolicyIdle                        // skip the device in SLEEP, STANDBY or IDLE
+)
+
+var powerPolicyNames = map[PowerPolicy]string{
+	PowerPolicyNever:   "never",
+	PowerPolicySleep:   "sleep",
+	PowerPolicyStandby: "standby",
+	PowerPolicyIdle:    "idle",
+}
+
+var ErrPowerPolicy = errors.New("unknown power policy")
+
+func (policy PowerPolicy) String() string {
+	if name, ok := powerPolicyNames[policy]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("PowerPolicy(%d)", int(policy))
+}
+
+// ParsePowerPolicy parses the policy name, never, sleep, standby or idle.
+func ParsePowerPolicy(name string) (PowerPolicy, error) {
+	for policy, policyName := range powerPolicyNames {
+		if strings.EqualFold(name, policyName) {
+			return policy, nil
+		}
+	}
+
+	return PowerPolicyNever, fmt.Errorf("%q: %w", name, ErrPowerPolicy)
+}
+
+// Skip reports the device in the mode should not be checked. The device in
+// the unknown mode is always checked.
+func (policy PowerPolicy) Skip(mode PowerMode) bool {
+	switch policy {
+	case PowerPolicySleep:
+		return mode >= PowerModeSleep
+	case PowerPolicyStandby:
+		return mode >= PowerModeStandby
+	case PowerPolicyIdle:
+		return mode >= PowerModeIdle
+	}
+
+	return false
+}
+
+// SkippedError is returned for the device not scanned by the power policy.
+type SkippedError struct {
+	Device string
+	Mode   PowerMode
+	Policy PowerPolicy
+}
+
+func (err *SkippedError) Error() string {
+	return fmt.Sprintf("%s: skipped, device is in %s mode (power policy: %s)", err.Device, err.Mode, err.Policy)
+}
+
+// ScanSMARTPolicy checks the power mode of the device before ScanSMART, and
+// returns SkippedError without issuing the SMART commands if the policy
+// skips the mode.
+func ScanSMARTPolicy(device StorageDevice, policy PowerPolicy) error {
+	if policy != PowerPolicyNever {
+		mode, err := device.PowerMode()
+		if err != nil {
+			return err
+		}
+
+		if policy.Skip(mode) {
+			return &SkippedError{Device: device.Device(), Mode: mode, Policy: policy}
+		}
+	}
+
+	return device.ScanSMART()
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPowerPolicy(t *testing.T) {
+	a := assert.New(t)
+
+	modes := []PowerMode{PowerModeUnknown, PowerModeActive, PowerModeIdle, PowerModeStandby, PowerModeSleep}
+
+	tcs := []struct {
+		policy  PowerPolicy
+		skipped []bool
+	}{
+		{PowerPolicyNever, []bool{false, false, false, false, false}},
+		{PowerPolicySleep, []bool{false, false, false, false, true}},
+		{PowerPolicyStandby, []bool{false, false, false, true, true}},
+		{PowerPolicyIdle, []bool{false, false, true, true, true}},
+	}
+
+	for _, tc := range tcs {
+		for i, mode := range modes {
+			a.Equal(tc.skipped[i], tc.policy.Skip(mode), "%s: %s", tc.policy, mode)
+		}
+
+		policy, err := ParsePowerPolicy(tc.policy.String())
+		a.NoError(err)
+		a.Equal(tc.policy, policy)
+	}
+
+	policy, err := ParsePowerPolicy("STANDBY")
+	a.NoError(err)
+	a.Equal(PowerPolicyStandby, policy)
+
+	_, err = ParsePowerPolicy("deep")
+	a.True(errors.Is(err, ErrPowerPolicy))
+}
+
+func TestScanSMARTPolicy(t *testing.T) {
+	a := assert.New(t)
+
+	standby := ataRegisters{status: ataStatusDRDY, count: uint16(ataPowerStandbyZ)}
+
+	transport := newFakeTransport(
+		fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: ataRegistersSense(standby)}},
+		fakeResponse{resp: ScsiResponse{Status: ScsiCheckCondition, Sense: ataRegistersSense(standby)}},
+	)
+
+	sata := newSATADev("/dev/sda")
+	sata.transport = transport
+
+	err := ScanSMARTPolicy(sata, PowerPolicyStandby)
+	skipped := &SkippedError{}
+	a.True(errors.As(err, &skipped))
+	a.Equal("/dev/sda", skipped.Device)
+	a.Equal(PowerModeStandby, skipped.Mode)
+	a.Equal("/dev/sda: skipped, device is in STANDBY mode (power policy: standby)", err.Error())
+	a.Len(transport.cdbs, 1)
+
+	// the device in STANDBY is scanned by the sleep policy, IDENTIFY fails by
+	// the empty fake transport
+	err = ScanSMARTPolicy(sata, PowerPolicySleep)
+	a.Error(err)
+	a.False(errors.As(err, &skipped))
+	a.Len(transport.cdbs, 3)
+	a.Equal(uint8(AtaIdentifyDev), transport.lastCDB()[14])
+}
@@ -0,0 +1,209 @@
+package internal
+
+import "fmt"
+
+const (
+	// SPC-4 (Table 61 - Commands for all device types), SBC-3 (Table 13)
+	ScsiCmdRequestSense    = 0x03
+	ScsiCmdInquiry         = 0x12
+	ScsiCmdReadCapacity10  = 0x25
+	ScsiCmdLogSense        = 0x4D
+	ScsiCmdServiceActionIn = 0x9E
+	scsiReadCapacity16SA   = uint8(0x10)
+
+	scsiRequestSenseLen = 252
+
+	// SPC-4 (Table 313 - ASC and ASCQ assignments) LOW POWER CONDITION ON
+	senseAscLowPower = uint8(0x5e)
+	// LOGICAL UNIT NOT READY, INITIALIZING COMMAND REQUIRED
+	senseAscNotReady       = uint8(0x04)
+	senseAscqStartRequired = uint8(0x02)
+)
+
+// SPC-4 (Table 313) ASCQ of LOW POWER CONDITION ON by the timer or the
+// command, the condition not listed is taken as IDLE.
+var scsiLowPowerModes = map[uint8]PowerMode{
+	0x01: PowerModeIdle,    // IDLE CONDITION ACTIVATED BY TIMER
+	0x02: PowerModeStandby, // STANDBY CONDITION ACTIVATED BY TIMER
+	0x03: PowerModeIdle,    // IDLE CONDITION ACTIVATED BY COMMAND
+	0x04: PowerModeStandby, // STANDBY CONDITION ACTIVATED BY COMMAND
+	0x05: PowerModeIdle,    // IDLE_B CONDITION ACTIVATED BY TIMER
+	0x06: PowerModeIdle,    // IDLE_B CONDITION ACTIVATED BY COMMAND
+	0x07: PowerModeIdle,    // IDLE_C CONDITION ACTIVATED BY TIMER
+	0x08: PowerModeIdle,    // IDLE_C CONDITION ACTIVATED BY COMMAND
+	0x09: PowerModeStandby, // STANDBY_Y CONDITION ACTIVATED BY TIMER
+	0x0a: PowerModeStandby, // STANDBY_Y CONDITION ACTIVATED BY COMMAND
+}
+
+// ScsiError is the command completed with the status other than GOOD, or
+// with CHECK CONDITION reporting the sense key other than RECOVERED ERROR.
+type ScsiError struct {
+	Opcode uint8
+	Status uint8
+	Key    uint8
+	ASC    uint8
+	ASCQ   uint8
+}
+
+func (e *ScsiError) Error() string {
+	return fmt.Sprintf("scsi command 0x%02x failed (status: 0x%02x, sense key: 0x%x, asc/ascq: 0x%02x/0x%02x)",
+		e.Opcode, e.Status, e.Key, e.ASC, e.ASCQ)
+}
+
+// IllegalRequest reports the device rejected the command, usually because
+// the command or the requested page is not supported.
+func (e *ScsiError) IllegalRequest() bool {
+	return e.Key == senseKeyIllegalRequest
+}
+
+// SCSIDevice is the SCSI block device like the SAS and the FC disk, which
+// reports the identity by INQUIRY and the health by LOG SENSE instead of the
+// ATA SMART.
+type SCSIDevice struct {
+	StorageMeta
+
+	transport Transport
+	inquiry   *ScsiInquiry
+	capacity  *ScsiCapacity
+	logPages  []uint8
+}
+
+func newSCSIDev(path string) *SCSIDevice {
+	scsi := new(SCSIDevice)
+
+	scsi.devType = SCSI
+	scsi.devPath = path
+
+	return scsi
+}
+
+func (scsi *SCSIDevice) open() error {
+	if scsi.transport != nil {
+		return nil
+	}
+
+	transport, err := OpenTransport(scsi.devPath)
+	if err != nil {
+		return err
+	}
+
+	scsi.transport = transport
+
+	return nil
+}
+
+func (scsi *SCSIDevice) Close() error {
+	if scsi.transport == nil {
+		return nil
+	}
+
+	err := scsi.transport.Close()
+	scsi.transport = nil
+
+	return err
+}
+
+// execute sends the cdb and reads buf from the device. The CHECK CONDITION
+// with RECOVERED ERROR completes the command successfully.
+func (scsi *SCSIDevice) execute(cdb []byte, buf []byte) error {
+	if err := scsi.open(); err != nil {
+		return err
+	}
+
+	dir := DataNone
+	if len(buf) > 0 {
+		dir = DataFromDev
+	}
+
+	resp, err := scsi.transport.Execute(cdb, dir, buf)
+	if err != nil {
+		return err
+	}
+
+	if resp.Status == ScsiGood {
+		return nil
+	}
+
+	key, asc, ascq := senseKeyCode(resp.Sense)
+	if resp.Status == ScsiCheckCondition && key == senseKeyRecovered {
+		return nil
+	}
+
+	return &ScsiError{Opcode: cdb[0], Status: resp.Status, Key: key, ASC: asc, ASCQ: ascq}
+}
+
+// REQUEST SENSE - 0x03
+//   [1]: DESC(0)
+//   [4]: ALLOCATION LENGTH
+
+// PowerMode issues REQUEST SENSE which does not spin up the device, and maps
+// the LOW POWER CONDITION ON sense to IDLE or STANDBY like smartd. The device
+// stopped by STOP UNIT is reported as STANDBY.
+func (scsi *SCSIDevice) PowerMode() (PowerMode, error) {
+	cdb := []byte{ScsiCmdRequestSense, 0, 0, 0, scsiRequestSenseLen, 0}
+
+	buf := make([]byte, scsiRequestSenseLen)
+	if err := scsi.execute(cdb, buf); err != nil {
+		return PowerModeUnknown, err
+	}
+
+	return scsiPowerMode(senseKeyCode(buf)), nil
+}
+
+func scsiPowerMode(key uint8, asc uint8, ascq uint8) PowerMode {
+	switch {
+	case key == senseKeyNotReady && asc == senseAscNotReady && ascq == senseAscqStartRequired:
+		return PowerModeStandby
+
+	case asc == senseAscLowPower:
+		if mode, ok := scsiLowPowerModes[ascq]; ok {
+			return mode
+		}
+
+		return PowerModeIdle
+	}
+
+	return PowerModeActive
+}
+
+// ID is the NAA world wide name of the logical unit, or the vendor, the
+// product and the serial if the device does not report the NAA designator.
+func (scsi *SCSIDevice) ID() string {
+	if scsi.inquiry != nil && scsi.inquiry.WWN != "" {
+		return "wwn-0x" + scsi.inquiry.WWN
+	}
+
+	return deviceID("scsi", scsi.model, scsi.serial)
+}
+
+// Capacity is the bytes of the logical blocks, 0 if the capacity is not read
+// yet.
+func (scsi *SCSIDevice) Capacity() uint64 {
+	if scsi.capacity == nil {
+		return 0
+	}
+
+	return scsi.capacity.Bytes
+}
+
+/*
+ * inherited interface methods
+ */
+func (scsi *SCSIDevice) ScanSMART() error {
+	if _, err := scsi.Inquiry(); err != nil {
+		return err
+	}
+
+	if _, err := scsi.ReadCapacity(); err != nil {
+		return err
+	}
+
+	pages, err := scsi.SupportedLogPages()
+	if err != nil {
+		return err
+	}
+
+	scsi.logPages = pages
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	scsiInquiryLen = 96
+	scsiVPDLen     = 252
+
+	// SPC-4 (7.8 Vital product data parameters)
+	scsiVPDSupported      = uint8(0x00)
+	scsiVPDSerial         = uint8(0x80)
+	scsiVPDIdentification = uint8(0x83)
+
+	// SPC-4 (Table 590 - DESIGNATOR TYPE, Table 589 - ASSOCIATION)
+	scsiDesignatorNAA      = uint8(0x03)
+	scsiDesignatorTypeMASK = uint8(0x0f)
+	scsiAssociationMASK    = uint8(0x30)
+
+	scsiPeripheralTypeMASK = uint8(0x1f)
+	scsiRemovableMASK      = uint8(0x80)
+
+	scsiReadCapacity10Len = 8
+	scsiReadCapacity16Len = 32
+	scsiReadCapacity10Max = uint32(0xffffffff)
+	scsiPhysicalExpMASK   = uint8(0x0f)
+)
+
+// ScsiInquiry is the standard INQUIRY data with the unit serial number and
+// the device identification vital product data pages.
+type ScsiInquiry struct {
+	PeripheralType uint8
+	Removable      bool
+	Version        uint8 // 6 is SPC-4, 7 is SPC-5
+	Vendor         string
+	Product        string
+	Revision       string
+	Serial         string // Unit Serial Number VPD page (80h)
+	WWN            string // NAA designator of the logical unit in hex, Device Identification VPD page (83h)
+}
+
+// INQUIRY - 0x12
+//   [1]:   EVPD(0)
+//   [2]:   PAGE CODE
+//   [4:3]: ALLOCATION LENGTH
+
+func makeInquiryCDB(evpd bool, page uint8, length uint16) []byte {
+	cdb := []byte{ScsiCmdInquiry, 0, page, byte(length >> 8), byte(length), 0}
+	if evpd {
+		cdb[1] = 0x01
+	}
+
+	return cdb
+}
+
+// Standard INQUIRY data (SPC-4 6.6.2)
+//   [0]:     PERIPHERAL QUALIFIER(7:5) | PERIPHERAL DEVICE TYPE(4:0)
+//   [1]:     RMB(7)
+//   [2]:     VERSION
+//   [15:8]:  T10 VENDOR IDENTIFICATION
+//   [31:16]: PRODUCT IDENTIFICATION
+//   [35:32]: PRODUCT REVISION LEVEL
+
+// parseScsiInquiry decodes the standard INQUIRY data (SPC-4 6.6.2).
+func parseScsiInquiry(buf []byte) *ScsiInquiry {
+	return &ScsiInquiry{
+		PeripheralType: buf[0] & scsiPeripheralTypeMASK,
+		Removable:      buf[1]&scsiRemovableMASK != 0,
+		Version:        buf[2],
+		Vendor:         scsiString(buf[8:16]),
+		Product:        scsiString(buf[16:32]),
+		Revision:       scsiString(buf[32:36]),
+	}
+}
+
+// scsiString trims the space padding of the ASCII field.
+func scsiString(raw []byte) string {
+	return strings.TrimSpace(strings.TrimRight(string(raw), "\x00"))
+}
+
+// vpdPage returns the page payload after the 4 bytes header bounded by the
+// PAGE LENGTH.
+func vpdPage(buf []byte) []byte {
+	end := 4 + int(binary.BigEndian.Uint16(buf[2:4]))
+	if end > len(buf) {
+		end = len(buf)
+	}
+
+	return buf[4:end]
+}
+
+// naaDesignator finds the NAA designator of the logical unit in the Device
+// Identification VPD page (SPC-4 7.8.6).
+func naaDesignator(page []byte) []byte {
+	for pos := 0; pos+4 <= len(page); {
+		length := int(page[pos+3])
+		if pos+4+length > len(page) {
+			return nil
+		}
+
+		if page[pos+1]&scsiDesignatorTypeMASK == scsiDesignatorNAA && page[pos+1]&scsiAssociationMASK == 0 {
+			return page[pos+4 : pos+4+length]
+		}
+
+		pos += 4 + length
+	}
+
+	return nil
+}
+
+func (scsi *SCSIDevice) readVPD(page uint8) ([]byte, error) {
+	buf := make([]byte, scsiVPDLen)
+	if err := scsi.execute(makeInquiryCDB(true, page, scsiVPDLen), buf); err != nil {
+		return nil, err
+	}
+
+	return vpdPage(buf), nil
+}
+
+// Inquiry issues INQUIRY and reads the serial and the NAA designator from the
+// vital product data pages the device supports, then updates the model,
+// firmware and serial of the device. The model is the vendor and the product.
+func (scsi *SCSIDevice) Inquiry() (*ScsiInquiry, error) {
+	buf := make([]byte, scsiInquiryLen)
+	if err := scsi.execute(makeInquiryCDB(false, 0, scsiInquiryLen), buf); err != nil {
+		return nil, err
+	}
+
+	inquiry := parseScsiInquiry(buf)
+
+	// the vital product data pages are optional for the older devices
+	supported, err := scsi.readVPD(scsiVPDSupported)
+	if err != nil {
+		if e, ok := err.(*ScsiError); !ok || !e.IllegalRequest() {
+			return nil, err
+		}
+	}
+
+	for _, page := range supported {
+		switch page {
+		case scsiVPDSerial:
+			serial, err := scsi.readVPD(scsiVPDSerial)
+			if err != nil {
+				return nil, err
+			}
+
+			inquiry.Serial = scsiString(serial)
+
+		case scsiVPDIdentification:
+			ident, err := scsi.readVPD(scsiVPDIdentification)
+			if err != nil {
+				return nil, err
+			}
+
+			if naa := naaDesignator(ident); naa != nil {
+				inquiry.WWN = hex.EncodeToString(naa)
+			}
+		}
+	}
+
+	scsi.inquiry = inquiry
+
+	scsi.model = strings.TrimSpace(inquiry.Vendor + " " + inquiry.Product)
+	scsi.firmware = inquiry.Revision
+	scsi.serial = inquiry.Serial
+
+	return inquiry, nil
+}
+
+// InquiryData returns the data of the last Inquiry.
+func (scsi *SCSIDevice) InquiryData() *ScsiInquiry {
+	return scsi.inquiry
+}
+
+// ScsiCapacity is the capacity of the logical unit read by READ CAPACITY.
+type ScsiCapacity struct {
+	Blocks            uint64 // the last LBA + 1
+	BlockSize         uint32
+	PhysicalBlockSize uint32
+	Bytes             uint64
+}
+
+// READ CAPACITY(10) - 0x25
+//   no field is used
+// READ CAPACITY(16) - SERVICE ACTION IN(16) 0x9E
+//   [1]:     SERVICE ACTION(4:0) 0x10
+//   [13:10]: ALLOCATION LENGTH
+
+// ReadCapacity issues READ CAPACITY(10), and READ CAPACITY(16) if the last
+// LBA does not fit in 32 bits, like sd of linux. The physical block size is
+// only reported by READ CAPACITY(16).
+func (scsi *SCSIDevice) ReadCapacity() (*ScsiCapacity, error) {
+	buf := make([]byte, scsiReadCapacity10Len)
+	if err := scsi.execute([]byte{ScsiCmdReadCapacity10, 0, 0, 0, 0, 0, 0, 0, 0, 0}, buf); err != nil {
+		return nil, err
+	}
+
+	// [3:0]: RETURNED LOGICAL BLOCK ADDRESS, [7:4]: LOGICAL BLOCK LENGTH
+	lastLBA, blockSize := uint64(binary.BigEndian.Uint32(buf[0:4])), binary.BigEndian.Uint32(buf[4:8])
+	physical := blockSize
+
+	if lastLBA == uint64(scsiReadCapacity10Max) {
+		cdb := make([]byte, 16)
+		cdb[0], cdb[1] = ScsiCmdServiceActionIn, scsiReadCapacity16SA
+		binary.BigEndian.PutUint32(cdb[10:14], scsiReadCapacity16Len)
+
+		buf = make([]byte, scsiReadCapacity16Len)
+		if err := scsi.execute(cdb, buf); err != nil {
+			return nil, err
+		}
+
+		// [7:0]: RETURNED LOGICAL BLOCK ADDRESS, [11:8]: LOGICAL BLOCK LENGTH
+		// [13]:  LOGICAL BLOCKS PER PHYSICAL BLOCK EXPONENT(3:0)
+		lastLBA, blockSize = binary.BigEndian.Uint64(buf[0:8]), binary.BigEndian.Uint32(buf[8:12])
+		physical = blockSize << (buf[13] & scsiPhysicalExpMASK)
+	}
+
+	scsi.capacity = &ScsiCapacity{
+		Blocks:            lastLBA + 1,
+		BlockSize:         blockSize,
+		PhysicalBlockSize: physical,
+		Bytes:             (lastLBA + 1) * uint64(blockSize),
+	}
+
+	return scsi.capacity, nil
+}
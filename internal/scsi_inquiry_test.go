@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleScsiInquiry() []byte {
+	buf := make([]byte, scsiInquiryLen)
+
+	buf[0] = 0x00 // direct access block device
+	buf[2] = 0x06 // SPC-4
+	buf[4] = scsiInquiryLen - 5
+	copy(buf[8:16], "SEAGATE ")
+	copy(buf[16:32], "ST4000NM0023    ")
+	copy(buf[32:36], "0003")
+
+	return buf
+}
+
+func sampleVPD(page uint8, payload []byte) []byte {
+	buf := []byte{0x00, page, 0x00, 0x00}
+	binary.BigEndian.PutUint16(buf[2:4], uint16(len(payload)))
+
+	return append(buf, payload...)
+}
+
+func sampleDeviceIdentification() []byte {
+	// the T10 vendor id and the NAA of the target port are skipped
+	payload := []byte{0x02, 0x01, 0x00, 0x08}
+	payload = append(payload, "SEAGATE "...)
+	payload = append(payload, 0x61, 0x93, 0x00, 0x08, 0x50, 0x00, 0xc5, 0x00, 0xa1, 0xb2, 0xc3, 0xd5)
+	payload = append(payload, 0x01, 0x03, 0x00, 0x08, 0x50, 0x00, 0xc5, 0x00, 0xa1, 0xb2, 0xc3, 0xd4)
+
+	return sampleVPD(scsiVPDIdentification, payload)
+}
+
+func TestSCSIInquiry(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeTransport(
+		fakeResponse{data: sampleScsiInquiry()},
+		fakeResponse{data: sampleVPD(scsiVPDSupported, []byte{scsiVPDSupported, scsiVPDSerial, scsiVPDIdentification})},
+		fakeResponse{data: sampleVPD(scsiVPDSerial, []byte("    Z1Z0ABCD"))},
+		fakeResponse{data: sampleDeviceIdentification()},
+	)
+
+	scsi := newSCSIDev("/dev/sdaa")
+	scsi.transport = transport
+
+	inquiry, err := scsi.Inquiry()
+	a.NoError(err)
+	a.Equal(&ScsiInquiry{
+		Version:  0x06,
+		Vendor:   "SEAGATE",
+		Product:  "ST4000NM0023",
+		Revision: "0003",
+		Serial:   "Z1Z0ABCD",
+		WWN:      "5000c500a1b2c3d4",
+	}, inquiry)
+	a.Equal(inquiry, scsi.InquiryData())
+
+	a.Equal("SEAGATE ST4000NM0023", scsi.Model())
+	a.Equal("0003", scsi.Firmware())
+	a.Equal("Z1Z0ABCD", scsi.Serial())
+	a.Equal("wwn-0x5000c500a1b2c3d4", scsi.ID())
+
+	a.Equal([]byte{ScsiCmdInquiry, 0x00, 0x00, 0x00, scsiInquiryLen, 0x00}, transport.cdbs[0])
+	a.Equal([]byte{ScsiCmdInquiry, 0x01, scsiVPDIdentification, 0x00, scsiVPDLen, 0x00}, transport.lastCDB())
+
+	// the NAA designator is not reported
+	scsi.transport = newFakeTransport(
+		fakeResponse{data: sampleScsiInquiry()},
+		fakeResponse{data: sampleVPD(scsiVPDSupported, []byte{scsiVPDSupported, scsiVPDSerial})},
+		fakeResponse{data: sampleVPD(scsiVPDSerial, []byte("Z1Z0ABCD"))},
+	)
+
+	_, err = scsi.Inquiry()
+	a.NoError(err)
+	a.Equal("scsi-SEAGATE_ST4000NM0023_Z1Z0ABCD", scsi.ID())
+
+	// the failure other than ILLEGAL REQUEST is returned
+	scsi.transport = newFakeTransport(
+		fakeResponse{data: sampleScsiInquiry()},
+		fakeResponse{err: errors.New("io error")},
+	)
+
+	_, err = scsi.Inquiry()
+	a.EqualError(err, "io error")
+}
+
+func TestSCSIReadCapacity(t *testing.T) {
+	a := assert.New(t)
+
+	// 4TB with 512 bytes logical blocks fits in READ CAPACITY(10)
+	transport := newFakeTransport(fakeResponse{data: []byte{0xd1, 0xc0, 0xbe, 0xaf, 0x00, 0x00, 0x02, 0x00}})
+
+	scsi := newSCSIDev("/dev/sdaa")
+	scsi.transport = transport
+
+	capacity, err := scsi.ReadCapacity()
+	a.NoError(err)
+	a.Equal(&ScsiCapacity{Blocks: 0xd1c0beb0, BlockSize: 512, PhysicalBlockSize: 512, Bytes: 0xd1c0beb0 * 512}, capacity)
+	a.Equal(capacity.Bytes, scsi.Capacity())
+	a.Len(transport.cdbs, 1)
+
+	// 12TB 512e disk
+	rc16 := make([]byte, scsiReadCapacity16Len)
+	binary.BigEndian.PutUint64(rc16[0:8], 23437770751)
+	binary.BigEndian.PutUint32(rc16[8:12], 512)
+	rc16[13] = 0x03
+
+	transport = newFakeTransport(
+		fakeResponse{data: []byte{0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x02, 0x00}},
+		fakeResponse{data: rc16},
+	)
+	scsi.transport = transport
+
+	capacity, err = scsi.ReadCapacity()
+	a.NoError(err)
+	a.Equal(&ScsiCapacity{Blocks: 23437770752, BlockSize: 512, PhysicalBlockSize: 4096, Bytes: 12000138625024}, capacity)
+
+	cdb := transport.lastCDB()
+	a.Len(cdb, 16)
+	a.Equal([]byte{ScsiCmdServiceActionIn, scsiReadCapacity16SA}, cdb[0:2])
+	a.Equal(uint32(scsiReadCapacity16Len), binary.BigEndian.Uint32(cdb[10:14]))
+
+	scsi.transport = newFakeTransport(fakeResponse{resp: checkCondition(senseKeyIllegalRequest, 0x20, 0x00)})
+	_, err = scsi.ReadCapacity()
+	a.Error(err)
+}
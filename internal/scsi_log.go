@@ -0,0 +1,323 @@
+package internal
+
+import "encoding/binary"
+
+const (
+	// SPC-4 (Table 349 - Log page codes), SBC-3 (Table 185)
+	ScsiLogSupported       = uint8(0x00)
+	ScsiLogWriteErrors     = uint8(0x02)
+	ScsiLogReadErrors      = uint8(0x03)
+	ScsiLogVerifyErrors    = uint8(0x05)
+	ScsiLogTemperature     = uint8(0x0D)
+	ScsiLogStartStop       = uint8(0x0E)
+	ScsiLogSelfTest        = uint8(0x10)
+	ScsiLogSolidStateMedia = uint8(0x11)
+	ScsiLogBackgroundScan  = uint8(0x15)
+	ScsiLogInfoExceptions  = uint8(0x2F)
+	scsiLogPageCodeMASK    = uint8(0x3f)
+	scsiLogCumulativeValue = uint8(0x01 << 6)
+
+	scsiLogSenseLen = 0x1000
+
+	// SPC-4 (7.3.22.1 Temperature log page) the temperature is not available
+	ScsiTemperatureInvalid = uint8(0xff)
+)
+
+var scsiLogFeatures = []struct {
+	name string
+	page uint8
+}{
+	{"Informational Exceptions log", ScsiLogInfoExceptions},
+	{"Temperature log", ScsiLogTemperature},
+	{"Start-Stop Cycle Counter log", ScsiLogStartStop},
+	{"Self-Test Results log", ScsiLogSelfTest},
+	{"Read Error Counter log", ScsiLogReadErrors},
+	{"Write Error Counter log", ScsiLogWriteErrors},
+	{"Verify Error Counter log", ScsiLogVerifyErrors},
+}
+
+// ScsiLogParam is a log parameter of the log page, Value is the parameter
+// bytes after the 4 bytes parameter header.
+type ScsiLogParam struct {
+	Code    uint16
+	Control uint8
+	Value   []byte
+}
+
+// Uint64 decodes the big-endian counter of up to 8 bytes, the longer value
+// is truncated to the last 8 bytes.
+func (param ScsiLogParam) Uint64() uint64 {
+	value := uint64(0)
+	for _, b := range param.Value {
+		value = value<<8 | uint64(b)
+	}
+
+	return value
+}
+
+// ScsiLogPage is the log page read by LOG SENSE.
+type ScsiLogPage struct {
+	Page    uint8
+	Subpage uint8
+	Params  []ScsiLogParam
+}
+
+// Param finds the log parameter by the parameter code.
+func (page *ScsiLogPage) Param(code uint16) (ScsiLogParam, bool) {
+	for _, param := range page.Params {
+		if param.Code == code {
+			return param, true
+		}
+	}
+
+	return ScsiLogParam{}, false
+}
+
+// LOG SENSE - 0x4D
+//   [2]:   PC(7:6) | PAGE CODE(5:0)
+//   [3]:   SUBPAGE CODE
+//   [8:7]: ALLOCATION LENGTH
+
+func makeLogSenseCDB(page uint8, subpage uint8, length uint16) []byte {
+	cdb := make([]byte, 10)
+
+	cdb[0] = ScsiCmdLogSense
+	cdb[2] = scsiLogCumulativeValue | page&scsiLogPageCodeMASK
+	cdb[3] = subpage
+	binary.BigEndian.PutUint16(cdb[7:9], length)
+
+	return cdb
+}
+
+// Log page (SPC-4 7.3.2)
+//   [0]:   SPF(6) | PAGE CODE(5:0)
+//   [1]:   SUBPAGE CODE
+//   [3:2]: PAGE LENGTH
+//   [n:4]: log parameters of [1:0] PARAMETER CODE, [2] CONTROL, [3] LENGTH
+
+// logPagePayload returns the log parameters after the 4 bytes log page header
+// bounded by the PAGE LENGTH.
+func logPagePayload(buf []byte) []byte {
+	end := 4 + int(binary.BigEndian.Uint16(buf[2:4]))
+	if end > len(buf) {
+		end = len(buf)
+	}
+
+	return buf[4:end]
+}
+
+// parseScsiLogPage decodes the log page (SPC-4 7.3.2).
+func parseScsiLogPage(buf []byte) *ScsiLogPage {
+	page := &ScsiLogPage{Page: buf[0] & scsiLogPageCodeMASK, Subpage: buf[1]}
+
+	params := logPagePayload(buf)
+	for pos := 0; pos+4 <= len(params); {
+		length := int(params[pos+3])
+		if pos+4+length > len(params) {
+			break
+		}
+
+		page.Params = append(page.Params, ScsiLogParam{
+			Code:    binary.BigEndian.Uint16(params[pos : pos+2]),
+			Control: params[pos+2],
+			Value:   append([]byte{}, params[pos+4:pos+4+length]...),
+		})
+
+		pos += 4 + length
+	}
+
+	return page
+}
+
+// ReadLogPage issues LOG SENSE for the cumulative values of the log page.
+func (scsi *SCSIDevice) ReadLogPage(page uint8, subpage uint8) (*ScsiLogPage, error) {
+	buf := make([]byte, scsiLogSenseLen)
+	if err := scsi.execute(makeLogSenseCDB(page, subpage, scsiLogSenseLen), buf); err != nil {
+		return nil, err
+	}
+
+	return parseScsiLogPage(buf), nil
+}
+
+// SupportedLogPages reads the page codes of the Supported Log Pages log page,
+// the page codes are listed one byte each in place of the log parameters.
+func (scsi *SCSIDevice) SupportedLogPages() ([]uint8, error) {
+	buf := make([]byte, scsiLogSenseLen)
+	if err := scsi.execute(makeLogSenseCDB(ScsiLogSupported, 0, scsiLogSenseLen), buf); err != nil {
+		return nil, err
+	}
+
+	pages := make([]uint8, 0)
+	for _, code := range logPagePayload(buf) {
+		pages = append(pages, code&scsiLogPageCodeMASK)
+	}
+
+	return pages, nil
+}
+
+// ScsiTemperature is the Temperature log page (0Dh) in Celsius, or
+// ScsiTemperatureInvalid if the device does not report it.
+type ScsiTemperature struct {
+	Current   uint8 // parameter 0000h
+	Reference uint8 // parameter 0001h, the maximum for the continuous operation
+}
+
+// ReadTemperature reads the Temperature log page, the byte 1 of the
+// parameter value is the temperature.
+func (scsi *SCSIDevice) ReadTemperature() (*ScsiTemperature, error) {
+	page, err := scsi.ReadLogPage(ScsiLogTemperature, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	temp := &ScsiTemperature{Current: ScsiTemperatureInvalid, Reference: ScsiTemperatureInvalid}
+
+	if param, ok := page.Param(0x0000); ok && len(param.Value) >= 2 {
+		temp.Current = param.Value[1]
+	}
+
+	if param, ok := page.Param(0x0001); ok && len(param.Value) >= 2 {
+		temp.Reference = param.Value[1]
+	}
+
+	return temp, nil
+}
+
+// ScsiStartStop is the Start-Stop Cycle Counter log page (0Eh), the counter
+// not reported by the device is 0.
+type ScsiStartStop struct {
+	ManufactureYear       string // parameter 0001h, 4 ASCII digits
+	ManufactureWeek       string // parameter 0001h, 2 ASCII digits
+	SpecifiedCycles       uint32 // parameter 0003h, over the device lifetime
+	AccumulatedCycles     uint32 // parameter 0004h
+	SpecifiedLoadUnload   uint32 // parameter 0005h, over the device lifetime
+	AccumulatedLoadUnload uint32 // parameter 0006h
+}
+
+// ReadStartStop reads the Start-Stop Cycle Counter log page.
+func (scsi *SCSIDevice) ReadStartStop() (*ScsiStartStop, error) {
+	page, err := scsi.ReadLogPage(ScsiLogStartStop, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	counter := func(code uint16) uint32 {
+		param, _ := page.Param(code)
+		return uint32(param.Uint64())
+	}
+
+	cycles := &ScsiStartStop{
+		SpecifiedCycles:       counter(0x0003),
+		AccumulatedCycles:     counter(0x0004),
+		SpecifiedLoadUnload:   counter(0x0005),
+		AccumulatedLoadUnload: counter(0x0006),
+	}
+
+	if param, ok := page.Param(0x0001); ok && len(param.Value) >= 6 {
+		cycles.ManufactureYear = scsiString(param.Value[0:4])
+		cycles.ManufactureWeek = scsiString(param.Value[4:6])
+	}
+
+	return cycles, nil
+}
+
+// ScsiErrorCounters is the Write, Read or Verify Error Counter log page
+// (02h, 03h, 05h).
+type ScsiErrorCounters struct {
+	CorrectedFast        uint64 // 0000h, corrected without substantial delay
+	CorrectedDelayed     uint64 // 0001h, corrected with possible delay
+	Retries              uint64 // 0002h, total rewrites or rereads
+	TotalCorrected       uint64 // 0003h
+	AlgorithmInvocations uint64 // 0004h, correction algorithm invocations
+	BytesProcessed       uint64 // 0005h
+	TotalUncorrected     uint64 // 0006h
+}
+
+// ReadErrorCounters reads the error counter log page of ScsiLogWriteErrors,
+// ScsiLogReadErrors or ScsiLogVerifyErrors.
+func (scsi *SCSIDevice) ReadErrorCounters(page uint8) (*ScsiErrorCounters, error) {
+	log, err := scsi.ReadLogPage(page, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	counter := func(code uint16) uint64 {
+		param, _ := log.Param(code)
+		return param.Uint64()
+	}
+
+	return &ScsiErrorCounters{
+		CorrectedFast:        counter(0x0000),
+		CorrectedDelayed:     counter(0x0001),
+		Retries:              counter(0x0002),
+		TotalCorrected:       counter(0x0003),
+		AlgorithmInvocations: counter(0x0004),
+		BytesProcessed:       counter(0x0005),
+		TotalUncorrected:     counter(0x0006),
+	}, nil
+}
+
+// ScsiInfoExceptions is the parameter 0000h of the Informational Exceptions
+// log page (2Fh). The ASC other than 0 is the failure predicted by the
+// device, 5Dh is FAILURE PREDICTION THRESHOLD EXCEEDED.
+type ScsiInfoExceptions struct {
+	ASC         uint8
+	ASCQ        uint8
+	Temperature uint8 // the most recent temperature in Celsius
+}
+
+// ReadInfoExceptions reads the Informational Exceptions log page.
+func (scsi *SCSIDevice) ReadInfoExceptions() (*ScsiInfoExceptions, error) {
+	page, err := scsi.ReadLogPage(ScsiLogInfoExceptions, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ie := &ScsiInfoExceptions{Temperature: ScsiTemperatureInvalid}
+
+	// [0]: INFORMATIONAL EXCEPTION ADDITIONAL SENSE CODE, [1]: ASCQ,
+	// [2]: MOST RECENT TEMPERATURE READING
+	if param, ok := page.Param(0x0000); ok && len(param.Value) >= 3 {
+		ie.ASC, ie.ASCQ, ie.Temperature = param.Value[0], param.Value[1], param.Value[2]
+	}
+
+	return ie, nil
+}
+
+// HealthStatus reads the Informational Exceptions log page like smartctl,
+// the device without the page is HealthUnknown.
+func (scsi *SCSIDevice) HealthStatus() (HealthStatus, error) {
+	ie, err := scsi.ReadInfoExceptions()
+	if e, ok := err.(*ScsiError); ok && e.IllegalRequest() {
+		return HealthUnknown, nil
+	} else if err != nil {
+		return HealthUnknown, err
+	}
+
+	if ie.ASC != 0 {
+		return HealthFailed, nil
+	}
+
+	return HealthPassed, nil
+}
+
+// Features lists the log pages of the last ScanSMART, the supported log
+// pages are always enabled.
+func (scsi *SCSIDevice) Features() []Capability {
+	if scsi.logPages == nil {
+		return nil
+	}
+
+	supported := make(map[uint8]bool, len(scsi.logPages))
+	for _, page := range scsi.logPages {
+		supported[page] = true
+	}
+
+	features := make([]Capability, 0, len(scsiLogFeatures))
+
+	for _, log := range scsiLogFeatures {
+		features = append(features, Capability{Name: log.name, Supported: supported[log.page], Enabled: supported[log.page]})
+	}
+
+	return features
+}
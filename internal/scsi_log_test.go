@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScsiLogPage(t *testing.T) {
+	a := assert.New(t)
+
+	buf := sampleScsiLogPage(ScsiLogTemperature,
+		ScsiLogParam{Code: 0x0000, Control: 0x03, Value: []byte{0x00, 0x25}},
+		ScsiLogParam{Code: 0x0001, Control: 0x03, Value: []byte{0x00, 0x3c}},
+	)
+
+	page := parseScsiLogPage(buf)
+	a.Equal(ScsiLogTemperature, page.Page)
+	a.Len(page.Params, 2)
+
+	param, ok := page.Param(0x0001)
+	a.True(ok)
+	a.Equal(uint8(0x03), param.Control)
+	a.Equal(uint64(0x3c), param.Uint64())
+
+	_, ok = page.Param(0x0002)
+	a.False(ok)
+
+	// the truncated parameter is dropped
+	a.Len(parseScsiLogPage(buf[:len(buf)-1]).Params, 1)
+	a.Len(parseScsiLogPage(append(buf[:4:4], make([]byte, 3)...)).Params, 0)
+
+	a.Equal(uint64(0x0102030405060708), ScsiLogParam{Value: []byte{0xff, 1, 2, 3, 4, 5, 6, 7, 8}}.Uint64())
+}
+
+func TestSCSIReadLogPages(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeTransport(
+		fakeResponse{data: []byte{0x00, 0x00, 0x00, 0x03, ScsiLogSupported, ScsiLogTemperature, ScsiLogStartStop}},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogTemperature,
+			ScsiLogParam{Code: 0x0000, Value: []byte{0x00, 0x25}},
+		)},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogStartStop,
+			ScsiLogParam{Code: 0x0001, Value: []byte("201708")},
+			be32Param(0x0003, 50000),
+			be32Param(0x0004, 61),
+			be32Param(0x0005, 600000),
+			be32Param(0x0006, 1208),
+		)},
+		fakeResponse{data: sampleScsiLogPage(ScsiLogReadErrors,
+			be64Param(0x0000, 1234),
+			be64Param(0x0003, 1240),
+			be64Param(0x0005, 0x0000_7e21_5ac3_1200),
+			be64Param(0x0006, 2),
+		)},
+	)
+
+	scsi := newSCSIDev("/dev/sdaa")
+	scsi.transport = transport
+
+	pages, err := scsi.SupportedLogPages()
+	a.NoError(err)
+	a.Equal([]uint8{ScsiLogSupported, ScsiLogTemperature, ScsiLogStartStop}, pages)
+	a.Equal([]byte{ScsiCmdLogSense, 0x00, 0x40, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00}, transport.lastCDB())
+
+	temp, err := scsi.ReadTemperature()
+	a.NoError(err)
+	a.Equal(&ScsiTemperature{Current: 37, Reference: ScsiTemperatureInvalid}, temp)
+	a.Equal(uint8(0x40|ScsiLogTemperature), transport.lastCDB()[2])
+
+	cycles, err := scsi.ReadStartStop()
+	a.NoError(err)
+	a.Equal(&ScsiStartStop{
+		ManufactureYear:       "2017",
+		ManufactureWeek:       "08",
+		SpecifiedCycles:       50000,
+		AccumulatedCycles:     61,
+		SpecifiedLoadUnload:   600000,
+		AccumulatedLoadUnload: 1208,
+	}, cycles)
+
+	counters, err := scsi.ReadErrorCounters(ScsiLogReadErrors)
+	a.NoError(err)
+	a.Equal(&ScsiErrorCounters{
+		CorrectedFast:    1234,
+		TotalCorrected:   1240,
+		BytesProcessed:   0x0000_7e21_5ac3_1200,
+		TotalUncorrected: 2,
+	}, counters)
+
+	scsi.transport = newFakeTransport(fakeResponse{resp: checkCondition(senseKeyIllegalRequest, 0x24, 0x00)})
+	_, err = scsi.ReadStartStop()
+	a.Error(err)
+}
+
+func TestSCSIHealthStatus(t *testing.T) {
+	a := assert.New(t)
+
+	ie := func(asc, ascq uint8) fakeResponse {
+		return fakeResponse{data: sampleScsiLogPage(ScsiLogInfoExceptions,
+			ScsiLogParam{Code: 0x0000, Value: []byte{asc, ascq, 0x26, 0x00}},
+		)}
+	}
+
+	scsi := newSCSIDev("/dev/sdaa")
+	scsi.transport = newFakeTransport(
+		ie(0x00, 0x00),
+		ie(0x5d, 0x10),
+		fakeResponse{resp: checkCondition(senseKeyIllegalRequest, 0x24, 0x00)},
+		fakeResponse{resp: ScsiResponse{Status: ScsiBusy}},
+		ie(0x5d, 0x10),
+	)
+
+	for _, expected := range []HealthStatus{HealthPassed, HealthFailed, HealthUnknown} {
+		status, err := scsi.HealthStatus()
+		a.NoError(err)
+		a.Equal(expected, status)
+	}
+
+	status, err := scsi.HealthStatus()
+	a.Error(err)
+	a.Equal(HealthUnknown, status)
+
+	exceptions, err := scsi.ReadInfoExceptions()
+	a.NoError(err)
+	a.Equal(&ScsiInfoExceptions{ASC: 0x5d, ASCQ: 0x10, Temperature: 38}, exceptions)
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scsiSense builds the fixed format sense data.
+func scsiSense(key, asc, ascq uint8) []byte {
+	sense := make([]byte, 18)
+
+	sense[0] = senseFixedCurrent
+	sense[2] = key
+	sense[7] = 10
+	sense[12], sense[13] = asc, ascq
+
+	return sense
+}
+
+func checkCondition(key, asc, ascq uint8) ScsiResponse {
+	return ScsiResponse{Status: ScsiCheckCondition, Sense: scsiSense(key, asc, ascq)}
+}
+
+// sampleScsiLogPage builds the log page of the parameters.
+func sampleScsiLogPage(page uint8, params ...ScsiLogParam) []byte {
+	buf := []byte{page, 0x00, 0x00, 0x00}
+
+	for _, param := range params {
+		buf = append(buf, byte(param.Code>>8), byte(param.Code), param.Control, byte(len(param.Value)))
+		buf = append(buf, param.Value...)
+	}
+
+	binary.BigEndian.PutUint16(buf[2:4], uint16(len(buf)-4))
+
+	return buf
+}
+
+func be32Param(code uint16, value uint32) ScsiLogParam {
+	param := ScsiLogParam{Code: code, Value: make([]byte, 4)}
+	binary.BigEndian.PutUint32(param.Value, value)
+
+	return param
+}
+
+func be64Param(code uint16, value uint64) ScsiLogParam {
+	param := ScsiLogParam{Code: code, Value: make([]byte, 8)}
+	binary.BigEndian.PutUint64(param.Value, value)
+
+	return param
+}
+
+func TestSCSIExecute(t *testing.T) {
+	a := assert.New(t)
+
+	transport := newFakeTransport(
+		fakeResponse{resp: checkCondition(senseKeyRecovered, 0x5d, 0x00)},
+		fakeResponse{resp: checkCondition(senseKeyIllegalRequest, 0x24, 0x00)},
+		fakeResponse{resp: ScsiResponse{Status: ScsiBusy}},
+		fakeResponse{err: errors.New("io error")},
+	)
+
+	scsi := newSCSIDev("/dev/sdaa")
+	scsi.transport = transport
+
+	a.NoError(scsi.execute([]byte{ScsiCmdInquiry, 0, 0, 0, 0, 0}, nil))
+	a.Equal(DataNone, transport.dirs[0])
+
+	err := scsi.execute([]byte{ScsiCmdLogSense, 0, 0, 0, 0, 0, 0, 0, 0, 0}, make([]byte, 4))
+	a.Equal(DataFromDev, transport.dirs[1])
+	a.Equal(&ScsiError{Opcode: ScsiCmdLogSense, Status: ScsiCheckCondition, Key: senseKeyIllegalRequest, ASC: 0x24}, err)
+	a.True(err.(*ScsiError).IllegalRequest())
+	a.Equal("scsi command 0x4d failed (status: 0x02, sense key: 0x5, asc/ascq: 0x24/0x00)", err.Error())
+
+	err = scsi.execute([]byte{ScsiCmdInquiry, 0, 0, 0, 0, 0}, nil)
+	a.False(err.(*ScsiError).IllegalRequest())
+
+	a.EqualError(scsi.execute([]byte{ScsiCmdInquiry, 0, 0, 0, 0, 0}, nil), "io error")
+
+	a.NoError(scsi.Close())
+	a.True(transport.closed)
+	a.NoError(scsi.Close())
+}
+
+func TestSCSIPowerMode(t *testing.T) {
+	a := assert.New(t)
+
+	for _, tc := range []struct {
+		sense    []byte
+		expected PowerMode
+	}{
+		{scsiSense(senseKeyNoSense, 0x00, 0x00), PowerModeActive},
+		{scsiSense(senseKeyNoSense, senseAscLowPower, 0x03), PowerModeIdle},
+		{scsiSense(senseKeyNoSense, senseAscLowPower, 0x07), PowerModeIdle},
+		{scsiSense(senseKeyNoSense, senseAscLowPower, 0x04), PowerModeStandby},
+		{scsiSense(senseKeyNoSense, senseAscLowPower, 0x09), PowerModeStandby},
+		{scsiSense(senseKeyNoSense, senseAscLowPower, 0x00), PowerModeIdle},
+		{scsiSense(senseKeyNotReady, senseAscNotReady, senseAscqStartRequired), PowerModeStandby},
+	} {
+		transport := newFakeTransport(fakeResponse{data: tc.sense})
+
+		scsi := newSCSIDev("/dev/sdaa")
+		scsi.transport = transport
+
+		mode, err := scsi.PowerMode()
+		a.NoError(err)
+		a.Equal(tc.expected, mode, "% x", tc.sense)
+		a.Equal(uint8(ScsiCmdRequestSense), transport.lastCDB()[0])
+	}
+
+	scsi := newSCSIDev("/dev/sdaa")
+	scsi.transport = newFakeTransport(fakeResponse{err: errors.New("io error")})
+
+	mode, err := scsi.PowerMode()
+	a.Error(err)
+	a.Equal(PowerModeUnknown, mode)
+}
+
+func TestSCSIScanSMART(t *testing.T) {
+	a := assert.New(t)
+
+	scsi := newSCSIDev("/dev/sdaa")
+	a.Nil(scsi.Features())
+	a.Equal("", scsi.ID())
+	a.Equal(uint64(0), scsi.Capacity())
+
+	scsi.transport = newFakeTransport(
+		fakeResponse{data: sampleScsiInquiry()},
+		fakeResponse{resp: checkCondition(senseKeyIllegalRequest, 0x24, 0x00)},
+		fakeResponse{data: []byte{0x00, 0x00, 0x00, 0x7f, 0x00, 0x00, 0x02, 0x00}},
+		fakeResponse{data: []byte{0x00, 0x00, 0x00, 0x04, ScsiLogSupported, ScsiLogReadErrors, ScsiLogTemperature, ScsiLogInfoExceptions}},
+	)
+
+	a.NoError(scsi.ScanSMART())
+	a.Equal(SCSI, scsi.Type())
+	a.Equal("SEAGATE ST4000NM0023", scsi.Model())
+	a.Equal("0003", scsi.Firmware())
+	a.Equal("", scsi.ID(), "no serial without the VPD pages")
+	a.Equal(uint64(128*512), scsi.Capacity())
+
+	features := scsi.Features()
+	a.Len(features, len(scsiLogFeatures))
+	a.Equal(Capability{Name: "Informational Exceptions log", Supported: true, Enabled: true}, features[0])
+	a.Equal(Capability{Name: "Start-Stop Cycle Counter log", Supported: false, Enabled: false}, features[2])
+
+	// the identify failure
+	scsi = newSCSIDev("/dev/sdaa")
+	scsi.transport = newFakeTransport(fakeResponse{err: errors.New("io error")})
+	a.Error(scsi.ScanSMART())
+}
@@ -0,0 +1,160 @@
+package internal
+
+const (
+	// SPC-4 (4.5 Sense data)
+	senseFixedCurrent  = uint8(0x70)
+	senseFixedDeferred = uint8(0x71)
+	senseDescCurrent   = uint8(0x72)
+	senseDescDeferred  = uint8(0x73)
+
+	senseResponseMASK = uint8(0x7f)
+
+	// SAT-3 (12.2.2.6 ATA Status Return sense data descriptor)
+	senseDescATAReturn    = uint8(0x09)
+	senseDescATAReturnLen = 14
+
+	// SAT-3 (12.2.2.7 Fixed format sense data), ASC/ASCQ 00h/1Dh
+	senseAscATAInfo  = uint8(0x00)
+	senseAscqATAInfo = uint8(0x1d)
+)
+
+func senseResponseCode(sense []byte) uint8 {
+	if len(sense) == 0 {
+		return 0
+	}
+
+	return sense[0] & senseResponseMASK
+}
+
+// findSenseDescriptor returns the first descriptor of the descriptor format
+// sense data with the given descriptor code.
+func findSenseDescriptor(sense []byte, code uint8) []byte {
+	switch senseResponseCode(sense) {
+	case senseDescCurrent, senseDescDeferred:
+	default:
+		return nil
+	}
+
+	if len(sense) < 8 {
+		return nil
+	}
+
+	end := 8 + int(sense[7])
+	if end > len(sense) {
+		end = len(sense)
+	}
+
+	for pos := 8; pos+2 <= end; {
+		length := 2 + int(sense[pos+1])
+
+		if sense[pos] == code {
+			if pos+length > end {
+				return nil
+			}
+
+			return sense[pos : pos+length]
+		}
+
+		pos += length
+	}
+
+	return nil
+}
+
+// ataRegisters is the ATA output registers returned in the sense data.
+type ataRegisters struct {
+	extend bool
+	error  uint8
+	count  uint16
+	lba    uint64
+	device uint8
+	status uint8
+}
+
+func (regs *ataRegisters) lbaMid() uint8 {
+	return uint8(regs.lba >> 8)
+}
+
+func (regs *ataRegisters) lbaHigh() uint8 {
+	return uint8(regs.lba >> 16)
+}
+
+// ataRegistersFromSense decodes the ATA output registers from either the ATA
+// Status Return descriptor or the fixed format sense data. The fixed format
+// carries the 28-bit registers only.
+func ataRegistersFromSense(sense []byte) (*ataRegisters, bool) {
+	switch senseResponseCode(sense) {
+	case senseDescCurrent, senseDescDeferred:
+		desc := findSenseDescriptor(sense, senseDescATAReturn)
+		if len(desc) < senseDescATAReturnLen {
+			return nil, false
+		}
+
+		// [2]: EXTEND(0), [3]: ERROR, [5:4]: COUNT(15:0),
+		// [11:6]: LBA(31:24, 7:0, 39:32, 15:8, 47:40, 23:16), [12]: DEVICE, [13]: STATUS
+		regs := &ataRegisters{
+			extend: desc[2]&0x01 != 0,
+			error:  desc[3],
+			count:  uint16(desc[4])<<8 | uint16(desc[5]),
+			device: desc[12],
+			status: desc[13],
+		}
+
+		regs.lba = uint64(desc[7]) | uint64(desc[9])<<8 | uint64(desc[11])<<16 |
+			uint64(desc[6])<<24 | uint64(desc[8])<<32 | uint64(desc[10])<<40
+
+		return regs, true
+
+	case senseFixedCurrent, senseFixedDeferred:
+		if len(sense) < 14 || sense[12] != senseAscATAInfo || sense[13] != senseAscqATAInfo {
+			return nil, false
+		}
+
+		// [3]: ERROR, [4]: STATUS, [5]: DEVICE, [6]: COUNT(7:0),
+		// [8]: EXTEND(7), [11:9]: LBA(23:16, 15:8, 7:0)
+		regs := &ataRegisters{
+			extend: sense[8]&0x80 != 0,
+			error:  sense[3],
+			count:  uint16(sense[6]),
+			device: sense[5],
+			status: sense[4],
+			lba:    uint64(sense[9]) | uint64(sense[10])<<8 | uint64(sense[11])<<16,
+		}
+
+		return regs, true
+	}
+
+	return nil, false
+}
+
+const (
+	// SPC-4 (4.5.6 Sense key and additional sense code definitions)
+	senseKeyNoSense        = uint8(0x0)
+	senseKeyRecovered      = uint8(0x1)
+	senseKeyNotReady       = uint8(0x2)
+	senseKeyIllegalRequest = uint8(0x5)
+
+	senseKeyMASK = uint8(0x0f)
+)
+
+// senseKeyCode decodes the sense key and the ASC/ASCQ of either the fixed or
+// the descriptor format sense data, zero if the field is not returned.
+func senseKeyCode(sense []byte) (key uint8, asc uint8, ascq uint8) {
+	switch senseResponseCode(sense) {
+	case senseDescCurrent, senseDescDeferred:
+		if len(sense) >= 4 {
+			key, asc, ascq = sense[1]&senseKeyMASK, sense[2], sense[3]
+		}
+
+	case senseFixedCurrent, senseFixedDeferred:
+		if len(sense) >= 3 {
+			key = sense[2] & senseKeyMASK
+		}
+
+		if len(sense) >= 14 {
+			asc, ascq = sense[12], sense[13]
+		}
+	}
+
+	return key, asc, ascq
+}
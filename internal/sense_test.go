@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSenseDescriptor(t *testing.T) {
+	a := assert.New(t)
+
+	sense := []byte{
+		0x72, 0x01, 0x00, 0x1d, 0x00, 0x00, 0x00, 0x16,
+		0x02, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // sense key specific
+		0x09, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x4f, 0x00, 0xc2, 0x00, 0x50,
+	}
+
+	desc := findSenseDescriptor(sense, senseDescATAReturn)
+	a.Len(desc, senseDescATAReturnLen)
+	a.Equal(senseDescATAReturn, desc[0])
+
+	a.Nil(findSenseDescriptor(sense, 0x03))
+	a.Nil(findSenseDescriptor(sense[:20], senseDescATAReturn))
+	a.Nil(findSenseDescriptor([]byte{0x70, 0x00, 0x05}, senseDescATAReturn))
+}
+
+func TestAtaRegistersFromSense(t *testing.T) {
+	a := assert.New(t)
+
+	expected := ataRegisters{
+		extend: true,
+		error:  0x04,
+		count:  0x1234,
+		lba:    0x0000_abcd_ef01_2345,
+		device: 0x40,
+		status: 0x51,
+	}
+
+	regs, ok := ataRegistersFromSense(ataRegistersSense(expected))
+	a.True(ok)
+	a.Equal(expected, *regs)
+
+	// fixed format with ATA PASS-THROUGH INFORMATION AVAILABLE
+	fixed := []byte{0x70, 0x00, 0x01, 0x04, 0x51, 0x40, 0x01, 0x0a, 0x00, 0x00, 0x4f, 0xc2, 0x00, 0x1d}
+	regs, ok = ataRegistersFromSense(fixed)
+	a.True(ok)
+	a.Equal(uint8(0x51), regs.status)
+	a.Equal(uint8(0x04), regs.error)
+	a.Equal(uint8(0x4f), regs.lbaMid())
+	a.Equal(uint8(0xc2), regs.lbaHigh())
+
+	// ILLEGAL REQUEST, INVALID FIELD IN CDB
+	_, ok = ataRegistersFromSense([]byte{0x70, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x24, 0x00})
+	a.False(ok)
+
+	_, ok = ataRegistersFromSense(nil)
+	a.False(ok)
+}
+
+func TestSenseKeyCode(t *testing.T) {
+	a := assert.New(t)
+
+	// ILLEGAL REQUEST, INVALID FIELD IN CDB
+	key, asc, ascq := senseKeyCode([]byte{0x70, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x24, 0x00})
+	a.Equal([]uint8{senseKeyIllegalRequest, 0x24, 0x00}, []uint8{key, asc, ascq})
+
+	// NOT READY, INITIALIZING COMMAND REQUIRED
+	key, asc, ascq = senseKeyCode([]byte{0x72, 0x02, 0x04, 0x02, 0x00, 0x00, 0x00, 0x00})
+	a.Equal([]uint8{senseKeyNotReady, 0x04, 0x02}, []uint8{key, asc, ascq})
+
+	// the fixed format without the additional sense bytes
+	key, asc, ascq = senseKeyCode([]byte{0x70, 0x00, 0x06})
+	a.Equal([]uint8{0x06, 0x00, 0x00}, []uint8{key, asc, ascq})
+
+	key, _, _ = senseKeyCode(nil)
+	a.Equal(senseKeyNoSense, key)
+}
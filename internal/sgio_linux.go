@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// linux/include/scsi/sg.h
+	sgIO          = 0x2285
+	sgInterfaceID = int32('S')
+
+	sgDxferNone    = int32(-1)
+	sgDxferToDev   = int32(-2)
+	sgDxferFromDev = int32(-3)
+
+	sgInfoOkMask = uint32(0x1)
+	sgDriverMask = uint16(0x7) // DRIVER_SENSE(0x08) is expected with sense data
+
+	defaultTimeout = 20 * time.Second
+)
+
+// sg_io_hdr_t in linux/include/scsi/sg.h
+type sgIOHdr struct {
+	interfaceID    int32   // [i] 'S' for SCSI generic (required)
+	dxferDirection int32   // [i] data transfer direction
+	cmdLen         uint8   // [i] SCSI command length
+	mxSbLen        uint8   // [i] max length to write to sbp
+	iovecCount     uint16  // [i] 0 implies no scatter gather
+	dxferLen       uint32  // [i] byte count of data transfer
+	dxferp         uintptr // [i], [*io] points to data transfer memory
+	cmdp           uintptr // [i], [*i] points to command to perform
+	sbp            uintptr // [i], [*o] points to sense_buffer memory
+	timeout        uint32  // [i] MAX_UINT->no timeout (unit: millisec)
+	flags          uint32  // [i] 0 -> default
+	packID         int32   // [i->o] unused internally (normally)
+	usrPtr         uintptr // [i->o] unused internally
+	status         uint8   // [o] scsi status
+	maskedStatus   uint8   // [o] shifted, masked scsi status
+	msgStatus      uint8   // [o] messaging level data (optional)
+	sbLenWr        uint8   // [o] byte count actually written to sbp
+	hostStatus     uint16  // [o] errors from host adapter
+	driverStatus   uint16  // [o] errors from software driver
+	resid          int32   // [o] dxfer_len - actual_transferred
+	duration       uint32  // [o] time taken by cmd (unit: millisec)
+	info           uint32  // [o] auxiliary information
+}
+
+type sgioTransport struct {
+	fd      int
+	timeout time.Duration
+}
+
+func openSGIO(path string) (*sgioTransport, error) {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sgioTransport{fd: fd, timeout: defaultTimeout}, nil
+}
+
+func OpenTransport(path string) (Transport, error) {
+	return openSGIO(path)
+}
+
+func (t *sgioTransport) Execute(cdb []byte, dir DataDirection, buf []byte) (ScsiResponse, error) {
+	if t.fd < 0 {
+		return ScsiResponse{}, ErrNotOpened
+	}
+
+	sense := make([]byte, senseBufferLen)
+
+	hdr := sgIOHdr{
+		interfaceID:    sgInterfaceID,
+		dxferDirection: sgDxferNone,
+		cmdLen:         uint8(len(cdb)),
+		mxSbLen:        uint8(len(sense)),
+		cmdp:           uintptr(unsafe.Pointer(&cdb[0])),
+		sbp:            uintptr(unsafe.Pointer(&sense[0])),
+		timeout:        uint32(t.timeout / time.Millisecond),
+	}
+
+	switch dir {
+	case DataToDev:
+		hdr.dxferDirection = sgDxferToDev
+	case DataFromDev:
+		hdr.dxferDirection = sgDxferFromDev
+	}
+
+	if dir != DataNone && len(buf) > 0 {
+		hdr.dxferLen = uint32(len(buf))
+		hdr.dxferp = uintptr(unsafe.Pointer(&buf[0]))
+	}
+
+	err := IoCtl(uintptr(t.fd), sgIO, uintptr(unsafe.Pointer(&hdr)))
+
+	// buffers are only referenced through uintptr in hdr
+	runtime.KeepAlive(cdb)
+	runtime.KeepAlive(buf)
+	runtime.KeepAlive(sense)
+
+	if err != nil {
+		return ScsiResponse{}, err
+	}
+
+	resp := ScsiResponse{Status: hdr.status, Sense: sense[:hdr.sbLenWr]}
+
+	if hdr.info&sgInfoOkMask != 0 && (hdr.hostStatus != 0 || hdr.driverStatus&sgDriverMask != 0) {
+		return resp, fmt.Errorf("sg_io failed: host status 0x%02x, driver status 0x%02x", hdr.hostStatus, hdr.driverStatus)
+	}
+
+	return resp, nil
+}
+
+func (t *sgioTransport) Close() error {
+	if t.fd < 0 {
+		return nil
+	}
+
+	err := unix.Close(t.fd)
+	t.fd = -1
+
+	return err
+}
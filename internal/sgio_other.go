@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package internal
+
+func OpenTransport(path string) (Transport, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,36 @@
+package internal
+
+import "errors"
+
+type DataDirection int
+
+const (
+	DataNone    = DataDirection(iota) // no data transfer
+	DataToDev                         // host to device (write)
+	DataFromDev                       // device to host (read)
+
+	// SCSI status codes (SAM-5, 5.3.1)
+	ScsiGood           = uint8(0x00)
+	ScsiCheckCondition = uint8(0x02)
+	ScsiBusy           = uint8(0x08)
+
+	senseBufferLen = 64
+)
+
+var (
+	ErrNotOpened   = errors.New("device transport is not opened")
+	ErrUnsupported = errors.New("unsupported operation on this platform")
+)
+
+// ScsiResponse is the completion of a CDB sent through a Transport.
+type ScsiResponse struct {
+	Status uint8  // SCSI status byte
+	Sense  []byte // sense data returned by the device, empty if none
+}
+
+// Transport sends a SCSI command descriptor block to a device. buf is read
+// from or written to according to dir, and must be nil for DataNone.
+type Transport interface {
+	Execute(cdb []byte, dir DataDirection, buf []byte) (ScsiResponse, error)
+	Close() error
+}
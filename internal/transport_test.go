@@ -0,0 +1,88 @@
+package internal
+
+import "errors"
+
+type fakeResponse struct {
+	data []byte
+	resp ScsiResponse
+	err  error
+}
+
+// fakeTransport records every CDB and returns the queued responses in order.
+type fakeTransport struct {
+	cdbs      [][]byte
+	dirs      []DataDirection
+	written   [][]byte
+	responses []fakeResponse
+	closed    bool
+}
+
+func newFakeTransport(responses ...fakeResponse) *fakeTransport {
+	return &fakeTransport{responses: responses}
+}
+
+func (t *fakeTransport) Execute(cdb []byte, dir DataDirection, buf []byte) (ScsiResponse, error) {
+	t.cdbs = append(t.cdbs, append([]byte{}, cdb...))
+	t.dirs = append(t.dirs, dir)
+
+	if dir == DataToDev {
+		t.written = append(t.written, append([]byte{}, buf...))
+	}
+
+	if len(t.responses) == 0 {
+		return ScsiResponse{}, errors.New("unexpected command")
+	}
+
+	next := t.responses[0]
+	t.responses = t.responses[1:]
+
+	copy(buf, next.data)
+
+	return next.resp, next.err
+}
+
+func (t *fakeTransport) Close() error {
+	t.closed = true
+
+	return nil
+}
+
+func (t *fakeTransport) lastCDB() []byte {
+	if len(t.cdbs) == 0 {
+		return nil
+	}
+
+	return t.cdbs[len(t.cdbs)-1]
+}
+
+// ataReturnSense builds the descriptor format sense data with the ATA Status
+// Return descriptor.
+func ataReturnSense(status, err uint8) []byte {
+	return ataRegistersSense(ataRegisters{status: status, error: err})
+}
+
+func ataRegistersSense(regs ataRegisters) []byte {
+	sense := make([]byte, 8+senseDescATAReturnLen)
+
+	sense[0] = senseDescCurrent
+	sense[1] = 0x01 // RECOVERED ERROR
+	sense[2] = senseAscATAInfo
+	sense[3] = senseAscqATAInfo
+	sense[7] = senseDescATAReturnLen
+
+	desc := sense[8:]
+	desc[0] = senseDescATAReturn
+	desc[1] = senseDescATAReturnLen - 2
+	if regs.extend {
+		desc[2] = 0x01
+	}
+	desc[3] = regs.error
+	desc[4], desc[5] = byte(regs.count>>8), byte(regs.count)
+	desc[6], desc[7] = byte(regs.lba>>24), byte(regs.lba)
+	desc[8], desc[9] = byte(regs.lba>>32), byte(regs.lba>>8)
+	desc[10], desc[11] = byte(regs.lba>>40), byte(regs.lba>>16)
+	desc[12] = regs.device
+	desc[13] = regs.status
+
+	return sense
+}
@@ -4,26 +4,77 @@ import (
 	"github.com/sungup/smartgo/internal"
 )
 
-/*
-Scan all devices
-*/
-func ScanDevice() (map[string]internal.StorageDevice, error) {
-	storage := make([]internal.StorageDevice, 0)
-	var err error
+// ScanResult has the scanned devices keyed by the WWN or the model and the
+// serial, so the same device keeps the key when the device path changes.
+// The devices skipped by the power policy and the devices failed to scan
+// are keyed by the device path.
+type ScanResult struct {
+	Devices map[string]Device
+	Skipped map[string]*SkippedError
+	Errors  map[string]error
+}
 
-	if storage, err = internal.ScanNVMe(storage); err != nil {
-		return nil, err
+func newScanResult() *ScanResult {
+	return &ScanResult{
+		Devices: make(map[string]Device),
+		Skipped: make(map[string]*SkippedError),
+		Errors:  make(map[string]error),
 	}
+}
 
-	if storage, err = internal.ScanSATA(storage); err != nil {
-		return nil, err
+// scanDevices scans every device and keeps going after the failed one. The
+// second path to the device already found, like the multipath, is closed.
+// The disks not supported are reported in the errors.
+func scanDevices(storage []internal.StorageDevice, unsupported map[string]error, policy PowerPolicy) *ScanResult {
+	result := newScanResult()
+
+	for path, err := range unsupported {
+		result.Errors[path] = err
 	}
 
 	for _, device := range storage {
-		if err := device.ScanSMART(); err != nil {
-			return nil, err
+		if err := internal.ScanSMARTPolicy(device, policy); err != nil {
+			if skipped, ok := err.(*SkippedError); ok {
+				result.Skipped[device.Device()] = skipped
+			} else {
+				result.Errors[device.Device()] = err
+			}
+
+			_ = device.Close()
+
+			continue
+		}
+
+		key := device.ID()
+		if key == "" {
+			key = device.Device()
 		}
+
+		if _, found := result.Devices[key]; found {
+			_ = device.Close()
+
+			continue
+		}
+
+		result.Devices[key] = device
+	}
+
+	return result
+}
+
+/*
+Scan all devices found in the system, the USB devices are scanned as SATA
+and the disks of the type not supported are reported in Errors with
+ErrUnsupportedType. The devices in the power mode skipped by the policy are not
+woken up and reported in Skipped with the mode and the policy. The error is
+returned only if the devices cannot be listed, and the failure of each device
+is reported in the result.
+*/
+func ScanDevice(policy PowerPolicy) (*ScanResult, error) {
+	storage, unsupported, err := internal.ScanStorage()
+	if err != nil {
+		return nil, err
 	}
 
-	return storage, nil
+	return scanDevices(storage, unsupported, policy), nil
 }
@@ -0,0 +1,90 @@
+package smartgo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sungup/smartgo/internal"
+)
+
+type fakeDevice struct {
+	path   string
+	id     string
+	mode   internal.PowerMode
+	err    error
+	closed bool
+}
+
+func (dev *fakeDevice) Type() internal.DeviceType { return internal.SATA }
+func (dev *fakeDevice) Device() string            { return dev.path }
+func (dev *fakeDevice) Model() string             { return "" }
+func (dev *fakeDevice) Firmware() string          { return "" }
+func (dev *fakeDevice) Serial() string            { return "" }
+func (dev *fakeDevice) ID() string                { return dev.id }
+func (dev *fakeDevice) Capacity() uint64          { return 0 }
+
+func (dev *fakeDevice) HealthStatus() (internal.HealthStatus, error) {
+	return internal.HealthUnknown, nil
+}
+
+func (dev *fakeDevice) Features() []internal.Capability {
+	return nil
+}
+
+func (dev *fakeDevice) PowerMode() (internal.PowerMode, error) {
+	return dev.mode, nil
+}
+
+func (dev *fakeDevice) ScanSMART() error {
+	return dev.err
+}
+
+func (dev *fakeDevice) Close() error {
+	dev.closed = true
+
+	return nil
+}
+
+func TestScanDevices(t *testing.T) {
+	a := assert.New(t)
+
+	usb := &fakeDevice{path: "/dev/sdc", err: errors.New("unsupported bridge")}
+	multipath := &fakeDevice{path: "/dev/sdd", id: "wwn-0x5000c500a1b2c3d4", mode: internal.PowerModeActive}
+	standby := &fakeDevice{path: "/dev/sde", mode: internal.PowerModeStandby}
+
+	storage := []internal.StorageDevice{
+		&fakeDevice{path: "/dev/sda", id: "wwn-0x5000c500a1b2c3d4", mode: internal.PowerModeActive},
+		&fakeDevice{path: "/dev/sdb", mode: internal.PowerModeIdle},
+		usb,
+		multipath,
+		standby,
+	}
+
+	unsupported := map[string]error{"/dev/sdf": fmt.Errorf("%s: %w", internal.DeviceType("fcoe"), internal.ErrUnsupportedType)}
+
+	result := scanDevices(storage, unsupported, internal.PowerPolicyStandby)
+
+	a.Len(result.Devices, 2)
+	a.Equal("/dev/sda", result.Devices["wwn-0x5000c500a1b2c3d4"].Device())
+	a.Contains(result.Devices, "/dev/sdb")
+	a.True(multipath.closed)
+
+	a.Len(result.Errors, 2)
+	a.EqualError(result.Errors["/dev/sdc"], "unsupported bridge")
+	a.True(errors.Is(result.Errors["/dev/sdf"], internal.ErrUnsupportedType))
+	a.True(usb.closed)
+
+	a.Len(result.Skipped, 1)
+	a.Equal(internal.PowerModeStandby, result.Skipped["/dev/sde"].Mode)
+	a.Equal(internal.PowerPolicyStandby, result.Skipped["/dev/sde"].Policy)
+	a.EqualError(result.Skipped["/dev/sde"], "/dev/sde: skipped, device is in STANDBY mode (power policy: standby)")
+	a.NotContains(result.Errors, "/dev/sde")
+	a.True(standby.closed)
+
+	// nothing is skipped without the policy
+	result = scanDevices(storage, nil, internal.PowerPolicyNever)
+	a.Len(result.Devices, 3)
+	a.Len(result.Skipped, 0)
+}